@@ -2,13 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
+	"lunar/src/domain"
+	"lunar/src/domain/port"
 	"lunar/src/domain/validator"
+	"os"
+	"strconv"
+	"time"
 
 	"lunar/src/application"
 	"lunar/src/infrastructure/http/handler"
 	"lunar/src/infrastructure/http/routes"
+	"lunar/src/infrastructure/notify"
 	"lunar/src/infrastructure/persistence"
+	"lunar/src/infrastructure/persistence/postgres"
+	"lunar/src/infrastructure/persistence/sqlite"
 	"lunar/src/infrastructure/pubsub"
+	"lunar/src/infrastructure/receipt"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
@@ -16,7 +26,20 @@ import (
 	"go.uber.org/zap"
 )
 
-const topicMessages = "rockets.messages"
+const (
+	topicMessages = "rockets.messages"
+	envDataDir    = "ROCKETS_DATA_DIR"
+	envSnapshot   = "ROCKETS_SNAPSHOT_EVERY"
+	envSQLitePath = "ROCKETS_SQLITE_PATH"
+	envPostgres   = "ROCKETS_POSTGRES_DSN"
+
+	envStrictOrdering = "ROCKETS_STRICT_ORDERING"
+	envReorderBuffer  = "ROCKETS_REORDER_BUFFER"
+	envGapTimeout     = "ROCKETS_GAP_TIMEOUT"
+
+	envEventTime        = "ROCKETS_EVENT_TIME"
+	envHistoryRetention = "ROCKETS_HISTORY_RETENTION"
+)
 
 func mustSucceed[C any](h C, err error) C {
 	if err != nil {
@@ -25,11 +48,120 @@ func mustSucceed[C any](h C, err error) C {
 	return h
 }
 
+type store interface {
+	port.Persistence
+	port.EventReader
+	port.RocketHistoryReader
+	port.GapReader
+	port.StatsReader
+}
+
+// newStore usa PostgreSQL si hay ROCKETS_POSTGRES_DSN, SQLite si hay
+// ROCKETS_SQLITE_PATH, el log en disco si hay ROCKETS_DATA_DIR y, si no,
+// memoria.
+func newStore(
+	eventTime, strict bool,
+	onChange func(domain.Rocket),
+	onResult func(domain.MessageEnvelope, domain.MessageResult),
+	opts ...persistence.Option,
+) (store, error) {
+	if dsn := os.Getenv(envPostgres); dsn != "" {
+		if strict {
+			return nil, errors.New("strict ordering is not supported by the postgres store")
+		}
+		pgOpts := []postgres.Option{postgres.WithChangeListener(onChange), postgres.WithResultListener(onResult)}
+		if eventTime {
+			pgOpts = append(pgOpts, postgres.WithEventTime())
+		}
+		return postgres.NewStore(dsn, pgOpts...)
+	}
+	if path := os.Getenv(envSQLitePath); path != "" {
+		if strict {
+			return nil, errors.New("strict ordering is not supported by the sqlite store")
+		}
+		sqlOpts := []sqlite.Option{sqlite.WithChangeListener(onChange), sqlite.WithResultListener(onResult)}
+		if eventTime {
+			sqlOpts = append(sqlOpts, sqlite.WithEventTime())
+		}
+		return sqlite.NewStore(path, sqlOpts...)
+	}
+	opts = append(opts, persistence.WithChangeListener(onChange), persistence.WithResultListener(onResult))
+	if dir := os.Getenv(envDataDir); dir != "" {
+		return persistence.NewFileStore(dir, opts...)
+	}
+	return persistence.NewMemoryStore(opts...), nil
+}
+
+// strictOrdering lee la configuración del modo estricto del entorno.
+func strictOrdering() (bool, int, time.Duration, error) {
+	if on, _ := strconv.ParseBool(os.Getenv(envStrictOrdering)); !on {
+		return false, 0, 0, nil
+	}
+	var (
+		size    int
+		timeout = 30 * time.Second
+		err     error
+	)
+	if raw := os.Getenv(envReorderBuffer); raw != "" {
+		if size, err = strconv.Atoi(raw); err != nil {
+			return false, 0, 0, err
+		}
+	}
+	if raw := os.Getenv(envGapTimeout); raw != "" {
+		if timeout, err = time.ParseDuration(raw); err != nil {
+			return false, 0, 0, err
+		}
+	}
+	return true, size, timeout, nil
+}
+
 func main() {
-	mem := persistence.NewMemoryStore()
 	logger := mustSucceed(zap.NewDevelopment())
 	ctx := context.Background()
 
+	var opts []persistence.Option
+	strict, bufferSize, gapTimeout, err := strictOrdering()
+	if err != nil {
+		logger.Fatal("invalid strict ordering config", zap.Error(err))
+	}
+	if strict {
+		opts = append(opts, persistence.WithStrictOrdering(bufferSize, gapTimeout))
+	}
+	eventTime, _ := strconv.ParseBool(os.Getenv(envEventTime))
+	if eventTime {
+		opts = append(opts, persistence.WithEventTime())
+	}
+	if raw := os.Getenv(envSnapshot); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			logger.Fatal("invalid snapshot interval", zap.Error(err))
+		}
+		opts = append(opts, persistence.WithSnapshotEvery(n))
+	}
+	if raw := os.Getenv(envHistoryRetention); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			logger.Fatal("invalid history retention", zap.Error(err))
+		}
+		opts = append(opts, persistence.WithHistoryRetention(n))
+	}
+	// Los cambios que aplica el consumer se reparten a los streams HTTP.
+	broker := notify.NewBroker(notify.DefaultHistory, notify.DefaultBuffer)
+	// Y lo que hace con cada mensaje cierra su recibo.
+	receipts := receipt.NewTracker(receipt.DefaultCapacity)
+	st, err := newStore(eventTime, strict, broker.Publish, receipts.Record, opts...)
+	if err != nil {
+		logger.Fatal("failed to open store", zap.Error(err))
+	}
+	if g, ok := st.(interface{ ExpireGaps() }); ok && strict {
+		// Los huecos de canales sin tráfico nuevo también deben caducar.
+		go func() {
+			for range time.Tick(gapTimeout / 2) {
+				g.ExpireGaps()
+			}
+		}()
+	}
+
 	channel := gochannel.NewGoChannel(
 		gochannel.Config{},
 		watermill.NewStdLogger(false, false),
@@ -37,7 +169,7 @@ func main() {
 
 	// Producer & Consumer
 	producer := pubsub.NewProducer(channel)
-	applyUC := application.NewApplyMessageUC(mem)
+	applyUC := application.NewApplyMessageUC(st, receipts)
 	consumer := pubsub.NewConsumer(channel, logger, applyUC, topicMessages)
 
 	// Arranca el consumer
@@ -46,23 +178,48 @@ func main() {
 	}
 
 	// Usecases para HTTP
-	enqueueUC := application.NewEnqueueMessageUC(producer, topicMessages)
-	getUC := application.NewGetRocketUC(mem)
-	listUC := application.NewListRocketsUC(mem)
+	enqueueUC := application.NewEnqueueMessageUC(producer, receipts, topicMessages)
+	enqueueSyncUC := application.NewEnqueueMessageSyncUC(enqueueUC, receipts, receipts)
+	enqueueBatchUC := application.NewEnqueueMessageBatchUC(producer, receipts, topicMessages)
+	getUC := application.NewGetRocketUC(st)
+	listUC := application.NewListRocketsUC(st)
+	eventsUC := application.NewListRocketEventsUC(st)
+	getAsOfUC := application.NewGetRocketAsOfUC(st)
+	gapsUC := application.NewGetRocketGapsUC(st)
+	statsUC := application.NewGetFleetStatsUC(st)
+	watchUC := application.NewWatchRocketsUC(broker)
+	subscribeUC := application.NewSubscribeRocketsUC(broker)
+	waitUC := application.NewWaitRocketUC(st, broker)
+	receiptUC := application.NewGetMessageReceiptUC(receipts)
 
 	// Handlers HTTP
 	v := validator.New()
-	msgHandler := handler.NewMessages(enqueueUC, v)
-	rockHandler := handler.NewRockets(getUC, listUC)
+	msgHandler := handler.NewMessages(enqueueUC, enqueueSyncUC, v)
+	batchHandler := handler.NewMessageBatch(enqueueBatchUC, v)
+	receiptHandler := handler.NewMessageReceipts(receiptUC)
+	rockHandler := handler.NewRockets(getUC, listUC, getAsOfUC, waitUC)
+	eventsHandler := handler.NewRocketEvents(eventsUC)
+	gapsHandler := handler.NewRocketGaps(gapsUC)
+	statsHandler := handler.NewStats(statsUC)
+	streamHandler := handler.NewRocketStream(watchUC)
+	socketHandler := handler.NewRocketSocket(subscribeUC, listUC)
 
 	// Router Gin
 	r := gin.Default()
 	r.POST(routes.PostMessagesPath, msgHandler.Handle)
+	r.POST(routes.MessagesActionPath, batchHandler.Handle)
+	r.GET(routes.ReceiptPath, receiptHandler.GetOne)
 
 	protected := r.Group(routes.ApiGroup)
 	{
 		protected.GET(routes.ListRocketsPath, rockHandler.List)
 		protected.GET(routes.GetRocketPath, rockHandler.GetOne)
+		protected.GET(routes.RocketEventsPath, eventsHandler.List)
+		protected.GET(routes.RocketGapsPath, gapsHandler.GetOne)
+		protected.GET(routes.StatsPath, statsHandler.Get)
+		protected.GET(routes.StreamPath, streamHandler.All)
+		protected.GET(routes.RocketStreamPath, streamHandler.One)
+		protected.GET(routes.WebSocketPath, socketHandler.Handle)
 	}
 
 	logger.Info("listening on :8088")
@@ -9,13 +9,24 @@ type ApplyMessageUCInterface interface {
 	Execute(env domain.MessageEnvelope) error
 }
 type ApplyMessageUC struct {
-	writer port.MessageWriter
+	writer   port.MessageWriter
+	receipts port.ReceiptWriter
 }
 
-func NewApplyMessageUC(writer port.MessageWriter) ApplyMessageUCInterface {
-	return &ApplyMessageUC{writer: writer}
+func NewApplyMessageUC(writer port.MessageWriter, receipts port.ReceiptWriter) ApplyMessageUCInterface {
+	return &ApplyMessageUC{writer: writer, receipts: receipts}
 }
 
+// Execute aplica el envelope. Lo que hace el store con él lo apunta su
+// listener; aquí sólo se cierra el recibo cuando ni siquiera se pudo aplicar.
 func (uc *ApplyMessageUC) Execute(env domain.MessageEnvelope) error {
-	return uc.writer.Apply(env)
+	// Sólo se aplican tipos registrados en el dominio con payload válido.
+	err := domain.ValidatePayload(env.Metadata.MessageType, env.Message)
+	if err == nil {
+		err = uc.writer.Apply(env)
+	}
+	if err != nil && env.ID != "" {
+		uc.receipts.Resolve(env.ID, domain.MessageResult{Outcome: domain.OutcomeRejected, Reason: err.Error()})
+	}
+	return err
 }
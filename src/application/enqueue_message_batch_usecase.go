@@ -0,0 +1,41 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type EnqueueMessageBatchUCInterface interface {
+	Execute(envs []domain.MessageEnvelope) ([]domain.Receipt, error)
+}
+type EnqueueMessageBatchUC struct {
+	pub      port.MessagePublisher
+	receipts port.ReceiptWriter
+	topic    string
+}
+
+func NewEnqueueMessageBatchUC(pub port.MessagePublisher, receipts port.ReceiptWriter, topic string) EnqueueMessageBatchUCInterface {
+	return &EnqueueMessageBatchUC{pub: pub, receipts: receipts, topic: topic}
+}
+
+// Execute publica los envelopes en un solo mensaje, que el consumer aplica
+// en orden, y devuelve sus recibos en ese mismo orden. Como en
+// EnqueueMessageUC, los recibos se dan de alta antes de publicar. Si la
+// publicación falla no se publica ninguno y todos los recibos se cierran
+// rechazados con el error.
+func (uc *EnqueueMessageBatchUC) Execute(envs []domain.MessageEnvelope) ([]domain.Receipt, error) {
+	receipts := make([]domain.Receipt, len(envs))
+	queued := make([]domain.MessageEnvelope, len(envs))
+	for i, env := range envs {
+		receipts[i] = uc.receipts.Queue(env)
+		env.ID = receipts[i].ID
+		queued[i] = env
+	}
+	if err := uc.pub.PublishBatch(uc.topic, queued); err != nil {
+		for _, rc := range receipts {
+			uc.receipts.Resolve(rc.ID, domain.MessageResult{Outcome: domain.OutcomeRejected, Reason: err.Error()})
+		}
+		return nil, err
+	}
+	return receipts, nil
+}
@@ -0,0 +1,19 @@
+package application
+
+import (
+	"lunar/src/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type EnqueueMessageBatchUCMock struct{ mock.Mock }
+
+func (m *EnqueueMessageBatchUCMock) Execute(envs []domain.MessageEnvelope) ([]domain.Receipt, error) {
+	args := m.Called(envs)
+
+	var rcs []domain.Receipt
+	if v, ok := args.Get(0).([]domain.Receipt); ok {
+		rcs = v
+	}
+	return rcs, args.Error(1)
+}
@@ -0,0 +1,81 @@
+package application
+
+import (
+	"context"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type EnqueueMessageSyncUCInterface interface {
+	Execute(ctx context.Context, env domain.MessageEnvelope) (domain.Receipt, domain.MessageResult, error)
+}
+
+type EnqueueMessageSyncUC struct {
+	enqueue EnqueueMessageUCInterface
+	waiter  port.ReceiptWaiter
+	reader  port.ReceiptReader
+}
+
+func NewEnqueueMessageSyncUC(
+	enqueue EnqueueMessageUCInterface,
+	waiter port.ReceiptWaiter,
+	reader port.ReceiptReader,
+) EnqueueMessageSyncUCInterface {
+	return &EnqueueMessageSyncUC{enqueue: enqueue, waiter: waiter, reader: reader}
+}
+
+type enqueued struct {
+	rc  domain.Receipt
+	err error
+}
+
+// Execute publica el envelope como EnqueueMessageUC y espera a que el
+// consumer lo procese. ctx acota las dos cosas: si vence antes, aunque el
+// publisher siga bloqueado, el resultado vuelve vacío (sin Outcome) y el
+// recibo sigue en cola.
+func (uc *EnqueueMessageSyncUC) Execute(ctx context.Context, env domain.MessageEnvelope) (domain.Receipt, domain.MessageResult, error) {
+	// El ID se reserva antes de publicar: el consumer puede cerrar el recibo
+	// antes de que Execute vuelva.
+	id, result, stop := uc.waiter.Expect()
+	defer stop()
+
+	env.ID = id
+	done := make(chan enqueued, 1)
+	go func() {
+		rc, err := uc.enqueue.Execute(env)
+		done <- enqueued{rc: rc, err: err}
+	}()
+
+	var rc domain.Receipt
+	select {
+	case e := <-done:
+		if e.err != nil {
+			return domain.Receipt{}, domain.MessageResult{}, e.err
+		}
+		rc = e.rc
+	case <-ctx.Done():
+		return uc.queued(id, env), domain.MessageResult{}, nil
+	}
+	select {
+	case res := <-result:
+		return rc, res, nil
+	case <-ctx.Done():
+		return rc, domain.MessageResult{}, nil
+	}
+}
+
+// queued es el recibo de un envelope cuya publicación sigue en curso. Si la
+// publicación acaba fallando, el recibo se cierra rechazado.
+func (uc *EnqueueMessageSyncUC) queued(id string, env domain.MessageEnvelope) domain.Receipt {
+	if rc, ok := uc.reader.Get(id); ok {
+		return rc
+	}
+	// Todavía no se ha dado de alta; lo hará enseguida con este mismo ID.
+	return domain.Receipt{
+		ID:            id,
+		Status:        domain.ReceiptQueued,
+		Channel:       env.Metadata.Channel,
+		MessageNumber: env.Metadata.MessageNum,
+	}
+}
@@ -0,0 +1,25 @@
+package application
+
+import (
+	"context"
+
+	"lunar/src/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type EnqueueMessageSyncUCMock struct{ mock.Mock }
+
+func (m *EnqueueMessageSyncUCMock) Execute(ctx context.Context, env domain.MessageEnvelope) (domain.Receipt, domain.MessageResult, error) {
+	args := m.Called(ctx, env)
+
+	var rc domain.Receipt
+	if v, ok := args.Get(0).(domain.Receipt); ok {
+		rc = v
+	}
+	var res domain.MessageResult
+	if v, ok := args.Get(1).(domain.MessageResult); ok {
+		res = v
+	}
+	return rc, res, args.Error(2)
+}
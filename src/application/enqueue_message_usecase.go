@@ -6,17 +6,26 @@ import (
 )
 
 type EnqueueMessageUCInterface interface {
-	Execute(env domain.MessageEnvelope) error
+	Execute(env domain.MessageEnvelope) (domain.Receipt, error)
 }
 type EnqueueMessageUC struct {
-	pub   port.MessagePublisher
-	topic string
+	pub      port.MessagePublisher
+	receipts port.ReceiptWriter
+	topic    string
 }
 
-func NewEnqueueMessageUC(pub port.MessagePublisher, topic string) EnqueueMessageUCInterface {
-	return &EnqueueMessageUC{pub: pub, topic: topic}
+func NewEnqueueMessageUC(pub port.MessagePublisher, receipts port.ReceiptWriter, topic string) EnqueueMessageUCInterface {
+	return &EnqueueMessageUC{pub: pub, receipts: receipts, topic: topic}
 }
 
-func (uc *EnqueueMessageUC) Execute(env domain.MessageEnvelope) error {
-	return uc.pub.Publish(uc.topic, env)
+// Execute da de alta el recibo antes de publicar: el consumer puede
+// procesar el mensaje antes de que Publish vuelva.
+func (uc *EnqueueMessageUC) Execute(env domain.MessageEnvelope) (domain.Receipt, error) {
+	rc := uc.receipts.Queue(env)
+	env.ID = rc.ID
+	if err := uc.pub.Publish(uc.topic, env); err != nil {
+		uc.receipts.Resolve(rc.ID, domain.MessageResult{Outcome: domain.OutcomeRejected, Reason: err.Error()})
+		return domain.Receipt{}, err
+	}
+	return rc, nil
 }
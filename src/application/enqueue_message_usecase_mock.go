@@ -8,7 +8,12 @@ import (
 
 type EnqueueMessageUCMock struct{ mock.Mock }
 
-func (m *EnqueueMessageUCMock) Execute(env domain.MessageEnvelope) error {
+func (m *EnqueueMessageUCMock) Execute(env domain.MessageEnvelope) (domain.Receipt, error) {
 	args := m.Called(env)
-	return args.Error(0)
+
+	var rc domain.Receipt
+	if v, ok := args.Get(0).(domain.Receipt); ok {
+		rc = v
+	}
+	return rc, args.Error(1)
 }
@@ -0,0 +1,23 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+	"time"
+)
+
+type GetFleetStatsUCInterface interface {
+	Execute(window time.Duration) (domain.FleetStats, error)
+}
+
+type GetFleetStatsUC struct {
+	reader port.StatsReader
+}
+
+func NewGetFleetStatsUC(reader port.StatsReader) GetFleetStatsUCInterface {
+	return &GetFleetStatsUC{reader: reader}
+}
+
+func (s *GetFleetStatsUC) Execute(window time.Duration) (domain.FleetStats, error) {
+	return s.reader.Stats(window)
+}
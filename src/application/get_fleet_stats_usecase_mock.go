@@ -0,0 +1,20 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type GetFleetStatsUCMock struct{ mock.Mock }
+
+func (m *GetFleetStatsUCMock) Execute(window time.Duration) (domain.FleetStats, error) {
+	args := m.Called(window)
+
+	var st domain.FleetStats
+	if v, ok := args.Get(0).(domain.FleetStats); ok {
+		st = v
+	}
+	return st, args.Error(1)
+}
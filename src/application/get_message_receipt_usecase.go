@@ -0,0 +1,21 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type GetMessageReceiptUCInterface interface {
+	Execute(id string) (domain.Receipt, bool)
+}
+type GetMessageReceiptUC struct {
+	reader port.ReceiptReader
+}
+
+func NewGetMessageReceiptUC(reader port.ReceiptReader) GetMessageReceiptUCInterface {
+	return &GetMessageReceiptUC{reader: reader}
+}
+
+func (s *GetMessageReceiptUC) Execute(id string) (domain.Receipt, bool) {
+	return s.reader.Get(id)
+}
@@ -0,0 +1,19 @@
+package application
+
+import (
+	"lunar/src/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type GetMessageReceiptUCMock struct{ mock.Mock }
+
+func (m *GetMessageReceiptUCMock) Execute(id string) (domain.Receipt, bool) {
+	args := m.Called(id)
+
+	var rc domain.Receipt
+	if v, ok := args.Get(0).(domain.Receipt); ok {
+		rc = v
+	}
+	return rc, args.Bool(1)
+}
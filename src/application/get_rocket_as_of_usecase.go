@@ -0,0 +1,22 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type GetRocketAsOfUCInterface interface {
+	Execute(channel string, point domain.PointInTime) (domain.Rocket, bool, error)
+}
+
+type GetRocketAsOfUC struct {
+	reader port.RocketHistoryReader
+}
+
+func NewGetRocketAsOfUC(reader port.RocketHistoryReader) GetRocketAsOfUCInterface {
+	return &GetRocketAsOfUC{reader: reader}
+}
+
+func (s *GetRocketAsOfUC) Execute(channel string, point domain.PointInTime) (domain.Rocket, bool, error) {
+	return s.reader.GetAsOf(channel, point)
+}
@@ -0,0 +1,19 @@
+package application
+
+import (
+	"lunar/src/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type GetRocketAsOfUCMock struct{ mock.Mock }
+
+func (m *GetRocketAsOfUCMock) Execute(channel string, point domain.PointInTime) (domain.Rocket, bool, error) {
+	args := m.Called(channel, point)
+
+	var r domain.Rocket
+	if v, ok := args.Get(0).(domain.Rocket); ok {
+		r = v
+	}
+	return r, args.Bool(1), args.Error(2)
+}
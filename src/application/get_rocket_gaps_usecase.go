@@ -0,0 +1,22 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type GetRocketGapsUCInterface interface {
+	Execute(channel string) (domain.OrderingGaps, bool, error)
+}
+
+type GetRocketGapsUC struct {
+	reader port.GapReader
+}
+
+func NewGetRocketGapsUC(reader port.GapReader) GetRocketGapsUCInterface {
+	return &GetRocketGapsUC{reader: reader}
+}
+
+func (s *GetRocketGapsUC) Execute(channel string) (domain.OrderingGaps, bool, error) {
+	return s.reader.Gaps(channel)
+}
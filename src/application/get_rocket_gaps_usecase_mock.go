@@ -0,0 +1,19 @@
+package application
+
+import (
+	"lunar/src/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type GetRocketGapsUCMock struct{ mock.Mock }
+
+func (m *GetRocketGapsUCMock) Execute(channel string) (domain.OrderingGaps, bool, error) {
+	args := m.Called(channel)
+
+	var g domain.OrderingGaps
+	if v, ok := args.Get(0).(domain.OrderingGaps); ok {
+		g = v
+	}
+	return g, args.Bool(1), args.Error(2)
+}
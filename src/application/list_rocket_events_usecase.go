@@ -0,0 +1,22 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type ListRocketEventsUCInterface interface {
+	Execute(channel string) ([]domain.RocketEvent, bool, error)
+}
+
+type ListRocketEventsUC struct {
+	reader port.EventReader
+}
+
+func NewListRocketEventsUC(reader port.EventReader) ListRocketEventsUCInterface {
+	return &ListRocketEventsUC{reader: reader}
+}
+
+func (s *ListRocketEventsUC) Execute(channel string) ([]domain.RocketEvent, bool, error) {
+	return s.reader.Events(channel)
+}
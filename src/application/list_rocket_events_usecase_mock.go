@@ -0,0 +1,19 @@
+package application
+
+import (
+	"lunar/src/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type ListRocketEventsUCMock struct{ mock.Mock }
+
+func (m *ListRocketEventsUCMock) Execute(channel string) ([]domain.RocketEvent, bool, error) {
+	args := m.Called(channel)
+
+	var items []domain.RocketEvent
+	if v, ok := args.Get(0).([]domain.RocketEvent); ok {
+		items = v
+	}
+	return items, args.Bool(1), args.Error(2)
+}
@@ -6,7 +6,7 @@ import (
 )
 
 type ListRocketsUCInterface interface {
-	Execute(sortBy, order string) ([]domain.Rocket, error)
+	Execute(q domain.RocketQuery) (domain.Page, error)
 }
 
 type ListRocketsUC struct {
@@ -17,6 +17,6 @@ func NewListRocketsUC(reader port.RocketReader) ListRocketsUCInterface {
 	return &ListRocketsUC{reader: reader}
 }
 
-func (s *ListRocketsUC) Execute(sortBy, order string) ([]domain.Rocket, error) {
-	return s.reader.List(sortBy, order)
+func (s *ListRocketsUC) Execute(q domain.RocketQuery) (domain.Page, error) {
+	return s.reader.List(q)
 }
@@ -8,12 +8,12 @@ import (
 
 type ListRocketsUCMock struct{ mock.Mock }
 
-func (m *ListRocketsUCMock) Execute(sortBy, order string) ([]domain.Rocket, error) {
-	args := m.Called(sortBy, order)
+func (m *ListRocketsUCMock) Execute(q domain.RocketQuery) (domain.Page, error) {
+	args := m.Called(q)
 
-	var items []domain.Rocket
-	if v, ok := args.Get(0).([]domain.Rocket); ok {
-		items = v
+	var page domain.Page
+	if v, ok := args.Get(0).(domain.Page); ok {
+		page = v
 	}
-	return items, args.Error(1)
+	return page, args.Error(1)
 }
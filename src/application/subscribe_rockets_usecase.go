@@ -0,0 +1,27 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type SubscribeRocketsUCInterface interface {
+	Execute(match func(domain.Rocket) bool) port.ChangeSubscription
+}
+
+// SubscribeRocketsUC se suscribe a los cambios nuevos que pasen match. A
+// diferencia de WatchRocketsUC, match puede cambiar de criterio durante la
+// suscripción. El broker lo llama al repartir, fuera del lock del store pero
+// con el suyo tomado: no frena Apply, pero mientras se ejecuta no le llega
+// nada a ningún suscriptor, así que debe ser rápido y no llamar al broker.
+type SubscribeRocketsUC struct {
+	feed port.ChangeFeed
+}
+
+func NewSubscribeRocketsUC(feed port.ChangeFeed) SubscribeRocketsUCInterface {
+	return &SubscribeRocketsUC{feed: feed}
+}
+
+func (s *SubscribeRocketsUC) Execute(match func(domain.Rocket) bool) port.ChangeSubscription {
+	return s.feed.Subscribe(domain.ChangeID{}, match)
+}
@@ -0,0 +1,17 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SubscribeRocketsUCMock struct{ mock.Mock }
+
+func (m *SubscribeRocketsUCMock) Execute(match func(domain.Rocket) bool) port.ChangeSubscription {
+	args := m.Called(match)
+
+	sub, _ := args.Get(0).(port.ChangeSubscription)
+	return sub
+}
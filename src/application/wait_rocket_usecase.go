@@ -0,0 +1,63 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+// waitRecheck es cada cuánto se vuelve a leer el store mientras se espera:
+// con PostgreSQL otra instancia puede aplicar el mensaje sin que el feed de
+// esta se entere.
+const waitRecheck = time.Second
+
+type WaitRocketUCInterface interface {
+	Execute(ctx context.Context, channel string, minMsgNum int) (domain.Rocket, bool, bool, error)
+}
+
+type WaitRocketUC struct {
+	reader port.RocketReader
+	feed   port.ChangeFeed
+}
+
+func NewWaitRocketUC(reader port.RocketReader, feed port.ChangeFeed) WaitRocketUCInterface {
+	return &WaitRocketUC{reader: reader, feed: feed}
+}
+
+// Execute espera a que el LastMsgNum del cohete llegue a minMsgNum o a que
+// venza ctx. Devuelve la última versión conocida, si el cohete existe y si
+// se llegó a minMsgNum.
+func (s *WaitRocketUC) Execute(ctx context.Context, channel string, minMsgNum int) (domain.Rocket, bool, bool, error) {
+	// Primero la suscripción y luego la lectura: así no se pierde un cambio
+	// que llegue entre medias.
+	sub := s.subscribe(channel)
+	defer func() { sub.Close() }()
+	recheck := time.NewTicker(waitRecheck)
+	defer recheck.Stop()
+	for {
+		r, ok, err := s.reader.Get(channel)
+		if err != nil || (ok && r.LastMsgNum >= minMsgNum) {
+			return r, ok, ok, err
+		}
+		select {
+		case <-ctx.Done():
+			return r, ok, false, nil
+		case <-recheck.C:
+		case change, open := <-sub.Changes():
+			if !open {
+				// El broker la descolgó por no leer a tiempo.
+				sub = s.subscribe(channel)
+				continue
+			}
+			if change.Rocket.LastMsgNum >= minMsgNum {
+				return change.Rocket, true, true, nil
+			}
+		}
+	}
+}
+
+func (s *WaitRocketUC) subscribe(channel string) port.ChangeSubscription {
+	return s.feed.Subscribe(domain.ChangeID{}, func(r domain.Rocket) bool { return r.Channel == channel })
+}
@@ -0,0 +1,20 @@
+package application
+
+import (
+	"context"
+	"lunar/src/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type WaitRocketUCMock struct{ mock.Mock }
+
+func (m *WaitRocketUCMock) Execute(ctx context.Context, channel string, minMsgNum int) (domain.Rocket, bool, bool, error) {
+	args := m.Called(ctx, channel, minMsgNum)
+
+	var r domain.Rocket
+	if v, ok := args.Get(0).(domain.Rocket); ok {
+		r = v
+	}
+	return r, args.Bool(1), args.Bool(2), args.Error(3)
+}
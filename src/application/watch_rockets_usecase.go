@@ -0,0 +1,26 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+type WatchRocketsUCInterface interface {
+	Execute(after domain.ChangeID, channel string, filter domain.RocketFilter) port.ChangeSubscription
+}
+
+type WatchRocketsUC struct {
+	feed port.ChangeFeed
+}
+
+func NewWatchRocketsUC(feed port.ChangeFeed) WatchRocketsUCInterface {
+	return &WatchRocketsUC{feed: feed}
+}
+
+// Execute se suscribe a los cambios posteriores a after de los cohetes que
+// pasen filter; con channel, sólo a los de ese canal.
+func (s *WatchRocketsUC) Execute(after domain.ChangeID, channel string, filter domain.RocketFilter) port.ChangeSubscription {
+	return s.feed.Subscribe(after, func(r domain.Rocket) bool {
+		return (channel == "" || r.Channel == channel) && filter.Matches(r)
+	})
+}
@@ -0,0 +1,17 @@
+package application
+
+import (
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type WatchRocketsUCMock struct{ mock.Mock }
+
+func (m *WatchRocketsUCMock) Execute(after domain.ChangeID, channel string, filter domain.RocketFilter) port.ChangeSubscription {
+	args := m.Called(after, channel, filter)
+
+	sub, _ := args.Get(0).(port.ChangeSubscription)
+	return sub
+}
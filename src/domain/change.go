@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidChangeID = errors.New("invalid change id")
+
+// RocketChange es una versión nueva de un cohete, tal como queda tras
+// aplicarse un mensaje. Sequence numera todos los cambios de la flota en el
+// orden en que se publicaron; empieza en 1 en cada Epoch (cada arranque del
+// proceso que los numera).
+type RocketChange struct {
+	Epoch    string
+	Sequence uint64
+	Rocket   Rocket
+}
+
+func (c RocketChange) ID() ChangeID {
+	return ChangeID{Epoch: c.Epoch, Sequence: c.Sequence}
+}
+
+// ChangeID identifica un cambio entre reinicios: un Sequence sólo significa
+// algo dentro del Epoch que lo dio. El cero es "desde ahora".
+type ChangeID struct {
+	Epoch    string
+	Sequence uint64
+}
+
+func (id ChangeID) IsZero() bool {
+	return id.Sequence == 0
+}
+
+// String da la forma <epoch>-<sequence> que viaja como id de los eventos.
+func (id ChangeID) String() string {
+	return id.Epoch + "-" + strconv.FormatUint(id.Sequence, 10)
+}
+
+// ParseChangeID lee lo que da String. Un número suelto (ids de antes de que
+// hubiera epochs) se acepta sin Epoch: no coincide con ninguno.
+func ParseChangeID(raw string) (ChangeID, error) {
+	epoch, seq, found := strings.Cut(raw, "-")
+	if !found {
+		epoch, seq = "", raw
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || found && epoch == "" {
+		return ChangeID{}, ErrInvalidChangeID
+	}
+	return ChangeID{Epoch: epoch, Sequence: n}, nil
+}
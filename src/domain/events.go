@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrHistoryTrimmed indica que el punto pedido cae antes del histórico que
+// aún se conserva.
+var ErrHistoryTrimmed = errors.New("history before this point is no longer retained")
+
+// Outcome indica qué hizo el store con un envelope recibido.
+type Outcome string
+
+const (
+	OutcomeApplied   Outcome = "applied"
+	OutcomeDuplicate Outcome = "duplicate"
+	OutcomeStale     Outcome = "stale"
+	OutcomeRejected  Outcome = "rejected"
+)
+
+// ChangesRocket indica si el envelope cambió lo que se ve del cohete: uno
+// rechazado no mueve el estado pero sí LastRejection.
+func (o Outcome) ChangesRocket() bool {
+	return o == OutcomeApplied || o == OutcomeRejected
+}
+
+// RocketEvent es un envelope tal y como llegó a un canal, junto con lo que
+// se hizo con él. Sequence es el orden de llegada dentro del canal.
+type RocketEvent struct {
+	Sequence      int             `json:"sequence"`
+	MessageNumber int             `json:"messageNumber"`
+	MessageType   string          `json:"messageType"`
+	MessageTime   string          `json:"messageTime"`
+	Payload       json.RawMessage `json:"payload"`
+	Outcome       Outcome         `json:"outcome"`
+	Reason        string          `json:"reason,omitempty"`
+	ReceivedAt    time.Time       `json:"receivedAt"`
+}
+
+// Envelope reconstruye el envelope original del evento.
+func (e RocketEvent) Envelope(channel string) MessageEnvelope {
+	var env MessageEnvelope
+	env.Metadata.Channel = channel
+	env.Metadata.MessageNum = e.MessageNumber
+	env.Metadata.MessageTime = e.MessageTime
+	env.Metadata.MessageType = e.MessageType
+	env.Message = e.Payload
+	return env
+}
+
+// PointInTime acota una reconstrucción histórica. Los campos a cero no
+// restringen nada.
+type PointInTime struct {
+	MessageNumber int
+	Time          time.Time
+}
+
+func (p PointInTime) IsZero() bool {
+	return p.MessageNumber == 0 && p.Time.IsZero()
+}
+
+// Includes indica si el evento ocurrió antes (o en) el punto indicado.
+func (p PointInTime) Includes(e RocketEvent) bool {
+	if p.MessageNumber > 0 && e.MessageNumber > p.MessageNumber {
+		return false
+	}
+	if !p.Time.IsZero() {
+		t, err := e.Envelope("").Time()
+		if err != nil || t.After(p.Time) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// RocketQuery es lo que se pide a un RocketReader al listar: qué cohetes
+// (Filter) y qué página de ellos.
+type RocketQuery struct {
+	PageRequest
+	Filter RocketFilter
+}
+
+// RocketFilter restringe un listado. Los campos a cero no restringen nada y
+// todos los que tengan valor deben cumplirse a la vez.
+type RocketFilter struct {
+	Status        RocketStatus
+	Type          string
+	Mission       string
+	MissionPrefix string
+	Speed         IntRange
+	UpdatedAt     TimeRange
+	EventTime     TimeRange
+	IngestedAt    TimeRange
+}
+
+// IntRange es un intervalo cerrado; un extremo nil queda abierto.
+type IntRange struct {
+	Min, Max *int64
+}
+
+// TimeRange es un intervalo cerrado; un extremo a cero queda abierto.
+type TimeRange struct {
+	From, To time.Time
+}
+
+func (f RocketFilter) IsZero() bool {
+	return f == RocketFilter{}
+}
+
+// Matches indica si el cohete pasa el filtro.
+func (f RocketFilter) Matches(r Rocket) bool {
+	switch {
+	case f.Status != "" && r.Status != f.Status:
+		return false
+	case f.Type != "" && r.Type != f.Type:
+		return false
+	case f.Mission != "" && r.Mission != f.Mission:
+		return false
+	case f.MissionPrefix != "" && !strings.HasPrefix(r.Mission, f.MissionPrefix):
+		return false
+	}
+	return f.Speed.Contains(r.Speed) &&
+		f.UpdatedAt.Contains(r.UpdatedAt) &&
+		f.EventTime.Contains(r.EventTime) &&
+		f.IngestedAt.Contains(r.IngestedAt)
+}
+
+func (r IntRange) Contains(v int64) bool {
+	return (r.Min == nil || v >= *r.Min) && (r.Max == nil || v <= *r.Max)
+}
+
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || !t.After(r.To))
+}
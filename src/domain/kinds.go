@@ -0,0 +1,96 @@
+package domain
+
+import "errors"
+
+// Tipos de mensaje de serie. Entre todos forman el ciclo de vida:
+//
+//	PENDING → LAUNCHED → IN_FLIGHT → EXPLODED | LANDED
+//
+// Los deltas en PENDING se aceptan (sin salir de PENDING) porque el
+// lanzamiento puede llegar más tarde que ellos.
+func init() {
+	Register(MessageKind[RocketLaunchedPayload]{
+		Name: TypeLaunched,
+		Validate: func(p RocketLaunchedPayload) error {
+			if p.Type == "" || p.Mission == "" || p.LaunchSpeed < 0 {
+				return errors.New("type and mission are required and launchSpeed must be >= 0")
+			}
+			return nil
+		},
+		Apply: func(r *Rocket, p RocketLaunchedPayload) {
+			r.Type = p.Type
+			r.Mission = p.Mission
+			if r.Speed < p.LaunchSpeed {
+				r.Speed = p.LaunchSpeed
+			} // no reducimos velocidad
+		},
+		Transitions: map[RocketStatus]RocketStatus{
+			StatusPending: StatusLaunched,
+		},
+	})
+
+	Register(MessageKind[RocketSpeedDeltaPayload]{
+		Name:        TypeSpeedIncreased,
+		Validate:    validatePositiveDelta,
+		Apply:       func(r *Rocket, p RocketSpeedDeltaPayload) { r.Speed += p.By },
+		Commutative: true,
+		Transitions: deltaTransitions,
+	})
+	Register(MessageKind[RocketSpeedDeltaPayload]{
+		Name:        TypeSpeedDecreased,
+		Validate:    validatePositiveDelta,
+		Apply:       func(r *Rocket, p RocketSpeedDeltaPayload) { r.Speed -= p.By },
+		Commutative: true,
+		Transitions: deltaTransitions,
+	})
+
+	Register(MessageKind[RocketMissionChangedPayload]{
+		Name: TypeMissionChanged,
+		Validate: func(p RocketMissionChangedPayload) error {
+			if p.NewMission == "" {
+				return errors.New("newMission is required")
+			}
+			return nil
+		},
+		Apply: func(r *Rocket, p RocketMissionChangedPayload) { r.Mission = p.NewMission },
+		Transitions: map[RocketStatus]RocketStatus{
+			StatusPending:  StatusPending,
+			StatusLaunched: StatusLaunched,
+			StatusInFlight: StatusInFlight,
+		},
+	})
+
+	// Explosión y aterrizaje sólo cambian el estado.
+	Register(MessageKind[struct{}]{
+		Name:  TypeExploded,
+		Apply: func(*Rocket, struct{}) {},
+		Transitions: map[RocketStatus]RocketStatus{
+			StatusPending:  StatusExploded,
+			StatusLaunched: StatusExploded,
+			StatusInFlight: StatusExploded,
+		},
+	})
+	Register(MessageKind[struct{}]{
+		Name:  TypeLanded,
+		Apply: func(*Rocket, struct{}) {},
+		Transitions: map[RocketStatus]RocketStatus{
+			StatusLaunched: StatusLanded,
+			StatusInFlight: StatusLanded,
+		},
+	})
+}
+
+// deltaTransitions son las de cualquier telemetría conmutativa: se acepta
+// antes del lanzamiento y, después, indica que el cohete está en vuelo.
+var deltaTransitions = map[RocketStatus]RocketStatus{
+	StatusPending:  StatusPending,
+	StatusLaunched: StatusInFlight,
+	StatusInFlight: StatusInFlight,
+}
+
+func validatePositiveDelta(p RocketSpeedDeltaPayload) error {
+	if p.By <= 0 {
+		return errors.New("by must be > 0")
+	}
+	return nil
+}
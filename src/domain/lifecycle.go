@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrIllegalTransition = errors.New("illegal transition")
+
+// nextStatus devuelve el estado al que lleva el mensaje según las
+// transiciones de su tipo, o ErrIllegalTransition.
+func nextStatus(from RocketStatus, k kind, name string) (RocketStatus, error) {
+	to, ok := k.transitions[from]
+	if !ok {
+		return from, fmt.Errorf("%w: %s not allowed in state %s", ErrIllegalTransition, name, from)
+	}
+	return to, nil
+}
@@ -1,8 +1,14 @@
 package domain
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type MessageEnvelope struct {
+	// ID es el del recibo que se dio al aceptar el envelope; viaja como UUID
+	// del mensaje de watermill, no en el JSON.
+	ID       string `json:"-"`
 	Metadata struct {
 		Channel     string `json:"channel"`
 		MessageNum  int    `json:"messageNumber"`
@@ -12,12 +18,22 @@ type MessageEnvelope struct {
 	Message json.RawMessage `json:"message"`
 }
 
+// Time devuelve el messageTime del envelope. Acepta RFC3339 y RFC3339Nano.
+func (env MessageEnvelope) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, env.Metadata.MessageTime)
+}
+
 const (
 	TypeLaunched       = "RocketLaunched"
 	TypeSpeedIncreased = "RocketSpeedIncreased"
 	TypeSpeedDecreased = "RocketSpeedDecreased"
 	TypeExploded       = "RocketExploded"
 	TypeMissionChanged = "RocketMissionChanged"
+	TypeLanded         = "RocketLanded"
+
+	TypeAltitudeChanged  = "RocketAltitudeChanged"
+	TypeFuelLevelChanged = "RocketFuelLevelChanged"
+	TypeStageSeparated   = "RocketStageSeparated"
 )
 
 type RocketLaunchedPayload struct {
@@ -33,3 +49,17 @@ type RocketSpeedDeltaPayload struct {
 type RocketMissionChangedPayload struct {
 	NewMission string `json:"newMission"`
 }
+
+// RocketAltitudeChangedPayload y RocketFuelLevelChangedPayload son deltas con
+// signo (positivo sube, negativo baja), nunca cero.
+type RocketAltitudeChangedPayload struct {
+	By int64 `json:"by"`
+}
+
+type RocketFuelLevelChangedPayload struct {
+	By int64 `json:"by"`
+}
+
+type RocketStageSeparatedPayload struct {
+	Stage int `json:"stage"`
+}
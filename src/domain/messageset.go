@@ -0,0 +1,98 @@
+package domain
+
+import "sort"
+
+// MessageSet recuerda qué messageNumbers se han visto en un canal sin crecer
+// con el tráfico: todo lo que está por debajo (o igual) de Watermark ya se
+// vio, y lo que llegó adelantado se guarda como rangos disjuntos por encima.
+// Con entrega ordenada o casi ordenada ocupa un puñado de rangos. Add nunca
+// escribe en el array de Above, así que dos copias del valor pueden
+// compartirlo sin clonar.
+type MessageSet struct {
+	Watermark int            `json:"watermark"`
+	Above     []MessageRange `json:"above,omitempty"` // ordenados, disjuntos y no adyacentes
+}
+
+// MessageRange es un rango cerrado [From, To] de messageNumbers.
+type MessageRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (s *MessageSet) Has(n int) bool {
+	if n <= s.Watermark {
+		return true
+	}
+	i := sort.Search(len(s.Above), func(i int) bool { return s.Above[i].To >= n })
+	return i < len(s.Above) && s.Above[i].From <= n
+}
+
+// Add marca n como visto; devuelve false si ya lo estaba.
+func (s *MessageSet) Add(n int) bool {
+	if s.Has(n) {
+		return false
+	}
+	// Lo normal: el siguiente a la marca. No hace falta tocar Above.
+	if n == s.Watermark+1 {
+		s.Watermark = n
+		s.absorb()
+		return true
+	}
+	i := sort.Search(len(s.Above), func(i int) bool { return s.Above[i].To >= n })
+	// Copy-on-write: el array puede compartirse con otra copia.
+	s.Above = append(make([]MessageRange, 0, len(s.Above)+1), s.Above...)
+
+	joinsPrev := i > 0 && s.Above[i-1].To == n-1
+	joinsNext := i < len(s.Above) && s.Above[i].From == n+1
+	switch {
+	case joinsPrev && joinsNext:
+		s.Above[i-1].To = s.Above[i].To
+		s.Above = append(s.Above[:i], s.Above[i+1:]...)
+	case joinsPrev:
+		s.Above[i-1].To = n
+	case joinsNext:
+		s.Above[i].From = n
+	default:
+		s.Above = append(s.Above, MessageRange{})
+		copy(s.Above[i+1:], s.Above[i:])
+		s.Above[i] = MessageRange{From: n, To: n}
+	}
+
+	s.absorb()
+	return true
+}
+
+// absorb sube la marca si el primer rango ya es contiguo a ella.
+func (s *MessageSet) absorb() {
+	if len(s.Above) > 0 && s.Above[0].From == s.Watermark+1 {
+		s.Watermark = s.Above[0].To
+		s.Above = s.Above[1:]
+	}
+	if len(s.Above) == 0 {
+		s.Above = nil
+	}
+}
+
+// Missing devuelve los números sin ver en [1, upTo).
+func (s *MessageSet) Missing(upTo int) []int {
+	var out []int
+	next := s.Watermark + 1
+	for _, r := range s.Above {
+		for n := next; n < r.From && n < upTo; n++ {
+			out = append(out, n)
+		}
+		next = r.To + 1
+	}
+	for n := next; n < upTo; n++ {
+		out = append(out, n)
+	}
+	return out
+}
+
+// Clone devuelve una copia que no comparte memoria con s.
+func (s MessageSet) Clone() MessageSet {
+	if s.Above != nil {
+		s.Above = append(make([]MessageRange, 0, len(s.Above)), s.Above...)
+	}
+	return s
+}
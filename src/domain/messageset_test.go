@@ -0,0 +1,126 @@
+package domain_test
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"lunar/src/domain"
+)
+
+func TestMessageSet_ExactlyOnce(t *testing.T) {
+	var s domain.MessageSet
+	order := []int{3, 1, 5, 2, 4, 9, 7, 8, 6}
+	for _, n := range order {
+		if !s.Add(n) {
+			t.Fatalf("add(%d) reported duplicate on first sight", n)
+		}
+	}
+	for _, n := range order {
+		if s.Add(n) {
+			t.Fatalf("add(%d) accepted a duplicate", n)
+		}
+	}
+	if s.Watermark != 9 || len(s.Above) != 0 {
+		t.Errorf("set not compacted; watermark=%d above=%v", s.Watermark, s.Above)
+	}
+}
+
+func TestMessageSet_MergesIntervalsAndReportsMissing(t *testing.T) {
+	var s domain.MessageSet
+	for _, n := range []int{1, 2, 5, 7, 6, 10} {
+		s.Add(n)
+	}
+	if s.Watermark != 2 {
+		t.Fatalf("watermark; got=%d want=2", s.Watermark)
+	}
+	if want := []domain.MessageRange{{From: 5, To: 7}, {From: 10, To: 10}}; fmt.Sprint(s.Above) != fmt.Sprint(want) {
+		t.Errorf("intervals; got=%v want=%v", s.Above, want)
+	}
+	if got, want := s.Missing(10), []int{3, 4, 8, 9}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("missing; got=%v want=%v", got, want)
+	}
+	for _, n := range []int{1, 5, 6, 10} {
+		if !s.Has(n) {
+			t.Errorf("has(%d) = false", n)
+		}
+	}
+	for _, n := range []int{3, 8, 11} {
+		if s.Has(n) {
+			t.Errorf("has(%d) = true", n)
+		}
+	}
+}
+
+func TestMessageSet_CopiesShareNothingObservable(t *testing.T) {
+	var orig domain.MessageSet
+	for _, n := range []int{1, 5, 6, 10} {
+		orig.Add(n)
+	}
+	want := fmt.Sprint(orig)
+
+	// Una copia superficial comparte Above; Add sobre ella no puede tocarlo.
+	for _, n := range []int{2, 3, 4, 7, 8, 9, 11, 12} {
+		cp := orig
+		cp.Add(n)
+		if got := fmt.Sprint(orig); got != want {
+			t.Fatalf("add(%d) on a copy changed the original; got=%s want=%s", n, got, want)
+		}
+	}
+}
+
+// BenchmarkDedup mide la memoria que retiene el estado de deduplicación de un
+// canal tras n mensajes (entregados con un desorden local de hasta 64
+// posiciones). El MessageSet se mantiene plano; el map de antes crece con n.
+//
+//	go test ./src/domain -run '^$' -bench Dedup -benchtime 1x
+func BenchmarkDedup(b *testing.B) {
+	for _, n := range []int{10_000, 1_000_000, 4_000_000} {
+		seq := shuffledWindow(n, 64)
+
+		b.Run(fmt.Sprintf("MessageSet/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				before := heapInUse()
+				s := &domain.MessageSet{}
+				for _, num := range seq {
+					s.Add(num)
+				}
+				b.ReportMetric(float64(heapInUse()-before), "retained-B")
+				runtime.KeepAlive(s)
+			}
+		})
+
+		b.Run(fmt.Sprintf("map/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				before := heapInUse()
+				m := make(map[int]struct{})
+				for _, num := range seq {
+					m[num] = struct{}{}
+				}
+				b.ReportMetric(float64(heapInUse()-before), "retained-B")
+				runtime.KeepAlive(m)
+			}
+		})
+	}
+}
+
+func shuffledWindow(n, window int) []int {
+	rng := rand.New(rand.NewSource(1))
+	seq := make([]int, n)
+	for i := range seq {
+		seq[i] = i + 1
+	}
+	for lo := 0; lo < n; lo += window {
+		hi := min(lo+window, n)
+		rng.Shuffle(hi-lo, func(i, j int) { seq[lo+i], seq[lo+j] = seq[lo+j], seq[lo+i] })
+	}
+	return seq
+}
+
+func heapInUse() int64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return int64(ms.HeapInuse)
+}
@@ -5,16 +5,50 @@ import "time"
 type RocketStatus string
 
 const (
-	StatusActive   RocketStatus = "ACTIVE"
+	StatusPending  RocketStatus = "PENDING"
+	StatusLaunched RocketStatus = "LAUNCHED"
+	StatusInFlight RocketStatus = "IN_FLIGHT"
 	StatusExploded RocketStatus = "EXPLODED"
+	StatusLanded   RocketStatus = "LANDED"
 )
 
 type Rocket struct {
-	Channel    string
-	Type       string
-	Mission    string
-	Speed      int64
-	Status     RocketStatus
+	Channel string
+	Type    string
+	Mission string
+	Speed   int64
+	Status  RocketStatus
+
+	Altitude        int64
+	FuelLevel       int64
+	StagesSeparated int
+
 	LastMsgNum int
 	UpdatedAt  time.Time
+
+	// EventTime es el messageTime más reciente entre los mensajes aplicados;
+	// IngestedAt, cuándo se aplicó el último de ellos.
+	EventTime  time.Time
+	IngestedAt time.Time
+
+	// LastRejection es el último mensaje rechazado por el ciclo de vida.
+	LastRejection *Rejection
+
+	// Processed son los messageNumbers ya vistos; sólo lo usa Apply.
+	Processed MessageSet `json:"-"`
+}
+
+type Rejection struct {
+	MessageNumber int
+	MessageType   string
+	Reason        string
+}
+
+// OrderingGaps describe la reordenación de un canal en modo estricto: qué
+// número se espera, cuáles se dieron por perdidos y cuáles esperan en buffer.
+type OrderingGaps struct {
+	Channel      string `json:"channel"`
+	NextExpected int    `json:"nextExpected"`
+	Missing      []int  `json:"missing"`
+	Buffered     []int  `json:"buffered"`
 }
@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageRequest pide una página de la flota ordenada por SortBy y Order. Sin
+// Cursor empieza por el principio; con Limit 0 no hay límite.
+type PageRequest struct {
+	SortBy string
+	Order  string
+	Limit  int
+	Cursor *Cursor
+}
+
+// Cursor es una posición en un listado: la que ocupaba el cohete Channel
+// cuando su clave de ordenación valía Value. Al basarse en valores y no en
+// desplazamientos, las páginas no se corren cuando otros cohetes cambian.
+// Con Backward la página es la que va justo antes de la posición.
+type Cursor struct {
+	SortBy   string `json:"s"`
+	Order    string `json:"o"`
+	Channel  string `json:"c"`
+	Value    string `json:"v"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Page es el resultado de un PageRequest. Next y Prev son nil cuando no hay
+// nada más en ese sentido.
+type Page struct {
+	Items []Rocket
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+// Forward indica si la página se recorre en el mismo sentido que Order.
+func (p PageRequest) Forward() bool {
+	return p.Cursor == nil || !p.Cursor.Backward
+}
+
+// Descending indica si hay que leer de mayor a menor: Order, invertido si el
+// cursor va hacia atrás.
+func (p PageRequest) Descending() bool {
+	return (p.Order == "desc") == p.Forward()
+}
+
+// NewPage arma la página con lo leído en el sentido del recorrido (al revés
+// si el cursor es Backward). Si hay más de Limit cohetes, el sobrante sólo
+// indica que hay otra página.
+func NewPage(req PageRequest, fetched []Rocket) Page {
+	more := req.Limit > 0 && len(fetched) > req.Limit
+	if more {
+		fetched = fetched[:req.Limit]
+	}
+	page := Page{Items: fetched}
+	if !req.Forward() {
+		for i, j := 0, len(fetched)-1; i < j; i, j = i+1, j-1 {
+			fetched[i], fetched[j] = fetched[j], fetched[i]
+		}
+	}
+	if len(fetched) == 0 {
+		// Página vacía tras un cursor: se puede volver por donde se vino.
+		if req.Cursor != nil {
+			back := *req.Cursor
+			back.Backward = !back.Backward
+			if req.Forward() {
+				page.Prev = &back
+			} else {
+				page.Next = &back
+			}
+		}
+		return page
+	}
+	first, last := fetched[0], fetched[len(fetched)-1]
+	if (req.Forward() && more) || (!req.Forward() && req.Cursor != nil) {
+		page.Next = newCursor(last, req, false)
+	}
+	if (!req.Forward() && more) || (req.Forward() && req.Cursor != nil) {
+		page.Prev = newCursor(first, req, true)
+	}
+	return page
+}
+
+func newCursor(r Rocket, req PageRequest, backward bool) *Cursor {
+	return &Cursor{
+		SortBy:   req.SortBy,
+		Order:    req.Order,
+		Channel:  r.Channel,
+		Value:    formatSortValue(r.SortValue(req.SortBy)),
+		Backward: backward,
+	}
+}
+
+// SortValue devuelve el valor de la clave de ordenación sortBy: string,
+// int64, int o time.Time. Una clave desconocida devuelve el canal.
+func (r Rocket) SortValue(sortBy string) any {
+	switch sortBy {
+	case "speed":
+		return r.Speed
+	case "updated_at":
+		return r.UpdatedAt
+	case "event_time":
+		return r.EventTime
+	case "ingested_at":
+		return r.IngestedAt
+	case "altitude":
+		return r.Altitude
+	case "fuel_level":
+		return r.FuelLevel
+	case "stages_separated":
+		return r.StagesSeparated
+	}
+	return r.Channel
+}
+
+func formatSortValue(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// Pivot devuelve un cohete con sólo el canal y la clave de ordenación del
+// cursor, para compararlo con los del listado.
+func (c Cursor) Pivot() (Rocket, error) {
+	r := Rocket{Channel: c.Channel}
+	var err error
+	switch c.SortBy {
+	case "speed":
+		r.Speed, err = strconv.ParseInt(c.Value, 10, 64)
+	case "updated_at":
+		r.UpdatedAt, err = time.Parse(time.RFC3339Nano, c.Value)
+	case "event_time":
+		r.EventTime, err = time.Parse(time.RFC3339Nano, c.Value)
+	case "ingested_at":
+		r.IngestedAt, err = time.Parse(time.RFC3339Nano, c.Value)
+	case "altitude":
+		r.Altitude, err = strconv.ParseInt(c.Value, 10, 64)
+	case "fuel_level":
+		r.FuelLevel, err = strconv.ParseInt(c.Value, 10, 64)
+	case "stages_separated":
+		r.StagesSeparated, err = strconv.Atoi(c.Value)
+	}
+	if err != nil {
+		return Rocket{}, ErrInvalidCursor
+	}
+	return r, nil
+}
+
+// Encode devuelve el cursor como texto opaco para la API.
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseCursor es la inversa de Encode.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Channel == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if _, err := c.Pivot(); err != nil {
+		return Cursor{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,23 @@
+package port
+
+import "lunar/src/domain"
+
+// ChangeFeed reparte los cambios de cohetes entre quienes se suscriben.
+type ChangeFeed interface {
+	// Subscribe entrega, en orden, los cambios posteriores a after que pasen
+	// match, empezando por los que aún guarde en memoria. Con after cero sólo
+	// llegan los nuevos.
+	Subscribe(after domain.ChangeID, match func(domain.Rocket) bool) ChangeSubscription
+}
+
+type ChangeSubscription interface {
+	// Changes se cierra al llamar a Close o si el suscriptor no da abasto.
+	Changes() <-chan domain.RocketChange
+	// Complete es false si faltan cambios entre after y el primero entregado
+	// porque ya no estaban en memoria o after es de otro epoch.
+	Complete() bool
+	// Sync deja en Changes todo lo publicado hasta ahora que pase match; sin
+	// él, lo que aún está en cola en el feed puede llegar más tarde.
+	Sync()
+	Close()
+}
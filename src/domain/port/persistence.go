@@ -1,6 +1,10 @@
 package port
 
-import "lunar/src/domain"
+import (
+	"time"
+
+	"lunar/src/domain"
+)
 
 type MessageWriter interface {
 	Apply(env domain.MessageEnvelope) error
@@ -8,10 +12,32 @@ type MessageWriter interface {
 
 type RocketReader interface {
 	Get(channel string) (domain.Rocket, bool, error)
-	List(sortBy string, order string) ([]domain.Rocket, error)
+	List(q domain.RocketQuery) (domain.Page, error)
+}
+
+// EventReader devuelve el histórico de envelopes de un canal ordenado por
+// messageNumber (y por llegada en caso de duplicados).
+type EventReader interface {
+	Events(channel string) ([]domain.RocketEvent, bool, error)
+}
+
+// RocketHistoryReader reconstruye el estado de un cohete en un punto pasado.
+type RocketHistoryReader interface {
+	GetAsOf(channel string, point domain.PointInTime) (domain.Rocket, bool, error)
+}
+
+// GapReader informa de los mensajes que faltan en un canal.
+type GapReader interface {
+	Gaps(channel string) (domain.OrderingGaps, bool, error)
 }
 
 type Persistence interface {
 	MessageWriter
 	RocketReader
 }
+
+// StatsReader devuelve los agregados de la flota; window es la ventana de
+// FleetStats.UpdatedRecently.
+type StatsReader interface {
+	Stats(window time.Duration) (domain.FleetStats, error)
+}
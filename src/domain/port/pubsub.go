@@ -4,4 +4,7 @@ import "lunar/src/domain"
 
 type MessagePublisher interface {
 	Publish(topic string, env domain.MessageEnvelope) error
+	// PublishBatch publica los envelopes como un único mensaje: el consumer
+	// los recibe todos, en ese orden, o no recibe ninguno.
+	PublishBatch(topic string, envs []domain.MessageEnvelope) error
 }
@@ -0,0 +1,25 @@
+package port
+
+import "lunar/src/domain"
+
+// ReceiptWriter sigue los mensajes aceptados hasta que el consumer los
+// procesa.
+type ReceiptWriter interface {
+	// Queue da de alta un recibo en cola para env y lo devuelve. Usa env.ID
+	// si ya trae uno.
+	Queue(env domain.MessageEnvelope) domain.Receipt
+	// Resolve cierra el recibo id; los desconocidos o ya cerrados se ignoran.
+	Resolve(id string, res domain.MessageResult)
+}
+
+type ReceiptReader interface {
+	Get(id string) (domain.Receipt, bool)
+}
+
+// ReceiptWaiter deja esperar a que se cierre un recibo.
+type ReceiptWaiter interface {
+	// Expect reserva un ID para usarlo como MessageEnvelope.ID antes de
+	// publicar y devuelve por dónde llegará su resultado; stop deja de
+	// esperarlo.
+	Expect() (id string, result <-chan domain.MessageResult, stop func())
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// ReceiptStatus es el estado de un mensaje aceptado: en cola hasta que el
+// consumer lo procesa y, después, lo que hizo el store con él.
+type ReceiptStatus string
+
+const (
+	ReceiptQueued    ReceiptStatus = "queued"
+	ReceiptApplied                 = ReceiptStatus(OutcomeApplied)
+	ReceiptDuplicate               = ReceiptStatus(OutcomeDuplicate)
+	ReceiptStale                   = ReceiptStatus(OutcomeStale)
+	ReceiptRejected                = ReceiptStatus(OutcomeRejected)
+)
+
+// Receipt sigue un mensaje desde que se acepta hasta que se procesa. Reason
+// explica un rechazo, venga de las reglas del cohete o de un error al
+// aplicarlo.
+type Receipt struct {
+	ID            string        `json:"id"`
+	Status        ReceiptStatus `json:"status"`
+	Reason        string        `json:"reason,omitempty"`
+	Channel       string        `json:"channel"`
+	MessageNumber int           `json:"messageNumber"`
+	AcceptedAt    time.Time     `json:"acceptedAt"`
+	ProcessedAt   *time.Time    `json:"processedAt,omitempty"`
+}
+
+// MessageResult es lo que hizo el store con un envelope y cómo quedó el
+// cohete después.
+type MessageResult struct {
+	Outcome Outcome
+	Reason  string
+	Rocket  Rocket
+}
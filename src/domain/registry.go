@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// MessageKind describe un tipo de mensaje con payload P: cómo se valida, qué
+// le hace al cohete y en qué estados del ciclo de vida es legal.
+type MessageKind[P any] struct {
+	Name string
+	// Validate comprueba las reglas del payload ya decodificado. Puede ser nil.
+	Validate func(P) error
+	// Apply muta el cohete. El estado (Status) lo fija Transitions.
+	Apply func(*Rocket, P)
+	// Commutative indica que el efecto no depende del orden de llegada, así
+	// que un mensaje atrasado se aplica en lugar de descartarse como stale.
+	Commutative bool
+	// Transitions dice, para cada estado en el que el mensaje es legal, a qué
+	// estado lleva. En cualquier otro estado el mensaje se rechaza.
+	Transitions map[RocketStatus]RocketStatus
+}
+
+// kind es la versión sin tipar de MessageKind que guarda el registro.
+type kind struct {
+	decode      func(json.RawMessage) (func(*Rocket), error)
+	commutative bool
+	transitions map[RocketStatus]RocketStatus
+}
+
+var registry = struct {
+	sync.RWMutex
+	kinds map[string]kind
+}{kinds: make(map[string]kind)}
+
+// Register da de alta un tipo de mensaje. Pensado para llamarse desde init;
+// registrar dos veces el mismo nombre es un error de programación.
+func Register[P any](k MessageKind[P]) {
+	if k.Name == "" || k.Apply == nil {
+		panic("domain: message kind needs a name and an Apply func")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, dup := registry.kinds[k.Name]; dup {
+		panic(fmt.Sprintf("domain: message kind %s registered twice", k.Name))
+	}
+	registry.kinds[k.Name] = kind{
+		decode: func(raw json.RawMessage) (func(*Rocket), error) {
+			var p P
+			if len(raw) > 0 {
+				if err := json.Unmarshal(raw, &p); err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+				}
+			}
+			if k.Validate != nil {
+				if err := k.Validate(p); err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+				}
+			}
+			return func(r *Rocket) { k.Apply(r, p) }, nil
+		},
+		commutative: k.Commutative,
+		transitions: k.Transitions,
+	}
+}
+
+// IsKnownMessageType indica si hay un tipo registrado con ese nombre.
+func IsKnownMessageType(name string) bool {
+	_, ok := lookupKind(name)
+	return ok
+}
+
+// MessageTypes devuelve los nombres registrados, ordenados.
+func MessageTypes() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.kinds))
+	for name := range registry.kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidatePayload decodifica y valida el payload según su tipo registrado.
+func ValidatePayload(name string, raw json.RawMessage) error {
+	k, ok := lookupKind(name)
+	if !ok {
+		return ErrUnknownMessageType
+	}
+	_, err := k.decode(raw)
+	return err
+}
+
+func lookupKind(name string) (kind, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	k, ok := registry.kinds[name]
+	return k, ok
+}
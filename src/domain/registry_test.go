@@ -0,0 +1,78 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"lunar/src/domain"
+)
+
+// Un tipo nuevo es una sola registración: payload, validación, efecto y
+// transiciones.
+type rocketPaintedPayload struct {
+	Color string `json:"color"`
+}
+
+const typePainted = "TestRocketPainted"
+
+func init() {
+	domain.Register(domain.MessageKind[rocketPaintedPayload]{
+		Name: typePainted,
+		Validate: func(p rocketPaintedPayload) error {
+			if p.Color == "" {
+				return errors.New("color is required")
+			}
+			return nil
+		},
+		Apply: func(r *domain.Rocket, p rocketPaintedPayload) { r.Mission = "painted " + p.Color },
+		Transitions: map[domain.RocketStatus]domain.RocketStatus{
+			domain.StatusPending: domain.StatusPending,
+		},
+	})
+}
+
+func TestRegistry_BuiltinKindsRegistered(t *testing.T) {
+	for _, name := range []string{
+		domain.TypeLaunched, domain.TypeSpeedIncreased, domain.TypeSpeedDecreased,
+		domain.TypeMissionChanged, domain.TypeExploded, domain.TypeLanded,
+	} {
+		if !domain.IsKnownMessageType(name) {
+			t.Errorf("%s not registered", name)
+		}
+	}
+	if !slices.Contains(domain.MessageTypes(), typePainted) {
+		t.Errorf("custom kind missing from MessageTypes()")
+	}
+}
+
+func TestRegistry_CustomKindDrivesValidationAndApply(t *testing.T) {
+	if err := domain.ValidatePayload(typePainted, json.RawMessage(`{"color":""}`)); !errors.Is(err, domain.ErrInvalidPayload) {
+		t.Errorf("got err=%v, want %v", err, domain.ErrInvalidPayload)
+	}
+	if err := domain.ValidatePayload(typePainted, json.RawMessage(`{"color":"red"}`)); err != nil {
+		t.Errorf("unexpected err=%v", err)
+	}
+
+	r := domain.NewRocket("c1")
+	mustApply(t, r, env("c1", 1, typePainted, rocketPaintedPayload{Color: "red"}))
+	if r.Mission != "painted red" || r.Status != domain.StatusPending {
+		t.Errorf("custom kind not applied; got=%+v", r)
+	}
+
+	// Sus transiciones también mandan: tras el lanzamiento ya no es legal.
+	mustApply(t, r, env("c1", 2, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "F9", LaunchSpeed: 1, Mission: "M"}))
+	if got, _ := r.Apply(env("c1", 3, typePainted, rocketPaintedPayload{Color: "blue"})); got != domain.OutcomeRejected {
+		t.Errorf("outcome=%s want=%s", got, domain.OutcomeRejected)
+	}
+}
+
+func TestRegistry_DuplicateRegistrationPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on duplicate registration")
+		}
+	}()
+	domain.Register(domain.MessageKind[struct{}]{Name: domain.TypeExploded, Apply: func(*domain.Rocket, struct{}) {}})
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrChannelMismatch    = errors.New("envelope belongs to another channel")
+	ErrUnknownMessageType = errors.New("unknown messageType")
+)
+
+func NewRocket(channel string) *Rocket {
+	return &Rocket{Channel: channel, Status: StatusPending}
+}
+
+// Apply pliega un envelope sobre el cohete y dice qué se hizo con él. Aquí
+// viven las reglas de negocio: idempotencia por messageNumber, descarte de
+// eventos no conmutativos más antiguos que el último aplicado, el ciclo de
+// vida y el efecto de cada tipo de mensaje. Un mensaje ilegal para el estado
+// actual se rechaza y queda anotado en LastRejection. Si devuelve error el
+// cohete no se ha modificado.
+func (r *Rocket) Apply(env MessageEnvelope) (Outcome, error) {
+	num, kind := env.Metadata.MessageNum, env.Metadata.MessageType
+	if env.Metadata.Channel != r.Channel {
+		return "", ErrChannelMismatch
+	}
+
+	k, ok := lookupKind(kind)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownMessageType, kind)
+	}
+
+	// idempotencia
+	if r.Processed.Has(num) {
+		return OutcomeDuplicate, nil
+	}
+
+	// Para eventos no conmutativos: ignora si es más antiguo que el último aplicado
+	if !k.commutative && num < r.LastMsgNum {
+		r.Processed.Add(num)
+		return OutcomeStale, nil // no “deshacemos” estado
+	}
+
+	// Se decodifica antes de tocar nada: un payload roto no deja rastro.
+	mutate, err := k.decode(env.Message)
+	if err != nil {
+		return "", err
+	}
+
+	// Un delta atrasado ocurrió antes que lo ya aplicado: cuenta para la
+	// velocidad pero no mueve el ciclo de vida.
+	status := r.Status
+	if num > r.LastMsgNum {
+		if status, err = nextStatus(r.Status, k, kind); err != nil {
+			r.Processed.Add(num)
+			r.LastRejection = &Rejection{MessageNumber: num, MessageType: kind, Reason: err.Error()}
+			return OutcomeRejected, nil
+		}
+	}
+	r.Processed.Add(num)
+	mutate(r)
+	r.Status = status
+	if num > r.LastMsgNum {
+		r.LastMsgNum = num
+	}
+	// Un mensaje que llega tarde no hace retroceder EventTime.
+	if t, err := env.Time(); err == nil && t.After(r.EventTime) {
+		r.EventTime = t
+	}
+	return OutcomeApplied, nil
+}
+
+// Clone devuelve una copia que no comparte memoria con r.
+func (r Rocket) Clone() Rocket {
+	r.Processed = r.Processed.Clone()
+	return r
+}
@@ -0,0 +1,162 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"lunar/src/domain"
+)
+
+func TestRocketApply_Outcomes(t *testing.T) {
+	ch := "193270a9-c9cf-404a-8f83-838e71d9ae67"
+	r := domain.NewRocket(ch)
+
+	steps := []struct {
+		name string
+		env  domain.MessageEnvelope
+		want domain.Outcome
+	}{
+		{"launch", env(ch, 1, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}), domain.OutcomeApplied},
+		{"newer_mission", env(ch, 4, domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "GEMINI"}), domain.OutcomeApplied},
+		{"older_mission_is_stale", env(ch, 3, domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "APOLLO"}), domain.OutcomeStale},
+		{"older_delta_still_applies", env(ch, 2, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}), domain.OutcomeApplied},
+		{"duplicate", env(ch, 2, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}), domain.OutcomeDuplicate},
+		{"stale_is_remembered", env(ch, 3, domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "APOLLO"}), domain.OutcomeDuplicate},
+		{"explode", env(ch, 5, domain.TypeExploded, struct{}{}), domain.OutcomeApplied},
+	}
+	for _, st := range steps {
+		got, err := r.Apply(st.env)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", st.name, err)
+		}
+		if got != st.want {
+			t.Errorf("%s: outcome=%s want=%s", st.name, got, st.want)
+		}
+	}
+
+	if r.Speed != 800 || r.Mission != "GEMINI" || r.Status != domain.StatusExploded || r.LastMsgNum != 5 {
+		t.Errorf("final rocket mismatch; got=%+v", r)
+	}
+}
+
+func TestRocketApply_LaunchDoesNotReduceSpeed(t *testing.T) {
+	r := domain.NewRocket("c1")
+	mustApply(t, r, env("c1", 1, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 900}))
+	mustApply(t, r, env("c1", 2, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "F9", LaunchSpeed: 500, Mission: "M"}))
+
+	if r.Speed != 900 || r.Type != "F9" {
+		t.Errorf("launch after increase; got=%+v", r)
+	}
+}
+
+func TestRocketApply_ErrorsLeaveRocketUntouched(t *testing.T) {
+	r := domain.NewRocket("c1")
+	mustApply(t, r, env("c1", 1, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}))
+	before := r.Clone()
+
+	broken := env("c1", 2, domain.TypeSpeedIncreased, nil)
+	broken.Message = json.RawMessage(`{"by":`)
+	if _, err := r.Apply(broken); err == nil {
+		t.Errorf("expected error for broken payload")
+	}
+	if _, err := r.Apply(env("other", 3, domain.TypeExploded, struct{}{})); !errors.Is(err, domain.ErrChannelMismatch) {
+		t.Errorf("got err=%v, want %v", err, domain.ErrChannelMismatch)
+	}
+	if _, err := r.Apply(env("c1", 4, "RocketTeleported", struct{}{})); !errors.Is(err, domain.ErrUnknownMessageType) {
+		t.Errorf("got err=%v, want %v", err, domain.ErrUnknownMessageType)
+	}
+	if !reflect.DeepEqual(*r, before) {
+		t.Errorf("rocket modified by failed applies; got=%+v want=%+v", *r, before)
+	}
+
+	// El mensaje roto no quedó marcado: puede llegar bien más tarde.
+	mustApply(t, r, env("c1", 2, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 50}))
+	if r.Speed != 150 {
+		t.Errorf("retry after broken payload; speed=%d want=150", r.Speed)
+	}
+}
+
+func TestRocketApply_Lifecycle(t *testing.T) {
+	r := domain.NewRocket("c1")
+	if r.Status != domain.StatusPending {
+		t.Fatalf("new rocket status=%s want=%s", r.Status, domain.StatusPending)
+	}
+
+	steps := []struct {
+		env  domain.MessageEnvelope
+		want domain.RocketStatus
+	}{
+		{env("c1", 1, domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "M0"}), domain.StatusPending},
+		{env("c1", 2, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "F9", LaunchSpeed: 500, Mission: "M1"}), domain.StatusLaunched},
+		{env("c1", 3, domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "M2"}), domain.StatusLaunched},
+		{env("c1", 4, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}), domain.StatusInFlight},
+		{env("c1", 5, domain.TypeLanded, struct{}{}), domain.StatusLanded},
+	}
+	for _, st := range steps {
+		if got, err := r.Apply(st.env); err != nil || got != domain.OutcomeApplied {
+			t.Fatalf("apply %d: outcome=%s err=%v", st.env.Metadata.MessageNum, got, err)
+		}
+		if r.Status != st.want {
+			t.Errorf("after %s: status=%s want=%s", st.env.Metadata.MessageType, r.Status, st.want)
+		}
+	}
+}
+
+func TestRocketApply_RejectsIllegalTransitions(t *testing.T) {
+	r := domain.NewRocket("c1")
+	mustApply(t, r, env("c1", 1, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "F9", LaunchSpeed: 500, Mission: "M1"}))
+	mustApply(t, r, env("c1", 3, domain.TypeExploded, struct{}{}))
+
+	for _, e := range []domain.MessageEnvelope{
+		env("c1", 4, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}),
+		env("c1", 5, domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "M2"}),
+	} {
+		got, err := r.Apply(e)
+		if err != nil || got != domain.OutcomeRejected {
+			t.Fatalf("apply %d after explosion: outcome=%s err=%v", e.Metadata.MessageNum, got, err)
+		}
+	}
+	if r.Speed != 500 || r.Mission != "M1" || r.LastMsgNum != 3 {
+		t.Errorf("rejected messages changed state; got=%+v", r)
+	}
+	if r.LastRejection == nil || r.LastRejection.MessageNumber != 5 || r.LastRejection.Reason == "" {
+		t.Errorf("rejection not recorded; got=%+v", r.LastRejection)
+	}
+
+	// Un delta anterior a la explosión llega tarde: sí ocurrió, así que cuenta.
+	if got, _ := r.Apply(env("c1", 2, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 50})); got != domain.OutcomeApplied {
+		t.Errorf("late delta before explosion; outcome=%s want=%s", got, domain.OutcomeApplied)
+	}
+	if r.Speed != 550 || r.Status != domain.StatusExploded {
+		t.Errorf("late delta; got=%+v", r)
+	}
+
+	// Un relanzamiento nunca es legal.
+	launched := domain.NewRocket("c2")
+	mustApply(t, launched, env("c2", 1, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "F9", LaunchSpeed: 500, Mission: "M1"}))
+	if got, _ := launched.Apply(env("c2", 2, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "F9", LaunchSpeed: 900, Mission: "M1"})); got != domain.OutcomeRejected {
+		t.Errorf("second launch; outcome=%s want=%s", got, domain.OutcomeRejected)
+	}
+}
+
+// ---------- helpers ----------
+
+func env(channel string, num int, kind string, payload any) domain.MessageEnvelope {
+	raw, _ := json.Marshal(payload)
+	var e domain.MessageEnvelope
+	e.Metadata.Channel = channel
+	e.Metadata.MessageNum = num
+	e.Metadata.MessageTime = "2022-02-02T19:39:05Z"
+	e.Metadata.MessageType = kind
+	e.Message = raw
+	return e
+}
+
+func mustApply(t *testing.T, r *domain.Rocket, e domain.MessageEnvelope) {
+	t.Helper()
+	if _, err := r.Apply(e); err != nil {
+		t.Fatalf("apply %d failed: %v", e.Metadata.MessageNum, err)
+	}
+}
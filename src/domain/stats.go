@@ -0,0 +1,73 @@
+package domain
+
+import "time"
+
+// FleetStats resume la flota entera. Los cohetes sin tipo o sin misión
+// (aún no lanzados) no cuentan en ByType ni en ByMission.
+type FleetStats struct {
+	Total           int                  `json:"total"`
+	ByStatus        map[RocketStatus]int `json:"byStatus"`
+	ByType          map[string]int       `json:"byType"`
+	ByMission       map[string]int       `json:"byMission"`
+	Speed           SpeedStats           `json:"speed"`
+	UpdatedRecently RecentUpdates        `json:"updatedRecently"`
+}
+
+// SpeedStats describe la distribución de velocidades. Los percentiles son de
+// rango más cercano: siempre la velocidad de algún cohete de la flota. Con la
+// flota vacía todo vale cero.
+type SpeedStats struct {
+	Min  int64   `json:"min"`
+	Max  int64   `json:"max"`
+	Mean float64 `json:"mean"`
+	P50  int64   `json:"p50"`
+	P90  int64   `json:"p90"`
+	P95  int64   `json:"p95"`
+	P99  int64   `json:"p99"`
+}
+
+// RecentUpdates cuenta los cohetes con UpdatedAt en los últimos Minutes
+// minutos.
+type RecentUpdates struct {
+	Minutes int `json:"minutes"`
+	Count   int `json:"count"`
+}
+
+// NewFleetStats devuelve unas estadísticas vacías, con los mapas creados, para
+// una ventana de cohetes recientes de window.
+func NewFleetStats(window time.Duration) FleetStats {
+	return FleetStats{
+		ByStatus:        map[RocketStatus]int{},
+		ByType:          map[string]int{},
+		ByMission:       map[string]int{},
+		UpdatedRecently: RecentUpdates{Minutes: int(window / time.Minute)},
+	}
+}
+
+// PercentileRank es la posición (desde 0, de menor a mayor) del percentil p
+// entre n valores según el método del rango más cercano.
+func PercentileRank(p, n int) int {
+	if n == 0 {
+		return 0
+	}
+	rank := (p*n + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return rank - 1
+}
+
+// SpeedPercentiles son los percentiles que lleva SpeedStats.
+var SpeedPercentiles = []int{50, 90, 95, 99}
+
+// SetPercentiles rellena los percentiles con nth, que devuelve la velocidad
+// en la posición k de menor a mayor entre las n de la flota.
+func (s *SpeedStats) SetPercentiles(n int, nth func(k int) int64) {
+	if n == 0 {
+		return
+	}
+	dst := []*int64{&s.P50, &s.P90, &s.P95, &s.P99}
+	for i, p := range SpeedPercentiles {
+		*dst[i] = nth(PercentileRank(p, n))
+	}
+}
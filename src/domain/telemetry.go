@@ -0,0 +1,52 @@
+package domain
+
+import "errors"
+
+// Telemetría: altitud, combustible y separación de etapas. Siguen las mismas
+// reglas que los deltas de velocidad (idempotentes y conmutativos), así que
+// un mensaje atrasado se aplica en lugar de descartarse.
+func init() {
+	Register(MessageKind[RocketAltitudeChangedPayload]{
+		Name: TypeAltitudeChanged,
+		Validate: func(p RocketAltitudeChangedPayload) error {
+			return validateNonZeroDelta(p.By)
+		},
+		Apply:       func(r *Rocket, p RocketAltitudeChangedPayload) { r.Altitude += p.By },
+		Commutative: true,
+		Transitions: deltaTransitions,
+	})
+
+	Register(MessageKind[RocketFuelLevelChangedPayload]{
+		Name: TypeFuelLevelChanged,
+		Validate: func(p RocketFuelLevelChangedPayload) error {
+			return validateNonZeroDelta(p.By)
+		},
+		Apply:       func(r *Rocket, p RocketFuelLevelChangedPayload) { r.FuelLevel += p.By },
+		Commutative: true,
+		Transitions: deltaTransitions,
+	})
+
+	// Quedarse con la etapa más alta separada es conmutativo: da igual en
+	// qué orden lleguen las separaciones.
+	Register(MessageKind[RocketStageSeparatedPayload]{
+		Name: TypeStageSeparated,
+		Validate: func(p RocketStageSeparatedPayload) error {
+			if p.Stage < 1 {
+				return errors.New("stage must be >= 1")
+			}
+			return nil
+		},
+		Apply: func(r *Rocket, p RocketStageSeparatedPayload) {
+			r.StagesSeparated = max(r.StagesSeparated, p.Stage)
+		},
+		Commutative: true,
+		Transitions: deltaTransitions,
+	})
+}
+
+func validateNonZeroDelta(by int64) error {
+	if by == 0 {
+		return errors.New("by must not be 0")
+	}
+	return nil
+}
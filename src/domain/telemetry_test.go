@@ -0,0 +1,77 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"lunar/src/domain"
+)
+
+func TestTelemetry_CommutativeAndIdempotent(t *testing.T) {
+	ch := "c1"
+	envs := []domain.MessageEnvelope{
+		env(ch, 1, domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "F9", LaunchSpeed: 100, Mission: "M"}),
+		env(ch, 2, domain.TypeFuelLevelChanged, domain.RocketFuelLevelChangedPayload{By: 100}),
+		env(ch, 3, domain.TypeAltitudeChanged, domain.RocketAltitudeChangedPayload{By: 1000}),
+		env(ch, 4, domain.TypeFuelLevelChanged, domain.RocketFuelLevelChangedPayload{By: -30}),
+		env(ch, 5, domain.TypeStageSeparated, domain.RocketStageSeparatedPayload{Stage: 1}),
+		env(ch, 6, domain.TypeAltitudeChanged, domain.RocketAltitudeChangedPayload{By: -200}),
+		env(ch, 7, domain.TypeStageSeparated, domain.RocketStageSeparatedPayload{Stage: 2}),
+	}
+
+	inOrder := domain.NewRocket(ch)
+	for _, e := range envs {
+		mustApply(t, inOrder, e)
+	}
+
+	// Mismo conjunto al revés y con duplicados: mismo resultado.
+	reversed := domain.NewRocket(ch)
+	for i := len(envs) - 1; i >= 0; i-- {
+		mustApply(t, reversed, envs[i])
+		mustApply(t, reversed, envs[i])
+	}
+
+	for _, r := range []*domain.Rocket{inOrder, reversed} {
+		if r.Altitude != 800 || r.FuelLevel != 70 || r.StagesSeparated != 2 || r.LastMsgNum != 7 {
+			t.Errorf("telemetry mismatch; got=%+v", r)
+		}
+	}
+	if inOrder.Status != domain.StatusInFlight {
+		t.Errorf("telemetry after launch should mean in flight; status=%s", inOrder.Status)
+	}
+}
+
+func TestTelemetry_RejectedAfterExplosion(t *testing.T) {
+	r := domain.NewRocket("c1")
+	mustApply(t, r, env("c1", 1, domain.TypeExploded, struct{}{}))
+
+	got, _ := r.Apply(env("c1", 2, domain.TypeAltitudeChanged, domain.RocketAltitudeChangedPayload{By: 10}))
+	if got != domain.OutcomeRejected || r.Altitude != 0 {
+		t.Errorf("altitude after explosion; outcome=%s altitude=%d", got, r.Altitude)
+	}
+}
+
+func TestTelemetry_Validation(t *testing.T) {
+	tests := []struct {
+		kind string
+		raw  string
+		ok   bool
+	}{
+		{domain.TypeAltitudeChanged, `{"by":-5}`, true},
+		{domain.TypeAltitudeChanged, `{"by":0}`, false},
+		{domain.TypeFuelLevelChanged, `{"by":12}`, true},
+		{domain.TypeFuelLevelChanged, `{}`, false},
+		{domain.TypeStageSeparated, `{"stage":1}`, true},
+		{domain.TypeStageSeparated, `{"stage":0}`, false},
+	}
+	for _, tc := range tests {
+		err := domain.ValidatePayload(tc.kind, json.RawMessage(tc.raw))
+		if tc.ok && err != nil {
+			t.Errorf("%s %s: unexpected err=%v", tc.kind, tc.raw, err)
+		}
+		if !tc.ok && !errors.Is(err, domain.ErrInvalidPayload) {
+			t.Errorf("%s %s: got err=%v, want %v", tc.kind, tc.raw, err, domain.ErrInvalidPayload)
+		}
+	}
+}
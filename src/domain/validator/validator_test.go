@@ -158,6 +158,34 @@ func TestValidatePayload(t *testing.T) {
 			want: nil,
 		},
 
+		// Landed (sin payload, como Exploded)
+		{
+			name: "landed_ok_empty_payload",
+			kind: domain.TypeLanded,
+			raw:  raw(struct{}{}),
+			want: nil,
+		},
+
+		// Telemetría
+		{
+			name: "altitude_changed_ok_negative",
+			kind: domain.TypeAltitudeChanged,
+			raw:  raw(domain.RocketAltitudeChangedPayload{By: -50}),
+			want: nil,
+		},
+		{
+			name: "fuel_level_changed_invalid_zero",
+			kind: domain.TypeFuelLevelChanged,
+			raw:  raw(domain.RocketFuelLevelChangedPayload{By: 0}),
+			want: validator.ErrInvalidPayload,
+		},
+		{
+			name: "stage_separated_invalid_zero",
+			kind: domain.TypeStageSeparated,
+			raw:  raw(domain.RocketStageSeparatedPayload{Stage: 0}),
+			want: validator.ErrInvalidPayload,
+		},
+
 		// Unknown type
 		{
 			name: "unknown_type",
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"lunar/src/infrastructure/http/httperror"
+	httpresponse "lunar/src/infrastructure/http/response"
+	"net/http"
+	"strconv"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	"lunar/src/domain/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	KeyAction   = "action"
+	BatchAction = ":batch"
+	KeyAtomic   = "atomic"
+
+	MaxBatchSize = 1000
+)
+
+type MessageBatch struct {
+	enqueue   application.EnqueueMessageBatchUCInterface
+	validator validator.Validator
+}
+
+func NewMessageBatch(
+	enqueue application.EnqueueMessageBatchUCInterface,
+	v validator.Validator,
+) *MessageBatch {
+	return &MessageBatch{enqueue: enqueue, validator: v}
+}
+
+// batchItem es el resultado de un envelope del lote: Code es el que habría
+// dado POST /messages con él solo.
+type batchItem struct {
+	Index   int             `json:"index"`
+	Code    int             `json:"code"`
+	Receipt *domain.Receipt `json:"receipt,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+type batchResponse struct {
+	Items []batchItem `json:"items"`
+}
+
+// Handle valida cada envelope por separado y publica los válidos juntos, en
+// un solo mensaje que se aplica en orden. La respuesta es 207 con el
+// resultado de cada uno. Con atomic=true basta uno inválido para no publicar
+// ninguno; entonces se responde 400 y los válidos llevan 424. La publicación
+// es siempre todo o nada (si falla, 500 y no se publica ninguno), pero cada
+// envelope publicado se aplica por su cuenta: que las reglas del cohete
+// rechacen uno no deshace los demás.
+func (h *MessageBatch) Handle(c *gin.Context) {
+	if c.Param(KeyAction) != BatchAction {
+		httpresponse.WriteErrorResponse(c, http.StatusNotFound, httperror.ErrUnknownMessagesAction)
+		return
+	}
+	atomic := false
+	if raw, ok := c.GetQuery(KeyAtomic); ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			httpresponse.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidAtomic)
+			return
+		}
+		atomic = b
+	}
+	var raws []json.RawMessage
+	if err := c.ShouldBindJSON(&raws); err != nil {
+		httpresponse.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidBatch)
+		return
+	}
+	if len(raws) == 0 || len(raws) > MaxBatchSize {
+		httpresponse.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidBatchSize)
+		return
+	}
+
+	items := make([]batchItem, len(raws))
+	var (
+		valid   []domain.MessageEnvelope
+		indexes []int
+	)
+	for i, raw := range raws {
+		items[i].Index = i
+		var env domain.MessageEnvelope
+		err := json.Unmarshal(raw, &env)
+		if err == nil {
+			err = validateEnvelope(h.validator, env)
+		}
+		if err != nil {
+			items[i].Code = http.StatusBadRequest
+			items[i].Message = err.Error()
+			continue
+		}
+		valid = append(valid, env)
+		indexes = append(indexes, i)
+	}
+
+	if atomic && len(valid) < len(raws) {
+		for _, i := range indexes {
+			items[i].Code = http.StatusFailedDependency
+			items[i].Message = httperror.ErrBatchItemNotPublished.Error()
+		}
+		httpresponse.WriteJSONResponse(c, http.StatusBadRequest, batchResponse{Items: items})
+		return
+	}
+	if len(valid) > 0 {
+		receipts, err := h.enqueue.Execute(valid)
+		if err != nil {
+			httpresponse.WriteErrorResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+		for j, i := range indexes {
+			items[i].Code = http.StatusAccepted
+			items[i].Receipt = &receipts[j]
+		}
+	}
+	httpresponse.WriteJSONResponse(c, http.StatusMultiStatus, batchResponse{Items: items})
+}
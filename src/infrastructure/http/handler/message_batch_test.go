@@ -0,0 +1,163 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	"lunar/src/domain/validator"
+	h "lunar/src/infrastructure/http/handler"
+)
+
+const (
+	pathMessagesAction = "/messages:action"
+	urlBatch           = "/messages:batch"
+)
+
+type batchBody struct {
+	Items []struct {
+		Index   int             `json:"index"`
+		Code    int             `json:"code"`
+		Receipt *domain.Receipt `json:"receipt"`
+		Message string          `json:"message"`
+	} `json:"items"`
+}
+
+func newBatchRouter(t *testing.T, uc application.EnqueueMessageBatchUCInterface) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	hdl := h.NewMessageBatch(uc, validator.New())
+	r.POST(pathMessagesAction, hdl.Handle)
+	return r
+}
+
+func batchOf(items ...string) string {
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func decodeBatch(t *testing.T, body []byte) batchBody {
+	t.Helper()
+	var got batchBody
+	require.NoError(t, json.Unmarshal(body, &got))
+	return got
+}
+
+func TestMessageBatch_PublishesValidOnes_Returns207(t *testing.T) {
+	ucMock := &application.EnqueueMessageBatchUCMock{}
+	ucMock.
+		On("Execute", mock.MatchedBy(func(envs []domain.MessageEnvelope) bool {
+			return len(envs) == 2 && envs[0].Metadata.Channel == "a" && envs[1].Metadata.Channel == "c"
+		})).
+		Return([]domain.Receipt{{ID: "ra"}, {ID: "rc"}}, nil).
+		Once()
+
+	r := newBatchRouter(t, ucMock)
+	w := postJSON(r, urlBatch, batchOf(launchBody("a"), launchBody(""), launchBody("c"), `"nope"`))
+
+	require.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+	got := decodeBatch(t, w.Body.Bytes())
+	require.Len(t, got.Items, 4)
+	for i, want := range []struct {
+		code    int
+		receipt string
+	}{
+		{http.StatusAccepted, "ra"},
+		{http.StatusBadRequest, ""},
+		{http.StatusAccepted, "rc"},
+		{http.StatusBadRequest, ""},
+	} {
+		item := got.Items[i]
+		require.Equal(t, i, item.Index)
+		require.Equal(t, want.code, item.Code, item.Message)
+		if want.receipt != "" {
+			require.Equal(t, want.receipt, item.Receipt.ID)
+		} else {
+			require.Nil(t, item.Receipt)
+			require.NotEmpty(t, item.Message)
+		}
+	}
+	ucMock.AssertExpectations(t)
+}
+
+func TestMessageBatch_Atomic(t *testing.T) {
+	t.Run("one invalid publishes none", func(t *testing.T) {
+		ucMock := &application.EnqueueMessageBatchUCMock{}
+		r := newBatchRouter(t, ucMock)
+
+		w := postJSON(r, urlBatch+"?atomic=true", batchOf(launchBody("a"), launchBody("")))
+
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		got := decodeBatch(t, w.Body.Bytes())
+		require.Equal(t, http.StatusFailedDependency, got.Items[0].Code)
+		require.Equal(t, http.StatusBadRequest, got.Items[1].Code)
+		ucMock.AssertNotCalled(t, "Execute", mock.Anything)
+	})
+	t.Run("all valid publishes all", func(t *testing.T) {
+		ucMock := &application.EnqueueMessageBatchUCMock{}
+		ucMock.
+			On("Execute", mock.AnythingOfType("[]domain.MessageEnvelope")).
+			Return([]domain.Receipt{{ID: "ra"}, {ID: "rb"}}, nil).
+			Once()
+		r := newBatchRouter(t, ucMock)
+
+		w := postJSON(r, urlBatch+"?atomic=true", batchOf(launchBody("a"), launchBody("b")))
+
+		require.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+		for _, item := range decodeBatch(t, w.Body.Bytes()).Items {
+			require.Equal(t, http.StatusAccepted, item.Code)
+		}
+		ucMock.AssertExpectations(t)
+	})
+}
+
+func TestMessageBatch_InvalidRequests(t *testing.T) {
+	tooMany := make([]string, h.MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = launchBody(fmt.Sprint("c", i))
+	}
+	tests := []struct {
+		name, url, body string
+		wantCode        int
+	}{
+		{"unknown action", "/messages:purge", batchOf(launchBody("a")), http.StatusNotFound},
+		{"not an array", urlBatch, launchBody("a"), http.StatusBadRequest},
+		{"empty", urlBatch, "[]", http.StatusBadRequest},
+		{"too many", urlBatch, batchOf(tooMany...), http.StatusBadRequest},
+		{"bad atomic", urlBatch + "?atomic=maybe", batchOf(launchBody("a")), http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ucMock := &application.EnqueueMessageBatchUCMock{}
+			r := newBatchRouter(t, ucMock)
+
+			w := postJSON(r, tc.url, tc.body)
+
+			require.Equal(t, tc.wantCode, w.Code, w.Body.String())
+			ucMock.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	}
+}
+
+func TestMessageBatch_EnqueueError_Returns500(t *testing.T) {
+	ucMock := &application.EnqueueMessageBatchUCMock{}
+	ucMock.
+		On("Execute", mock.AnythingOfType("[]domain.MessageEnvelope")).
+		Return(nil, fmt.Errorf("boom")).
+		Once()
+
+	r := newBatchRouter(t, ucMock)
+	w := postJSON(r, urlBatch, batchOf(launchBody("a")))
+
+	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"lunar/src/application"
+	"lunar/src/infrastructure/http/httperror"
+	"lunar/src/infrastructure/http/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageReceipts struct {
+	get application.GetMessageReceiptUCInterface
+}
+
+func NewMessageReceipts(get application.GetMessageReceiptUCInterface) *MessageReceipts {
+	return &MessageReceipts{get: get}
+}
+
+func (h *MessageReceipts) GetOne(c *gin.Context) {
+	rc, ok := h.get.Execute(c.Param("id"))
+	if !ok {
+		response.WriteErrorResponse(c, http.StatusNotFound, httperror.ErrReceiptNotFound)
+		return
+	}
+	response.WriteJSONResponse(c, http.StatusOK, rc)
+}
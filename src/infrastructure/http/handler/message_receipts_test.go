@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	h "lunar/src/infrastructure/http/handler"
+)
+
+const (
+	pathReceipt = "/messages/:id"
+	urlReceipt  = "/messages/%s"
+)
+
+func newReceiptsRouter(t *testing.T, uc application.GetMessageReceiptUCInterface) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	hdl := h.NewMessageReceipts(uc)
+	r.GET(pathReceipt, hdl.GetOne)
+	return r
+}
+
+func TestMessageReceipts_GetOne_HappyPath_Returns200(t *testing.T) {
+	ucMock := &application.GetMessageReceiptUCMock{}
+
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	rc := domain.Receipt{
+		ID:            "r1",
+		Status:        domain.ReceiptRejected,
+		Reason:        "rocket already exploded",
+		Channel:       "abc",
+		MessageNumber: 4,
+		AcceptedAt:    at,
+		ProcessedAt:   &at,
+	}
+	ucMock.
+		On("Execute", "r1").
+		Return(rc, true).
+		Once()
+
+	r := newReceiptsRouter(t, ucMock)
+	w := doGET(r, fmt.Sprintf(urlReceipt, "r1"))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var got domain.Receipt
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, rc, got)
+	ucMock.AssertExpectations(t)
+}
+
+func TestMessageReceipts_GetOne_Unknown_Returns404(t *testing.T) {
+	ucMock := &application.GetMessageReceiptUCMock{}
+
+	ucMock.
+		On("Execute", "missing").
+		Return(domain.Receipt{}, false).
+		Once()
+
+	r := newReceiptsRouter(t, ucMock)
+	w := doGET(r, fmt.Sprintf(urlReceipt, "missing"))
+
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+	"lunar/src/infrastructure/http/httperror"
 	httpresponse "lunar/src/infrastructure/http/response"
+	"lunar/src/infrastructure/http/routes"
 	"net/http"
+	"strings"
+	"time"
 
 	"lunar/src/application"
 	"lunar/src/domain"
@@ -11,35 +17,122 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	HeaderLocation          = "Location"
+	HeaderPrefer            = "Prefer"
+	HeaderPreferenceApplied = "Preference-Applied"
+
+	KeyMode   = "mode"
+	ModeAsync = "async"
+	ModeSync  = "sync"
+
+	// PreferRepresentation (RFC 7240) pide lo mismo que mode=sync.
+	PreferRepresentation = "return=representation"
+
+	// SyncTimeout es lo máximo que espera mode=sync a que el consumer procese
+	// el mensaje; después responde como el modo asíncrono.
+	SyncTimeout = 10 * time.Second
+)
+
 type Messages struct {
-	enqueue   application.EnqueueMessageUCInterface
-	validator validator.Validator
+	enqueue     application.EnqueueMessageUCInterface
+	enqueueSync application.EnqueueMessageSyncUCInterface
+	validator   validator.Validator
 }
 
 func NewMessages(
 	enqueue application.EnqueueMessageUCInterface,
+	enqueueSync application.EnqueueMessageSyncUCInterface,
 	v validator.Validator,
 ) *Messages {
-	return &Messages{enqueue: enqueue, validator: v}
+	return &Messages{enqueue: enqueue, enqueueSync: enqueueSync, validator: v}
 }
 
 func (h *Messages) Handle(c *gin.Context) {
+	sync, prefer, err := syncMode(c)
+	if err != nil {
+		httpresponse.WriteErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
 	var env domain.MessageEnvelope
 	if err := c.ShouldBindJSON(&env); err != nil {
 		httpresponse.WriteErrorResponse(c, http.StatusBadRequest, err)
 		return
 	}
-	if err := h.validator.ValidateEnvelope(env); err != nil {
+	if err := validateEnvelope(h.validator, env); err != nil {
 		httpresponse.WriteErrorResponse(c, http.StatusBadRequest, err)
 		return
 	}
-	if err := h.validator.ValidatePayload(env.Metadata.MessageType, env.Message); err != nil {
-		httpresponse.WriteErrorResponse(c, http.StatusBadRequest, err)
+	if sync {
+		h.handleSync(c, env, prefer)
 		return
 	}
-	if err := h.enqueue.Execute(env); err != nil {
+	rc, err := h.enqueue.Execute(env)
+	if err != nil {
 		httpresponse.WriteErrorResponse(c, http.StatusInternalServerError, err)
 		return
 	}
-	httpresponse.WriteEmptyResponse(c, http.StatusAccepted)
+	c.Header(HeaderLocation, receiptLocation(rc.ID))
+	httpresponse.WriteJSONResponse(c, http.StatusAccepted, rc)
+}
+
+// handleSync responde con el cohete tal como quedó si el mensaje se aplicó;
+// con 409 si era un duplicado o llegó atrasado, con 422 si se rechazó y, si
+// el consumer no lo procesa en SyncTimeout, con el recibo en cola como el
+// modo asíncrono.
+func (h *Messages) handleSync(c *gin.Context, env domain.MessageEnvelope, prefer bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), SyncTimeout)
+	defer cancel()
+
+	rc, res, err := h.enqueueSync.Execute(ctx, env)
+	if err != nil {
+		httpresponse.WriteErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Header(HeaderLocation, receiptLocation(rc.ID))
+	switch res.Outcome {
+	case domain.OutcomeApplied:
+		if prefer {
+			c.Header(HeaderPreferenceApplied, PreferRepresentation)
+		}
+		httpresponse.WriteJSONResponse(c, http.StatusOK, res.Rocket)
+	case domain.OutcomeDuplicate:
+		httpresponse.WriteErrorResponse(c, http.StatusConflict, httperror.ErrMessageDuplicate)
+	case domain.OutcomeStale:
+		httpresponse.WriteErrorResponse(c, http.StatusConflict, httperror.ErrMessageStale)
+	case domain.OutcomeRejected:
+		httpresponse.WriteErrorResponse(c, http.StatusUnprocessableEntity, fmt.Errorf("%w: %s", httperror.ErrMessageRejected, res.Reason))
+	default:
+		httpresponse.WriteJSONResponse(c, http.StatusAccepted, rc)
+	}
+}
+
+// syncMode indica si se pidió mode=sync o Prefer: return=representation, y
+// si fue por lo segundo.
+func syncMode(c *gin.Context) (bool, bool, error) {
+	prefer := false
+	for _, pref := range strings.FieldsFunc(c.GetHeader(HeaderPrefer), func(r rune) bool { return r == ',' || r == ';' }) {
+		if strings.EqualFold(strings.TrimSpace(pref), PreferRepresentation) {
+			prefer = true
+		}
+	}
+	switch c.DefaultQuery(KeyMode, ModeAsync) {
+	case ModeSync:
+		return true, prefer, nil
+	case ModeAsync:
+		return prefer, prefer, nil
+	}
+	return false, false, httperror.ErrInvalidMode
+}
+
+func validateEnvelope(v validator.Validator, env domain.MessageEnvelope) error {
+	if err := v.ValidateEnvelope(env); err != nil {
+		return err
+	}
+	return v.ValidatePayload(env.Metadata.MessageType, env.Message)
+}
+
+// receiptLocation es la URL de GET /messages/:id para el recibo id.
+func receiptLocation(id string) string {
+	return routes.PostMessagesPath + "/" + id
 }
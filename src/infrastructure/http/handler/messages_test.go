@@ -1,6 +1,8 @@
 package handler_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"lunar/src/application"
+	"lunar/src/domain"
 	"lunar/src/domain/validator"
 	h "lunar/src/infrastructure/http/handler"
 )
@@ -69,7 +72,7 @@ func TestMessages_EnqueueError_Returns500_AndCallsEnqueueOnce(t *testing.T) {
 	ucMock := &application.EnqueueMessageUCMock{}
 	ucMock.
 		On("Execute", mock.AnythingOfType("domain.MessageEnvelope")).
-		Return(fmt.Errorf("boom")).
+		Return(domain.Receipt{}, fmt.Errorf("boom")).
 		Once()
 
 	r := newRouter(t, ucMock)
@@ -91,7 +94,7 @@ func TestMessages_HappyPath_Returns202_AndCallsEnqueueOnce(t *testing.T) {
 	ucMock := &application.EnqueueMessageUCMock{}
 	ucMock.
 		On("Execute", mock.AnythingOfType("domain.MessageEnvelope")).
-		Return(nil).
+		Return(domain.Receipt{ID: "r1", Status: domain.ReceiptQueued, Channel: "c-ok", MessageNumber: 1}, nil).
 		Once()
 
 	r := newRouter(t, ucMock)
@@ -105,17 +108,127 @@ func TestMessages_HappyPath_Returns202_AndCallsEnqueueOnce(t *testing.T) {
 	w := postJSON(r, pathMessages, body)
 
 	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.Equal(t, "/messages/r1", w.Header().Get(h.HeaderLocation))
+	var rc domain.Receipt
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rc))
+	require.Equal(t, "r1", rc.ID)
+	require.Equal(t, domain.ReceiptQueued, rc.Status)
 	ucMock.AssertNumberOfCalls(t, "Execute", 1)
 	ucMock.AssertExpectations(t)
 }
 
+func TestMessages_Sync_MapsOutcomeToStatus(t *testing.T) {
+	rocket := domain.Rocket{Channel: "c-ok", Speed: 100, LastMsgNum: 1}
+	tests := []struct {
+		name     string
+		res      domain.MessageResult
+		wantCode int
+	}{
+		{"applied", domain.MessageResult{Outcome: domain.OutcomeApplied, Rocket: rocket}, http.StatusOK},
+		{"duplicate", domain.MessageResult{Outcome: domain.OutcomeDuplicate, Rocket: rocket}, http.StatusConflict},
+		{"stale", domain.MessageResult{Outcome: domain.OutcomeStale, Rocket: rocket}, http.StatusConflict},
+		{"rejected", domain.MessageResult{Outcome: domain.OutcomeRejected, Reason: "rocket exploded"}, http.StatusUnprocessableEntity},
+		{"timeout", domain.MessageResult{}, http.StatusAccepted},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ucMock := &application.EnqueueMessageUCMock{}
+			syncMock := &application.EnqueueMessageSyncUCMock{}
+			syncMock.
+				On("Execute", mock.Anything, mock.AnythingOfType("domain.MessageEnvelope")).
+				Return(domain.Receipt{ID: "r1", Status: domain.ReceiptQueued}, tc.res, nil).
+				Once()
+
+			r := newSyncRouter(t, ucMock, syncMock)
+			w := postJSON(r, pathMessages+"?mode=sync", launchBody("c-ok"))
+
+			require.Equal(t, tc.wantCode, w.Code, w.Body.String())
+			require.Equal(t, "/messages/r1", w.Header().Get(h.HeaderLocation))
+			switch tc.wantCode {
+			case http.StatusOK:
+				var got domain.Rocket
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				require.Equal(t, rocket.Speed, got.Speed)
+			case http.StatusAccepted:
+				var got domain.Receipt
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				require.Equal(t, domain.ReceiptQueued, got.Status)
+			case http.StatusUnprocessableEntity:
+				require.Contains(t, w.Body.String(), "rocket exploded")
+			}
+			syncMock.AssertExpectations(t)
+			ucMock.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	}
+}
+
+func TestMessages_Sync_PreferHeader(t *testing.T) {
+	syncMock := &application.EnqueueMessageSyncUCMock{}
+	var deadline time.Time
+	syncMock.
+		On("Execute", mock.Anything, mock.AnythingOfType("domain.MessageEnvelope")).
+		Run(func(args mock.Arguments) { deadline, _ = args.Get(0).(context.Context).Deadline() }).
+		Return(domain.Receipt{ID: "r1"}, domain.MessageResult{Outcome: domain.OutcomeApplied}, nil).
+		Once()
+
+	r := newSyncRouter(t, &application.EnqueueMessageUCMock{}, syncMock)
+	req := httptest.NewRequest(http.MethodPost, pathMessages, strings.NewReader(launchBody("c-ok")))
+	req.Header.Set(headerCT, ctJSON)
+	req.Header.Set(h.HeaderPrefer, "respond-async; wait=5, return=representation")
+	w := httptest.NewRecorder()
+	start := time.Now()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, h.PreferRepresentation, w.Header().Get(h.HeaderPreferenceApplied))
+	require.WithinDuration(t, start.Add(h.SyncTimeout), deadline, time.Second)
+	syncMock.AssertExpectations(t)
+}
+
+func TestMessages_Sync_Errors(t *testing.T) {
+	t.Run("invalid mode", func(t *testing.T) {
+		syncMock := &application.EnqueueMessageSyncUCMock{}
+		r := newSyncRouter(t, &application.EnqueueMessageUCMock{}, syncMock)
+
+		w := postJSON(r, pathMessages+"?mode=later", launchBody("c-ok"))
+
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		syncMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+	})
+	t.Run("enqueue fails", func(t *testing.T) {
+		syncMock := &application.EnqueueMessageSyncUCMock{}
+		syncMock.
+			On("Execute", mock.Anything, mock.AnythingOfType("domain.MessageEnvelope")).
+			Return(domain.Receipt{}, domain.MessageResult{}, fmt.Errorf("boom")).
+			Once()
+		r := newSyncRouter(t, &application.EnqueueMessageUCMock{}, syncMock)
+
+		w := postJSON(r, pathMessages+"?mode=sync", launchBody("c-ok"))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+		syncMock.AssertExpectations(t)
+	})
+}
+
+func launchBody(channel string) string {
+	return fmt.Sprintf(`{
+	  "metadata":{"channel":"%s","messageNumber":1,"messageTime":"%s","messageType":"RocketLaunched"},
+	  "message":{"type":"Falcon-9","launchSpeed":100,"mission":"M1"}
+	}`, channel, time.Now().Format(time.RFC3339Nano))
+}
+
 func newRouter(t *testing.T, uc application.EnqueueMessageUCInterface) *gin.Engine {
+	t.Helper()
+	return newSyncRouter(t, uc, &application.EnqueueMessageSyncUCMock{})
+}
+
+func newSyncRouter(t *testing.T, uc application.EnqueueMessageUCInterface, syncUC application.EnqueueMessageSyncUCInterface) *gin.Engine {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	v := validator.New()
-	msgHandler := h.NewMessages(uc, v)
+	msgHandler := h.NewMessages(uc, syncUC, v)
 
 	r.POST(pathMessages, msgHandler.Handle)
 	return r
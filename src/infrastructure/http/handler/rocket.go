@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"lunar/src/application"
+	"lunar/src/domain"
 	"lunar/src/infrastructure/http/httperror"
 	"lunar/src/infrastructure/http/response"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,26 +17,111 @@ import (
 const (
 	KeySort         = "sort"
 	KeyOrder        = "order"
+	KeyAsOf         = "asOf"
+	KeyAt           = "at"
+	KeyLimit        = "limit"
+	KeyCursor       = "cursor"
+	KeyMinMsgNum    = "minMessageNumber"
+	KeyWait         = "wait"
 	SortByChannel   = "channel"
 	SortBySpeed     = "speed"
 	SortByUpdatedAt = "updated_at"
+	SortByEventTime = "event_time"
+	SortByIngested  = "ingested_at"
+	SortByAltitude  = "altitude"
+	SortByFuelLevel = "fuel_level"
+	SortByStages    = "stages_separated"
 
 	OrderAsc  = "asc"
 	OrderDesc = "desc"
+
+	MaxLimit = 1000
+	MaxWait  = 30 * time.Second
+	// DefaultWait es la espera de minMessageNumber sin wait; wait=0s sólo
+	// comprueba.
+	DefaultWait = 5 * time.Second
+
+	// HeaderWaitResult dice, cuando se pide minMessageNumber, si el cohete
+	// llegó a ese mensaje (WaitReached) o se agotó la espera (WaitTimeout).
+	HeaderWaitResult = "X-Wait-Result"
+	WaitReached      = "reached"
+	WaitTimeout      = "timeout"
 )
 
+var validSortKeys = map[string]bool{
+	SortByChannel:   true,
+	SortBySpeed:     true,
+	SortByUpdatedAt: true,
+	SortByEventTime: true,
+	SortByIngested:  true,
+	SortByAltitude:  true,
+	SortByFuelLevel: true,
+	SortByStages:    true,
+}
+
 type Rockets struct {
-	get  application.GetRocketUCInterface
-	list application.ListRocketsUCInterface
+	get     application.GetRocketUCInterface
+	list    application.ListRocketsUCInterface
+	getAsOf application.GetRocketAsOfUCInterface
+	wait    application.WaitRocketUCInterface
 }
 
-func NewRockets(get application.GetRocketUCInterface, list application.ListRocketsUCInterface) *Rockets {
-	return &Rockets{get: get, list: list}
+func NewRockets(
+	get application.GetRocketUCInterface,
+	list application.ListRocketsUCInterface,
+	getAsOf application.GetRocketAsOfUCInterface,
+	wait application.WaitRocketUCInterface,
+) *Rockets {
+	return &Rockets{get: get, list: list, getAsOf: getAsOf, wait: wait}
 }
 
 func (h *Rockets) GetOne(c *gin.Context) {
 	ch := c.Param("channel")
-	rocket, ok, err := h.get.Execute(ch)
+
+	var point domain.PointInTime
+	if raw, ok := c.GetQuery(KeyAsOf); ok {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			response.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidAsOf)
+			return
+		}
+		point.MessageNumber = n
+	}
+	if raw, ok := c.GetQuery(KeyAt); ok {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			response.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidAt)
+			return
+		}
+		point.Time = t
+	}
+
+	minMsgNum, wait, err := parseWait(c)
+	if err == nil && minMsgNum > 0 && !point.IsZero() {
+		err = httperror.ErrWaitWithPointInTime
+	}
+	if err != nil {
+		response.WriteErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+	if minMsgNum > 0 {
+		h.waitFor(c, ch, minMsgNum, wait)
+		return
+	}
+
+	var (
+		rocket domain.Rocket
+		ok     bool
+	)
+	if point.IsZero() {
+		rocket, ok, err = h.get.Execute(ch)
+	} else {
+		rocket, ok, err = h.getAsOf.Execute(ch, point)
+	}
+	if errors.Is(err, domain.ErrHistoryTrimmed) {
+		response.WriteErrorResponse(c, http.StatusGone, httperror.ErrHistoryTrimmed)
+		return
+	}
 	if err != nil {
 		response.WriteErrorResponse(c, http.StatusInternalServerError, err)
 		return
@@ -43,11 +133,68 @@ func (h *Rockets) GetOne(c *gin.Context) {
 	response.WriteJSONResponse(c, http.StatusOK, rocket)
 }
 
+// parseWait lee minMessageNumber y wait; sin minMessageNumber devuelve 0 y,
+// sin wait, DefaultWait.
+func parseWait(c *gin.Context) (int, time.Duration, error) {
+	var minMsgNum int
+	wait := DefaultWait
+	if raw, ok := c.GetQuery(KeyMinMsgNum); ok {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return 0, 0, httperror.ErrInvalidMinMessageNumber
+		}
+		minMsgNum = n
+	}
+	if raw, ok := c.GetQuery(KeyWait); ok {
+		if minMsgNum == 0 {
+			return 0, 0, httperror.ErrWaitWithoutMin
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 || d > MaxWait {
+			return 0, 0, httperror.ErrInvalidWait
+		}
+		wait = d
+	}
+	return minMsgNum, wait, nil
+}
+
+// waitFor responde con el cohete en cuanto su LastMsgNum llega a minMsgNum o,
+// pasado wait, con la última versión conocida. HeaderWaitResult dice cuál de
+// las dos fue.
+func (h *Rockets) waitFor(c *gin.Context, ch string, minMsgNum int, wait time.Duration) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+	defer cancel()
+
+	rocket, ok, reached, err := h.wait.Execute(ctx, ch, minMsgNum)
+	if err != nil {
+		response.WriteErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if reached {
+		c.Header(HeaderWaitResult, WaitReached)
+	} else {
+		c.Header(HeaderWaitResult, WaitTimeout)
+	}
+	if !ok {
+		response.WriteErrorResponse(c, http.StatusNotFound, httperror.ErrChannelNotFound)
+		return
+	}
+	response.WriteJSONResponse(c, http.StatusOK, rocket)
+}
+
+// rocketPage es la respuesta de List; los cursores se omiten cuando no hay
+// más páginas en ese sentido.
+type rocketPage struct {
+	Items      []domain.Rocket `json:"items"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+	PrevCursor string          `json:"prevCursor,omitempty"`
+}
+
 func (h *Rockets) List(c *gin.Context) {
 
 	sortBy := c.DefaultQuery(KeySort, SortByChannel)
 	order := c.DefaultQuery(KeyOrder, OrderAsc)
-	if sortBy != SortByChannel && sortBy != SortBySpeed && sortBy != SortByUpdatedAt {
+	if !validSortKeys[sortBy] {
 		response.WriteErrorResponse(
 			c,
 			http.StatusBadRequest,
@@ -59,10 +206,45 @@ func (h *Rockets) List(c *gin.Context) {
 		response.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidOrder)
 		return
 	}
-	items, err := h.list.Execute(sortBy, order)
+	req := domain.PageRequest{SortBy: sortBy, Order: order}
+	if raw, ok := c.GetQuery(KeyLimit); ok {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > MaxLimit {
+			response.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidLimit)
+			return
+		}
+		req.Limit = n
+	}
+	if raw, ok := c.GetQuery(KeyCursor); ok {
+		cur, err := domain.ParseCursor(raw)
+		// Un cursor sólo vale para el orden con el que se generó.
+		if err != nil || cur.SortBy != sortBy || cur.Order != order {
+			response.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidCursor)
+			return
+		}
+		req.Cursor = &cur
+	}
+
+	filter, err := parseFilter(c, listParams)
+	if err != nil {
+		response.WriteErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.list.Execute(domain.RocketQuery{PageRequest: req, Filter: filter})
 	if err != nil {
 		response.WriteErrorResponse(c, http.StatusInternalServerError, err)
 		return
 	}
-	response.WriteJSONResponse(c, http.StatusOK, items)
+	body := rocketPage{Items: page.Items}
+	if body.Items == nil {
+		body.Items = []domain.Rocket{}
+	}
+	if page.Next != nil {
+		body.NextCursor = page.Next.Encode()
+	}
+	if page.Prev != nil {
+		body.PrevCursor = page.Prev.Encode()
+	}
+	response.WriteJSONResponse(c, http.StatusOK, body)
 }
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"lunar/src/application"
+	"lunar/src/infrastructure/http/httperror"
+	"lunar/src/infrastructure/http/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RocketEvents struct {
+	list application.ListRocketEventsUCInterface
+}
+
+func NewRocketEvents(list application.ListRocketEventsUCInterface) *RocketEvents {
+	return &RocketEvents{list: list}
+}
+
+func (h *RocketEvents) List(c *gin.Context) {
+	ch := c.Param("channel")
+	items, ok, err := h.list.Execute(ch)
+	if err != nil {
+		response.WriteErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		response.WriteErrorResponse(c, http.StatusNotFound, httperror.ErrChannelNotFound)
+		return
+	}
+	response.WriteJSONResponse(c, http.StatusOK, items)
+}
@@ -0,0 +1,83 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	h "lunar/src/infrastructure/http/handler"
+)
+
+const (
+	pathEvents = "/api/rockets/:channel/events"
+	urlEvents  = "/api/rockets/%s/events"
+)
+
+func newEventsRouter(t *testing.T, uc application.ListRocketEventsUCInterface) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	hdl := h.NewRocketEvents(uc)
+	r.GET(pathEvents, hdl.List)
+	return r
+}
+
+func TestRocketEvents_List_HappyPath_Returns200(t *testing.T) {
+	ucMock := &application.ListRocketEventsUCMock{}
+
+	ch := "abc"
+	items := []domain.RocketEvent{
+		{Sequence: 1, MessageNumber: 1, MessageType: domain.TypeSpeedIncreased, Payload: json.RawMessage(`{"by":5}`), Outcome: domain.OutcomeApplied},
+		{Sequence: 2, MessageNumber: 1, MessageType: domain.TypeSpeedIncreased, Payload: json.RawMessage(`{"by":5}`), Outcome: domain.OutcomeDuplicate},
+	}
+	ucMock.
+		On("Execute", ch).
+		Return(items, true, nil).
+		Once()
+
+	r := newEventsRouter(t, ucMock)
+	w := doGET(r, fmt.Sprintf(urlEvents, ch))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var got []domain.RocketEvent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, items, got)
+	ucMock.AssertExpectations(t)
+}
+
+func TestRocketEvents_List_NotFound_Returns404(t *testing.T) {
+	ucMock := &application.ListRocketEventsUCMock{}
+
+	ucMock.
+		On("Execute", "missing").
+		Return(nil, false, nil).
+		Once()
+
+	r := newEventsRouter(t, ucMock)
+	w := doGET(r, fmt.Sprintf(urlEvents, "missing"))
+
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
+
+func TestRocketEvents_List_InternalError_Returns500(t *testing.T) {
+	ucMock := &application.ListRocketEventsUCMock{}
+
+	ucMock.
+		On("Execute", "abc").
+		Return(nil, false, fmt.Errorf("repo error")).
+		Once()
+
+	r := newEventsRouter(t, ucMock)
+	w := doGET(r, fmt.Sprintf(urlEvents, "abc"))
+
+	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
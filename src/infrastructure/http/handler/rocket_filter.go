@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"fmt"
+	"lunar/src/domain"
+	"lunar/src/infrastructure/http/httperror"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	KeyStatus        = "status"
+	KeyType          = "type"
+	KeyMission       = "mission"
+	KeyMissionPrefix = "mission_prefix"
+	KeySpeedGTE      = "speed_gte"
+	KeySpeedLTE      = "speed_lte"
+	KeyUpdatedGTE    = "updated_at_gte"
+	KeyUpdatedLTE    = "updated_at_lte"
+	KeyEventTimeGTE  = "event_time_gte"
+	KeyEventTimeLTE  = "event_time_lte"
+	KeyIngestedGTE   = "ingested_at_gte"
+	KeyIngestedLTE   = "ingested_at_lte"
+)
+
+// filterParams son los parámetros de filtro que entiende parseFilter.
+var filterParams = []string{
+	KeyStatus, KeyType, KeyMission, KeyMissionPrefix,
+	KeySpeedGTE, KeySpeedLTE,
+	KeyUpdatedGTE, KeyUpdatedLTE,
+	KeyEventTimeGTE, KeyEventTimeLTE,
+	KeyIngestedGTE, KeyIngestedLTE,
+}
+
+// listParams son los parámetros que entiende List; cualquier otro se
+// rechaza en vez de ignorarse, para que un filtro mal escrito no devuelva la
+// flota entera.
+var listParams = withFilterParams(KeySort, KeyOrder, KeyLimit, KeyCursor)
+
+func withFilterParams(keys ...string) map[string]bool {
+	allowed := make(map[string]bool, len(keys)+len(filterParams))
+	for _, key := range append(keys, filterParams...) {
+		allowed[key] = true
+	}
+	return allowed
+}
+
+var validStatuses = map[domain.RocketStatus]bool{
+	domain.StatusPending:  true,
+	domain.StatusLaunched: true,
+	domain.StatusInFlight: true,
+	domain.StatusExploded: true,
+	domain.StatusLanded:   true,
+}
+
+// parseFilter lee los filtros de la query string. Cualquier parámetro que no
+// esté en allowed es un error.
+func parseFilter(c *gin.Context, allowed map[string]bool) (domain.RocketFilter, error) {
+	var f domain.RocketFilter
+	for key := range c.Request.URL.Query() {
+		if !allowed[key] {
+			return f, fmt.Errorf("%w %q", httperror.ErrUnknownFilter, key)
+		}
+	}
+
+	if raw, ok := c.GetQuery(KeyStatus); ok {
+		f.Status = domain.RocketStatus(raw)
+		if !validStatuses[f.Status] {
+			return f, httperror.ErrInvalidStatus
+		}
+	}
+	f.Type = c.Query(KeyType)
+	f.Mission = c.Query(KeyMission)
+	f.MissionPrefix = c.Query(KeyMissionPrefix)
+
+	var err error
+	if f.Speed.Min, err = intParam(c, KeySpeedGTE); err != nil {
+		return f, err
+	}
+	if f.Speed.Max, err = intParam(c, KeySpeedLTE); err != nil {
+		return f, err
+	}
+	if f.Speed.Min != nil && f.Speed.Max != nil && *f.Speed.Min > *f.Speed.Max {
+		return f, httperror.ErrInvalidRange
+	}
+	for _, r := range []struct {
+		gte, lte string
+		dst      *domain.TimeRange
+	}{
+		{KeyUpdatedGTE, KeyUpdatedLTE, &f.UpdatedAt},
+		{KeyEventTimeGTE, KeyEventTimeLTE, &f.EventTime},
+		{KeyIngestedGTE, KeyIngestedLTE, &f.IngestedAt},
+	} {
+		if r.dst.From, err = timeParam(c, r.gte); err != nil {
+			return f, err
+		}
+		if r.dst.To, err = timeParam(c, r.lte); err != nil {
+			return f, err
+		}
+		if !r.dst.From.IsZero() && !r.dst.To.IsZero() && r.dst.From.After(r.dst.To) {
+			return f, httperror.ErrInvalidRange
+		}
+	}
+	return f, nil
+}
+
+func intParam(c *gin.Context, key string) (*int64, error) {
+	raw, ok := c.GetQuery(key)
+	if !ok {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, httperror.ErrInvalidSpeedFilter
+	}
+	return &n, nil
+}
+
+func timeParam(c *gin.Context, key string) (time.Time, error) {
+	raw, ok := c.GetQuery(key)
+	if !ok {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, httperror.ErrInvalidTimeFilter
+	}
+	return t, nil
+}
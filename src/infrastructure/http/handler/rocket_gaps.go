@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"lunar/src/application"
+	"lunar/src/infrastructure/http/httperror"
+	"lunar/src/infrastructure/http/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RocketGaps struct {
+	get application.GetRocketGapsUCInterface
+}
+
+func NewRocketGaps(get application.GetRocketGapsUCInterface) *RocketGaps {
+	return &RocketGaps{get: get}
+}
+
+func (h *RocketGaps) GetOne(c *gin.Context) {
+	ch := c.Param("channel")
+	gaps, ok, err := h.get.Execute(ch)
+	if err != nil {
+		response.WriteErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		response.WriteErrorResponse(c, http.StatusNotFound, httperror.ErrChannelNotFound)
+		return
+	}
+	response.WriteJSONResponse(c, http.StatusOK, gaps)
+}
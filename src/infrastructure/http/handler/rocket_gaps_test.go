@@ -0,0 +1,64 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	h "lunar/src/infrastructure/http/handler"
+)
+
+const (
+	pathGaps = "/api/rockets/:channel/gaps"
+	urlGaps  = "/api/rockets/%s/gaps"
+)
+
+func newGapsRouter(t *testing.T, uc application.GetRocketGapsUCInterface) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	hdl := h.NewRocketGaps(uc)
+	r.GET(pathGaps, hdl.GetOne)
+	return r
+}
+
+func TestRocketGaps_GetOne_HappyPath_Returns200(t *testing.T) {
+	ucMock := &application.GetRocketGapsUCMock{}
+
+	gaps := domain.OrderingGaps{Channel: "abc", NextExpected: 5, Missing: []int{2, 3}, Buffered: []int{}}
+	ucMock.
+		On("Execute", "abc").
+		Return(gaps, true, nil).
+		Once()
+
+	r := newGapsRouter(t, ucMock)
+	w := doGET(r, fmt.Sprintf(urlGaps, "abc"))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var got domain.OrderingGaps
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, gaps, got)
+	ucMock.AssertExpectations(t)
+}
+
+func TestRocketGaps_GetOne_NotFound_Returns404(t *testing.T) {
+	ucMock := &application.GetRocketGapsUCMock{}
+
+	ucMock.
+		On("Execute", "missing").
+		Return(domain.OrderingGaps{}, false, nil).
+		Once()
+
+	r := newGapsRouter(t, ucMock)
+	w := doGET(r, fmt.Sprintf(urlGaps, "missing"))
+
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
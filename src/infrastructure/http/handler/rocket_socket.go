@@ -0,0 +1,498 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"lunar/src/application"
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+	"lunar/src/infrastructure/http/httperror"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Peticiones que manda el cliente.
+	SocketSubscribe   = "subscribe"
+	SocketUnsubscribe = "unsubscribe"
+
+	// Mensajes que manda el servidor.
+	SocketSnapshot     = "snapshot"
+	SocketAdded        = "added"
+	SocketChanged      = "changed"
+	SocketRemoved      = "removed"
+	SocketUnsubscribed = "unsubscribed"
+	SocketError        = "error"
+
+	MaxSocketSubscriptions = 32
+
+	socketReadLimit  = 4 << 10
+	socketWriteWait  = 10 * time.Second
+	socketPongWait   = 60 * time.Second
+	socketPingPeriod = socketPongWait * 9 / 10
+)
+
+// errSubscriptionDropped indica que el broker ha descolgado la conexión.
+var errSubscriptionDropped = errors.New("change subscription was dropped")
+
+// socketRequestErrors son los errores que se contestan al cliente sin
+// cerrar la conexión.
+var socketRequestErrors = []error{
+	httperror.ErrInvalidSocketRequest,
+	httperror.ErrUnknownSocketRequest,
+	httperror.ErrMissingSubscriptionID,
+	httperror.ErrDuplicateSubscription,
+	httperror.ErrUnknownSubscription,
+	httperror.ErrTooManySubscriptions,
+	httperror.ErrInvalidStatus,
+}
+
+// RocketSocket sirve /api/ws: cada conexión mantiene un conjunto de
+// suscripciones (por canal, misión o estado) que el cliente abre y cierra
+// sobre la marcha. Al suscribirse recibe una foto de los cohetes que
+// encajan y después sólo los campos que cambian. Una conexión que no lee lo
+// bastante rápido pierde su suscripción al broker y se cierra con 1013 (Try
+// Again Later): nunca frena al consumer.
+type RocketSocket struct {
+	subscribe application.SubscribeRocketsUCInterface
+	list      application.ListRocketsUCInterface
+	upgrader  websocket.Upgrader
+}
+
+func NewRocketSocket(
+	subscribe application.SubscribeRocketsUCInterface,
+	list application.ListRocketsUCInterface,
+) *RocketSocket {
+	return &RocketSocket{subscribe: subscribe, list: list}
+}
+
+func (h *RocketSocket) Handle(c *gin.Context) {
+	ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade ya ha contestado al cliente.
+		return
+	}
+	conn := &socketConn{
+		ws:        ws,
+		list:      h.list,
+		selectors: make(map[string]socketSelector),
+		held:      make(map[string]domain.Rocket),
+	}
+	sub := h.subscribe.Execute(conn.wants)
+	defer sub.Close()
+	conn.run(sub)
+}
+
+// socketRequest es lo que manda el cliente.
+type socketRequest struct {
+	Type     string   `json:"type"`
+	ID       string   `json:"id"`
+	Channels []string `json:"channels,omitempty"`
+	Missions []string `json:"missions,omitempty"`
+	Statuses []string `json:"statuses,omitempty"`
+}
+
+// socketMessage es lo que manda el servidor salvo la foto; cada tipo usa
+// sus campos.
+type socketMessage struct {
+	Type     string                     `json:"type"`
+	ID       string                     `json:"id,omitempty"`
+	Sequence uint64                     `json:"sequence,omitempty"`
+	Channel  string                     `json:"channel,omitempty"`
+	Rocket   *domain.Rocket             `json:"rocket,omitempty"`
+	Changes  map[string]json.RawMessage `json:"changes,omitempty"`
+	Message  string                     `json:"message,omitempty"`
+}
+
+// socketSnapshot lleva siempre rockets, aunque no encaje ninguno.
+type socketSnapshot struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	Rockets []domain.Rocket `json:"rockets"`
+}
+
+// socketSelector es una suscripción: cada lista no vacía tiene que
+// contener el valor del cohete.
+type socketSelector struct {
+	channels, missions map[string]bool
+	statuses           map[domain.RocketStatus]bool
+}
+
+func newSocketSelector(req socketRequest) (socketSelector, error) {
+	sel := socketSelector{}
+	for _, ch := range req.Channels {
+		if sel.channels == nil {
+			sel.channels = make(map[string]bool)
+		}
+		sel.channels[ch] = true
+	}
+	for _, m := range req.Missions {
+		if sel.missions == nil {
+			sel.missions = make(map[string]bool)
+		}
+		sel.missions[m] = true
+	}
+	for _, raw := range req.Statuses {
+		st := domain.RocketStatus(raw)
+		if !validStatuses[st] {
+			return sel, httperror.ErrInvalidStatus
+		}
+		if sel.statuses == nil {
+			sel.statuses = make(map[domain.RocketStatus]bool)
+		}
+		sel.statuses[st] = true
+	}
+	return sel, nil
+}
+
+func (s socketSelector) matches(r domain.Rocket) bool {
+	return (s.channels == nil || s.channels[r.Channel]) &&
+		(s.missions == nil || s.missions[r.Mission]) &&
+		(s.statuses == nil || s.statuses[r.Status])
+}
+
+// query es el listado más estrecho que cubre el selector; lo que no se
+// puede expresar como RocketFilter se filtra después con matches.
+func (s socketSelector) query() domain.RocketQuery {
+	q := domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: SortByChannel, Order: OrderAsc}}
+	if len(s.missions) == 1 {
+		for m := range s.missions {
+			q.Filter.Mission = m
+		}
+	}
+	if len(s.statuses) == 1 {
+		for st := range s.statuses {
+			q.Filter.Status = st
+		}
+	}
+	return q
+}
+
+type socketConn struct {
+	ws   *websocket.Conn
+	list application.ListRocketsUCInterface
+
+	// mu protege selectors y held, que también lee wants desde el broker.
+	// Nunca se tiene tomado mientras se llama al store o al broker.
+	mu        sync.Mutex
+	selectors map[string]socketSelector
+	held      map[string]domain.Rocket // la última versión que tiene el cliente
+}
+
+// wants es el criterio de la suscripción al broker: los cohetes que encajan
+// en alguna suscripción y los que tiene el cliente, para poder avisarle de
+// que han dejado de encajar.
+func (c *socketConn) wants(r domain.Rocket) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.held[r.Channel]; ok {
+		return true
+	}
+	return c.matchesLocked(r)
+}
+
+func (c *socketConn) matchesLocked(r domain.Rocket) bool {
+	for _, sel := range c.selectors {
+		if sel.matches(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// run atiende la conexión hasta que el cliente se va, falla una escritura o
+// el broker descuelga la suscripción. Es el único que escribe en ws.
+func (c *socketConn) run(sub port.ChangeSubscription) {
+	defer c.ws.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	requests := make(chan socketRequest)
+	go c.readLoop(requests, done)
+
+	ping := time.NewTicker(socketPingPeriod)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				return
+			}
+			err = c.handle(req, sub)
+		case change, ok := <-sub.Changes():
+			if !ok {
+				err = errSubscriptionDropped
+				break
+			}
+			err = c.apply(change)
+		case <-ping.C:
+			err = c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait))
+		}
+		if errors.Is(err, errSubscriptionDropped) {
+			_ = c.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow, reconnect"),
+				time.Now().Add(socketWriteWait))
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readLoop pasa a run las peticiones del cliente. Una petición mal formada
+// llega con Type vacío para que run conteste con un error.
+func (c *socketConn) readLoop(requests chan<- socketRequest, done <-chan struct{}) {
+	defer close(requests)
+	c.ws.SetReadLimit(socketReadLimit)
+	_ = c.ws.SetReadDeadline(time.Now().Add(socketPongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+	for {
+		_, raw, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req socketRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			req = socketRequest{}
+		}
+		select {
+		case requests <- req:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (c *socketConn) handle(req socketRequest, sub port.ChangeSubscription) error {
+	var err error
+	switch req.Type {
+	case SocketSubscribe:
+		err = c.subscribeTo(req, sub)
+	case SocketUnsubscribe:
+		err = c.unsubscribe(req)
+	case "":
+		err = httperror.ErrInvalidSocketRequest
+	default:
+		err = httperror.ErrUnknownSocketRequest
+	}
+	for _, reqErr := range socketRequestErrors {
+		if err == reqErr {
+			return c.send(socketMessage{Type: SocketError, ID: req.ID, Message: err.Error()})
+		}
+	}
+	return err
+}
+
+// subscribeTo da de alta el selector y manda la foto de lo que encaja. Los
+// cambios que llegan mientras se lee la foto se funden con ella, salvo los
+// más viejos que lo listado: lo que el cliente ve empieza en la foto y
+// avanza sin saltos hacia atrás.
+func (c *socketConn) subscribeTo(req socketRequest, sub port.ChangeSubscription) error {
+	if req.ID == "" {
+		return httperror.ErrMissingSubscriptionID
+	}
+	sel, err := newSocketSelector(req)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	_, dup := c.selectors[req.ID]
+	full := len(c.selectors) >= MaxSocketSubscriptions
+	if !dup && !full {
+		c.selectors[req.ID] = sel
+	}
+	c.mu.Unlock()
+	switch {
+	case dup:
+		return httperror.ErrDuplicateSubscription
+	case full:
+		return httperror.ErrTooManySubscriptions
+	}
+
+	// Desde aquí el broker ya entrega los cambios que encajan en sel.
+	page, err := c.list.Execute(sel.query())
+	if err != nil {
+		c.mu.Lock()
+		delete(c.selectors, req.ID)
+		c.mu.Unlock()
+		return c.send(socketMessage{Type: SocketError, ID: req.ID, Message: err.Error()})
+	}
+	snap := make(map[string]domain.Rocket)
+	for _, r := range page.Items {
+		if sel.matches(r) {
+			snap[r.Channel] = r
+		}
+	}
+	// El broker reparte desde su propia goroutine: lo publicado antes de
+	// listar puede seguir en su cola. Tras Sync está todo en sub, así que lo
+	// que llegue después es posterior; lo drenado puede ser más viejo que lo
+	// listado y entonces no cuenta.
+	sub.Sync()
+	pending, err := drain(sub)
+	if err != nil {
+		return err
+	}
+	for _, change := range pending {
+		r := change.Rocket
+		if listed, ok := snap[r.Channel]; ok && olderThan(r, listed) {
+			continue
+		}
+		if sel.matches(r) {
+			snap[r.Channel] = r
+		} else {
+			delete(snap, r.Channel)
+		}
+	}
+
+	rockets := make([]domain.Rocket, 0, len(snap))
+	for _, r := range snap {
+		rockets = append(rockets, r)
+	}
+	sort.Slice(rockets, func(i, j int) bool { return rockets[i].Channel < rockets[j].Channel })
+	c.mu.Lock()
+	for _, r := range rockets {
+		c.held[r.Channel] = r
+	}
+	c.mu.Unlock()
+	if err := c.send(socketSnapshot{Type: SocketSnapshot, ID: req.ID, Rockets: rockets}); err != nil {
+		return err
+	}
+	for _, change := range pending {
+		if _, ok := snap[change.Rocket.Channel]; ok {
+			continue
+		}
+		if err := c.apply(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drain recoge sin esperar los cambios que ya están en la suscripción.
+func drain(sub port.ChangeSubscription) ([]domain.RocketChange, error) {
+	var pending []domain.RocketChange
+	for {
+		select {
+		case change, ok := <-sub.Changes():
+			if !ok {
+				return nil, errSubscriptionDropped
+			}
+			pending = append(pending, change)
+		default:
+			return pending, nil
+		}
+	}
+}
+
+// unsubscribe quita el selector y retira los cohetes que ya no encajan en
+// ninguno de los que quedan.
+func (c *socketConn) unsubscribe(req socketRequest) error {
+	c.mu.Lock()
+	if _, ok := c.selectors[req.ID]; !ok {
+		c.mu.Unlock()
+		return httperror.ErrUnknownSubscription
+	}
+	delete(c.selectors, req.ID)
+	var gone []string
+	for ch, r := range c.held {
+		if !c.matchesLocked(r) {
+			gone = append(gone, ch)
+			delete(c.held, ch)
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Strings(gone)
+	for _, ch := range gone {
+		if err := c.send(socketMessage{Type: SocketRemoved, Channel: ch}); err != nil {
+			return err
+		}
+	}
+	return c.send(socketMessage{Type: SocketUnsubscribed, ID: req.ID})
+}
+
+// apply manda al cliente lo que cambia para él con la nueva versión. Una
+// versión anterior a la que ya tiene (un store que avisa después de que se
+// pueda leer lo que ha escrito) se descarta.
+func (c *socketConn) apply(change domain.RocketChange) error {
+	r := change.Rocket
+	c.mu.Lock()
+	old, held := c.held[r.Channel]
+	if held && olderThan(r, old) {
+		c.mu.Unlock()
+		return nil
+	}
+	matches := c.matchesLocked(r)
+	switch {
+	case matches:
+		c.held[r.Channel] = r
+	case held:
+		delete(c.held, r.Channel)
+	}
+	c.mu.Unlock()
+
+	switch {
+	case matches && !held:
+		return c.send(socketMessage{Type: SocketAdded, Sequence: change.Sequence, Rocket: &r})
+	case matches:
+		changes, err := rocketDiff(old, r)
+		if err != nil || len(changes) == 0 {
+			return err
+		}
+		return c.send(socketMessage{Type: SocketChanged, Sequence: change.Sequence, Channel: r.Channel, Changes: changes})
+	case held:
+		return c.send(socketMessage{Type: SocketRemoved, Sequence: change.Sequence, Channel: r.Channel})
+	}
+	return nil
+}
+
+// olderThan indica si r es una versión anterior a than. IngestedAt sólo
+// avanza al aplicar un mensaje, así que no distingue un rechazo de la
+// versión que lo precede, pero nunca toma una versión nueva por vieja.
+func olderThan(r, than domain.Rocket) bool {
+	return r.IngestedAt.Before(than.IngestedAt)
+}
+
+// rocketDiff devuelve los campos (con su nombre en JSON) en que new difiere
+// de old.
+func rocketDiff(old, new domain.Rocket) (map[string]json.RawMessage, error) {
+	before, err := rocketFields(old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := rocketFields(new)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]json.RawMessage)
+	for k, v := range after {
+		if !bytes.Equal(before[k], v) {
+			changes[k] = v
+		}
+	}
+	return changes, nil
+}
+
+func rocketFields(r domain.Rocket) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(raw, &fields)
+	return fields, err
+}
+
+func (c *socketConn) send(msg any) error {
+	_ = c.ws.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return c.ws.WriteJSON(msg)
+}
@@ -0,0 +1,213 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	h "lunar/src/infrastructure/http/handler"
+	"lunar/src/infrastructure/notify"
+)
+
+const pathSocket = "/api/ws"
+
+// socketReply recoge cualquier mensaje del servidor.
+type socketReply struct {
+	Type     string                     `json:"type"`
+	ID       string                     `json:"id"`
+	Sequence uint64                     `json:"sequence"`
+	Channel  string                     `json:"channel"`
+	Rocket   *domain.Rocket             `json:"rocket"`
+	Rockets  []domain.Rocket            `json:"rockets"`
+	Changes  map[string]json.RawMessage `json:"changes"`
+	Message  string                     `json:"message"`
+}
+
+func newSocketServer(t *testing.T, sub application.SubscribeRocketsUCInterface, list application.ListRocketsUCInterface) *websocket.Conn {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	hdl := h.NewRocketSocket(sub, list)
+	r.GET(pathSocket, hdl.Handle)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+pathSocket, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ws.Close() })
+	return ws
+}
+
+func sendRequest(t *testing.T, ws *websocket.Conn, req map[string]any) {
+	t.Helper()
+	require.NoError(t, ws.WriteJSON(req))
+}
+
+func readReply(t *testing.T, ws *websocket.Conn) socketReply {
+	t.Helper()
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var reply socketReply
+	require.NoError(t, ws.ReadJSON(&reply))
+	return reply
+}
+
+func listQuery(filter domain.RocketFilter) domain.RocketQuery {
+	return domain.RocketQuery{
+		PageRequest: domain.PageRequest{SortBy: h.SortByChannel, Order: h.OrderAsc},
+		Filter:      filter,
+	}
+}
+
+func TestRocketSocket_SnapshotThenDiffs(t *testing.T) {
+	broker := notify.NewBroker(16, 16)
+	listMock := &application.ListRocketsUCMock{}
+
+	artemis := domain.Rocket{Channel: "a", Mission: "ARTEMIS", Status: domain.StatusLaunched, Speed: 500}
+	listMock.
+		On("Execute", listQuery(domain.RocketFilter{Mission: "ARTEMIS"})).
+		Return(domain.Page{Items: []domain.Rocket{artemis}}, nil).
+		Once()
+
+	ws := newSocketServer(t, application.NewSubscribeRocketsUC(broker), listMock)
+	sendRequest(t, ws, map[string]any{"type": "subscribe", "id": "s1", "missions": []string{"ARTEMIS"}})
+
+	snap := readReply(t, ws)
+	require.Equal(t, h.SocketSnapshot, snap.Type)
+	require.Equal(t, "s1", snap.ID)
+	require.Equal(t, []domain.Rocket{artemis}, snap.Rockets)
+
+	// Sólo viajan los campos que cambian.
+	faster := artemis
+	faster.Speed = 800
+	broker.Publish(faster)
+	changed := readReply(t, ws)
+	require.Equal(t, h.SocketChanged, changed.Type)
+	require.Equal(t, "a", changed.Channel)
+	require.Equal(t, map[string]json.RawMessage{"Speed": json.RawMessage("800")}, changed.Changes)
+
+	// Lo que no encaja ni se tiene no llega; lo nuevo que encaja llega entero.
+	broker.Publish(domain.Rocket{Channel: "x", Mission: "APOLLO"})
+	broker.Publish(domain.Rocket{Channel: "b", Mission: "ARTEMIS"})
+	added := readReply(t, ws)
+	require.Equal(t, h.SocketAdded, added.Type)
+	require.Equal(t, "b", added.Rocket.Channel)
+	require.Equal(t, uint64(3), added.Sequence)
+
+	// Al dejar de encajar se avisa una vez y se olvida.
+	moved := faster
+	moved.Mission = "GATEWAY"
+	broker.Publish(moved)
+	removed := readReply(t, ws)
+	require.Equal(t, h.SocketRemoved, removed.Type)
+	require.Equal(t, "a", removed.Channel)
+
+	sendRequest(t, ws, map[string]any{"type": "unsubscribe", "id": "s1"})
+	require.Equal(t, socketReply{Type: h.SocketRemoved, Channel: "b"}, readReply(t, ws))
+	require.Equal(t, socketReply{Type: h.SocketUnsubscribed, ID: "s1"}, readReply(t, ws))
+	listMock.AssertExpectations(t)
+}
+
+func TestRocketSocket_InvalidRequests_ReplyWithErrorAndKeepConnection(t *testing.T) {
+	broker := notify.NewBroker(16, 16)
+	listMock := &application.ListRocketsUCMock{}
+
+	listMock.
+		On("Execute", listQuery(domain.RocketFilter{})).
+		Return(domain.Page{}, nil).
+		Once()
+
+	ws := newSocketServer(t, application.NewSubscribeRocketsUC(broker), listMock)
+
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("not json")))
+	require.Equal(t, h.SocketError, readReply(t, ws).Type)
+
+	for _, req := range []map[string]any{
+		{"type": "launch"},
+		{"type": "subscribe"},
+		{"type": "subscribe", "id": "s1", "statuses": []string{"FLYING"}},
+		{"type": "unsubscribe", "id": "missing"},
+	} {
+		sendRequest(t, ws, req)
+		reply := readReply(t, ws)
+		require.Equal(t, h.SocketError, reply.Type, req)
+		require.NotEmpty(t, reply.Message, req)
+	}
+
+	sendRequest(t, ws, map[string]any{"type": "subscribe", "id": "s1"})
+	snap := readReply(t, ws)
+	require.Equal(t, h.SocketSnapshot, snap.Type)
+	require.Empty(t, snap.Rockets)
+
+	sendRequest(t, ws, map[string]any{"type": "subscribe", "id": "s1"})
+	require.Equal(t, h.SocketError, readReply(t, ws).Type)
+	listMock.AssertExpectations(t)
+}
+
+func TestRocketSocket_DroppedSubscription_ClosesTryAgainLater(t *testing.T) {
+	subMock := &application.SubscribeRocketsUCMock{}
+
+	sub := newFakeSubscription(true)
+	subMock.
+		On("Execute", mock.Anything).
+		Return(sub).
+		Once()
+
+	ws := newSocketServer(t, subMock, &application.ListRocketsUCMock{})
+
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, _, err := ws.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater), "got %v", err)
+	subMock.AssertExpectations(t)
+}
+
+// lateSubscription entrega lo que el test le mande, cuando se lo mande: como
+// un broker cuya cola se reparte después de la foto.
+type lateSubscription struct{ ch chan domain.RocketChange }
+
+func (s *lateSubscription) Changes() <-chan domain.RocketChange { return s.ch }
+func (s *lateSubscription) Complete() bool                      { return true }
+func (s *lateSubscription) Sync()                               {}
+func (s *lateSubscription) Close()                              {}
+
+func TestRocketSocket_ChangeQueuedBeforeListDoesNotRewindSnapshot(t *testing.T) {
+	subMock := &application.SubscribeRocketsUCMock{}
+	listMock := &application.ListRocketsUCMock{}
+
+	t0 := time.Date(2022, 2, 2, 19, 39, 5, 0, time.UTC)
+	v1 := domain.Rocket{Channel: "a", Mission: "ARTEMIS", Speed: 500, IngestedAt: t0}
+	v2 := v1
+	v2.Speed, v2.IngestedAt = 800, t0.Add(time.Second)
+	v3 := v2
+	v3.Speed, v3.IngestedAt = 900, t0.Add(2*time.Second)
+
+	sub := &lateSubscription{ch: make(chan domain.RocketChange, 2)}
+	subMock.On("Execute", mock.Anything).Return(sub).Once()
+	listMock.
+		On("Execute", listQuery(domain.RocketFilter{Mission: "ARTEMIS"})).
+		Return(domain.Page{Items: []domain.Rocket{v2}}, nil).
+		Once()
+
+	ws := newSocketServer(t, subMock, listMock)
+	sendRequest(t, ws, map[string]any{"type": "subscribe", "id": "s1", "missions": []string{"ARTEMIS"}})
+	require.Equal(t, []domain.Rocket{v2}, readReply(t, ws).Rockets)
+
+	// v1 se aplicó antes de listar pero llega después de la foto.
+	sub.ch <- domain.RocketChange{Epoch: "e1", Sequence: 1, Rocket: v1}
+	sub.ch <- domain.RocketChange{Epoch: "e1", Sequence: 3, Rocket: v3}
+	changed := readReply(t, ws)
+	require.Equal(t, h.SocketChanged, changed.Type)
+	require.Equal(t, uint64(3), changed.Sequence)
+	require.Equal(t, json.RawMessage("900"), changed.Changes["Speed"])
+	subMock.AssertExpectations(t)
+	listMock.AssertExpectations(t)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"lunar/src/application"
+	"lunar/src/domain"
+	"lunar/src/infrastructure/http/httperror"
+	"lunar/src/infrastructure/http/response"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	KeyLastEventID    = "lastEventId"
+	HeaderLastEventID = "Last-Event-ID"
+
+	// EventRocket lleva la nueva versión de un cohete; EventReset avisa de
+	// que se han perdido cambios y hay que volver a leer con GET.
+	EventRocket = "rocket"
+	EventReset  = "reset"
+
+	// StreamHeartbeat es cada cuánto se manda un comentario para que los
+	// proxies no corten una conexión sin cambios.
+	StreamHeartbeat = 15 * time.Second
+)
+
+// streamParams son los parámetros que entiende el stream: los filtros de
+// List y, para EventSource, que no deja poner cabeceras, lastEventId.
+var streamParams = withFilterParams(KeyLastEventID)
+
+// RocketStream emite por Server-Sent Events cada cambio de los cohetes. El
+// id de cada evento es su número de cambio con el epoch del proceso: al
+// reconectar, el navegador lo manda en Last-Event-ID y se sigue donde se
+// quedó; si el proceso ha reiniciado se manda reset.
+type RocketStream struct {
+	watch application.WatchRocketsUCInterface
+}
+
+func NewRocketStream(watch application.WatchRocketsUCInterface) *RocketStream {
+	return &RocketStream{watch: watch}
+}
+
+// All emite los cambios de toda la flota.
+func (h *RocketStream) All(c *gin.Context) {
+	h.stream(c, "")
+}
+
+// One emite los cambios de un canal.
+func (h *RocketStream) One(c *gin.Context) {
+	h.stream(c, c.Param("channel"))
+}
+
+func (h *RocketStream) stream(c *gin.Context, channel string) {
+	filter, err := parseFilter(c, streamParams)
+	if err != nil {
+		response.WriteErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+	raw := c.GetHeader(HeaderLastEventID)
+	if raw == "" {
+		raw = c.Query(KeyLastEventID)
+	}
+	var after domain.ChangeID
+	if raw != "" {
+		if after, err = domain.ParseChangeID(raw); err != nil {
+			response.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidLastEventID)
+			return
+		}
+	}
+
+	sub := h.watch.Execute(after, channel, filter)
+	defer sub.Close()
+
+	c.Header(response.ContentType, "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if !sub.Complete() {
+		writeEvent(c.Writer, "", EventReset, []byte("{}"))
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, _ = io.WriteString(c.Writer, ": ping\n\n")
+		case change, ok := <-sub.Changes():
+			if !ok {
+				// El broker nos ha descolgado por lentos; el cliente se
+				// reconecta con Last-Event-ID y no pierde nada.
+				return
+			}
+			data, err := json.Marshal(change.Rocket)
+			if err != nil {
+				return
+			}
+			writeEvent(c.Writer, change.ID().String(), EventRocket, data)
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent escribe un evento SSE. data es JSON, así que no lleva saltos de
+// línea que haya que partir.
+func writeEvent(w io.Writer, id, event string, data []byte) {
+	if id != "" {
+		_, _ = fmt.Fprintf(w, "id: %s\n", id)
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
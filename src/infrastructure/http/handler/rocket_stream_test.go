@@ -0,0 +1,136 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	h "lunar/src/infrastructure/http/handler"
+)
+
+const (
+	pathStream       = "/api/rockets/stream"
+	pathRocketStream = "/api/rockets/:channel/stream"
+	urlRocketStream  = "/api/rockets/%s/stream"
+)
+
+func newStreamRouter(t *testing.T, uc application.WatchRocketsUCInterface) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	hdl := h.NewRocketStream(uc)
+	r.GET(pathStream, hdl.All)
+	r.GET(pathRocketStream, hdl.One)
+	return r
+}
+
+// fakeSubscription entrega los cambios que se le den y luego se cierra, como
+// cuando el broker descuelga a un suscriptor.
+type fakeSubscription struct {
+	ch       chan domain.RocketChange
+	complete bool
+	closed   bool
+}
+
+func newFakeSubscription(complete bool, changes ...domain.RocketChange) *fakeSubscription {
+	s := &fakeSubscription{ch: make(chan domain.RocketChange, len(changes)), complete: complete}
+	for _, c := range changes {
+		s.ch <- c
+	}
+	close(s.ch)
+	return s
+}
+
+func (s *fakeSubscription) Changes() <-chan domain.RocketChange { return s.ch }
+func (s *fakeSubscription) Complete() bool                      { return s.complete }
+func (s *fakeSubscription) Sync()                               {}
+func (s *fakeSubscription) Close()                              { s.closed = true }
+
+func TestRocketStream_All_WritesEvents(t *testing.T) {
+	ucMock := &application.WatchRocketsUCMock{}
+
+	sub := newFakeSubscription(true,
+		domain.RocketChange{Epoch: "e1", Sequence: 7, Rocket: domain.Rocket{Channel: "abc", Speed: 100}},
+		domain.RocketChange{Epoch: "e1", Sequence: 8, Rocket: domain.Rocket{Channel: "def", Speed: 200}},
+	)
+	ucMock.
+		On("Execute", domain.ChangeID{}, "", domain.RocketFilter{Status: domain.StatusLaunched}).
+		Return(sub).
+		Once()
+
+	r := newStreamRouter(t, ucMock)
+	w := doGET(r, pathStream+"?status=LAUNCHED")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	require.Len(t, events, 2)
+	require.True(t, strings.HasPrefix(events[0], "id: e1-7\nevent: rocket\ndata: {"), events[0])
+	require.Contains(t, events[0], `"Channel":"abc"`)
+	require.True(t, strings.HasPrefix(events[1], "id: e1-8\nevent: rocket\n"), events[1])
+	require.True(t, sub.closed, "subscription not closed")
+	ucMock.AssertExpectations(t)
+}
+
+func TestRocketStream_One_ResumesFromLastEventID(t *testing.T) {
+	ucMock := &application.WatchRocketsUCMock{}
+
+	ucMock.
+		On("Execute", domain.ChangeID{Epoch: "e1", Sequence: 41}, "abc", domain.RocketFilter{}).
+		Return(newFakeSubscription(true)).
+		Once()
+
+	r := newStreamRouter(t, ucMock)
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf(urlRocketStream, "abc"), nil)
+	req.Header.Set(h.HeaderLastEventID, "e1-41")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
+
+func TestRocketStream_IncompleteResume_SendsReset(t *testing.T) {
+	ucMock := &application.WatchRocketsUCMock{}
+
+	ucMock.
+		// Un id sin epoch es de antes de que los hubiera.
+		On("Execute", domain.ChangeID{Sequence: 3}, "", domain.RocketFilter{}).
+		Return(newFakeSubscription(false)).
+		Once()
+
+	r := newStreamRouter(t, ucMock)
+	w := doGET(r, pathStream+"?lastEventId=3")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "event: reset\ndata: {}\n\n", w.Body.String())
+	ucMock.AssertExpectations(t)
+}
+
+func TestRocketStream_InvalidParams_Returns400_AndDoesNotSubscribe(t *testing.T) {
+	for name, query := range map[string]string{
+		"bad last event id": "?lastEventId=abc",
+		"no epoch":          "?lastEventId=-3",
+		"list only param":   "?sort=speed",
+		"bad filter":        "?speed_gte=fast",
+	} {
+		t.Run(name, func(t *testing.T) {
+			ucMock := &application.WatchRocketsUCMock{}
+
+			r := newStreamRouter(t, ucMock)
+			w := doGET(r, pathStream+query)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			ucMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
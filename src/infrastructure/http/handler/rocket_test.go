@@ -1,10 +1,13 @@
 package handler_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/mock"
@@ -13,6 +16,8 @@ import (
 	"lunar/src/application"
 	"lunar/src/domain"
 	h "lunar/src/infrastructure/http/handler"
+	"lunar/src/infrastructure/notify"
+	"lunar/src/infrastructure/persistence"
 )
 
 const (
@@ -26,11 +31,32 @@ const (
 // -------- helpers --------
 
 func newRocketsRouter(t *testing.T, getUC application.GetRocketUCInterface, listUC application.ListRocketsUCInterface) *gin.Engine {
+	t.Helper()
+	return newRocketsRouterAsOf(t, getUC, listUC, &application.GetRocketAsOfUCMock{})
+}
+
+func newRocketsRouterAsOf(
+	t *testing.T,
+	getUC application.GetRocketUCInterface,
+	listUC application.ListRocketsUCInterface,
+	asOfUC application.GetRocketAsOfUCInterface,
+) *gin.Engine {
+	t.Helper()
+	return newRocketsRouterWait(t, getUC, listUC, asOfUC, &application.WaitRocketUCMock{})
+}
+
+func newRocketsRouterWait(
+	t *testing.T,
+	getUC application.GetRocketUCInterface,
+	listUC application.ListRocketsUCInterface,
+	asOfUC application.GetRocketAsOfUCInterface,
+	waitUC application.WaitRocketUCInterface,
+) *gin.Engine {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
-	hdl := h.NewRockets(getUC, listUC)
+	hdl := h.NewRockets(getUC, listUC, asOfUC, waitUC)
 	r.GET(pathGetOne, hdl.GetOne)
 	r.GET(pathList, hdl.List)
 	return r
@@ -103,6 +129,202 @@ func TestRockets_GetOne_InternalError_Returns500(t *testing.T) {
 	getMock.AssertExpectations(t)
 }
 
+func TestRockets_GetOne_AsOf_UsesHistory_Returns200(t *testing.T) {
+	getMock := &application.GetRocketUCMock{}
+	listMock := &application.ListRocketsUCMock{}
+	asOfMock := &application.GetRocketAsOfUCMock{}
+
+	ch := "abc"
+	at := time.Date(2022, 2, 2, 19, 39, 5, 0, time.UTC)
+	point := domain.PointInTime{MessageNumber: 3, Time: at}
+
+	asOfMock.
+		On("Execute", ch, point).
+		Return(domain.Rocket{Channel: ch, Speed: 100}, true, nil).
+		Once()
+
+	r := newRocketsRouterAsOf(t, getMock, listMock, asOfMock)
+	w := doGET(r, fmt.Sprintf(urlGetOne+"?asOf=3&at=%s", ch, at.Format(time.RFC3339)))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	asOfMock.AssertExpectations(t)
+	getMock.AssertNotCalled(t, "Execute", mock.Anything)
+}
+
+func TestRockets_GetOne_AsOf_Invalid_Returns400(t *testing.T) {
+	for _, query := range []string{"asOf=0", "asOf=abc", "at=yesterday"} {
+		t.Run(query, func(t *testing.T) {
+			getMock := &application.GetRocketUCMock{}
+			asOfMock := &application.GetRocketAsOfUCMock{}
+
+			r := newRocketsRouterAsOf(t, getMock, &application.ListRocketsUCMock{}, asOfMock)
+			w := doGET(r, fmt.Sprintf(urlGetOne+"?%s", "abc", query))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			asOfMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+			getMock.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	}
+}
+
+func TestRockets_GetOne_AsOf_NotFound_Returns404(t *testing.T) {
+	asOfMock := &application.GetRocketAsOfUCMock{}
+
+	asOfMock.
+		On("Execute", "abc", domain.PointInTime{MessageNumber: 1}).
+		Return(domain.Rocket{}, false, nil).
+		Once()
+
+	r := newRocketsRouterAsOf(t, &application.GetRocketUCMock{}, &application.ListRocketsUCMock{}, asOfMock)
+	w := doGET(r, fmt.Sprintf(urlGetOne+"?asOf=1", "abc"))
+
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	asOfMock.AssertExpectations(t)
+}
+
+func TestRockets_GetOne_AsOf_HistoryTrimmed_Returns410(t *testing.T) {
+	asOfMock := &application.GetRocketAsOfUCMock{}
+
+	asOfMock.
+		On("Execute", "abc", domain.PointInTime{MessageNumber: 1}).
+		Return(domain.Rocket{}, false, domain.ErrHistoryTrimmed).
+		Once()
+
+	r := newRocketsRouterAsOf(t, &application.GetRocketUCMock{}, &application.ListRocketsUCMock{}, asOfMock)
+	w := doGET(r, fmt.Sprintf(urlGetOne+"?asOf=1", "abc"))
+
+	require.Equal(t, http.StatusGone, w.Code, w.Body.String())
+	asOfMock.AssertExpectations(t)
+}
+
+func TestRockets_GetOne_MinMessageNumber_Reached_Returns200(t *testing.T) {
+	getMock := &application.GetRocketUCMock{}
+	waitMock := &application.WaitRocketUCMock{}
+
+	waitMock.
+		On("Execute", mock.Anything, "abc", 4).
+		Return(domain.Rocket{Channel: "abc", LastMsgNum: 5}, true, true, nil).
+		Once()
+
+	r := newRocketsRouterWait(t, getMock, &application.ListRocketsUCMock{}, &application.GetRocketAsOfUCMock{}, waitMock)
+	w := doGET(r, fmt.Sprintf(urlGetOne+"?minMessageNumber=4&wait=5s", "abc"))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, h.WaitReached, w.Header().Get(h.HeaderWaitResult))
+	waitMock.AssertExpectations(t)
+	getMock.AssertNotCalled(t, "Execute", mock.Anything)
+}
+
+func TestRockets_GetOne_MinMessageNumber_Timeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		found    bool
+		wantCode int
+	}{
+		{"returns last known version", true, http.StatusOK},
+		{"rocket never showed up", false, http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			waitMock := &application.WaitRocketUCMock{}
+
+			var deadline time.Time
+			waitMock.
+				On("Execute", mock.Anything, "abc", 4).
+				Run(func(args mock.Arguments) { deadline, _ = args.Get(0).(context.Context).Deadline() }).
+				Return(domain.Rocket{Channel: "abc", LastMsgNum: 2}, tc.found, false, nil).
+				Once()
+
+			r := newRocketsRouterWait(t, &application.GetRocketUCMock{}, &application.ListRocketsUCMock{}, &application.GetRocketAsOfUCMock{}, waitMock)
+			start := time.Now()
+			w := doGET(r, fmt.Sprintf(urlGetOne+"?minMessageNumber=4&wait=2s", "abc"))
+
+			require.Equal(t, tc.wantCode, w.Code, w.Body.String())
+			require.Equal(t, h.WaitTimeout, w.Header().Get(h.HeaderWaitResult))
+			require.WithinDuration(t, start.Add(2*time.Second), deadline, time.Second)
+			waitMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRockets_GetOne_MinMessageNumber_WithoutWait_UsesDefault(t *testing.T) {
+	waitMock := &application.WaitRocketUCMock{}
+
+	var deadline time.Time
+	waitMock.
+		On("Execute", mock.Anything, "abc", 4).
+		Run(func(args mock.Arguments) { deadline, _ = args.Get(0).(context.Context).Deadline() }).
+		Return(domain.Rocket{Channel: "abc", LastMsgNum: 5}, true, true, nil).
+		Once()
+
+	r := newRocketsRouterWait(t, &application.GetRocketUCMock{}, &application.ListRocketsUCMock{}, &application.GetRocketAsOfUCMock{}, waitMock)
+	start := time.Now()
+	w := doGET(r, fmt.Sprintf(urlGetOne+"?minMessageNumber=4", "abc"))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, h.WaitReached, w.Header().Get(h.HeaderWaitResult))
+	require.WithinDuration(t, start.Add(h.DefaultWait), deadline, time.Second)
+	waitMock.AssertExpectations(t)
+}
+
+func TestRockets_GetOne_MinMessageNumber_Invalid_Returns400(t *testing.T) {
+	for _, query := range []string{
+		"minMessageNumber=0",
+		"minMessageNumber=abc",
+		"wait=5s",
+		"minMessageNumber=3&wait=soon",
+		"minMessageNumber=3&wait=-1s",
+		"minMessageNumber=3&wait=1m",
+		"minMessageNumber=3&asOf=2",
+		"minMessageNumber=3&at=2022-02-02T19:39:05Z",
+	} {
+		t.Run(query, func(t *testing.T) {
+			waitMock := &application.WaitRocketUCMock{}
+
+			r := newRocketsRouterWait(t, &application.GetRocketUCMock{}, &application.ListRocketsUCMock{}, &application.GetRocketAsOfUCMock{}, waitMock)
+			w := doGET(r, fmt.Sprintf(urlGetOne+"?%s", "abc", query))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			waitMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRockets_GetOne_MinMessageNumber_WakesUpOnApply(t *testing.T) {
+	broker := notify.NewBroker(notify.DefaultHistory, notify.DefaultBuffer)
+	store := persistence.NewMemoryStore(persistence.WithChangeListener(broker.Publish))
+
+	r := newRocketsRouterWait(t,
+		application.NewGetRocketUC(store),
+		application.NewListRocketsUC(store),
+		application.NewGetRocketAsOfUC(store),
+		application.NewWaitRocketUC(store, broker),
+	)
+
+	env := domain.MessageEnvelope{}
+	env.Metadata.Channel = "abc"
+	env.Metadata.MessageNum = 1
+	env.Metadata.MessageTime = "2022-02-02T19:39:05Z"
+	env.Metadata.MessageType = domain.TypeSpeedIncreased
+	env.Message = json.RawMessage(`{"by":300}`)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = store.Apply(env)
+	}()
+
+	start := time.Now()
+	w := doGET(r, fmt.Sprintf(urlGetOne+"?minMessageNumber=1&wait=10s", "abc"))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, h.WaitReached, w.Header().Get(h.HeaderWaitResult))
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	var got domain.Rocket
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, 1, got.LastMsgNum)
+	require.EqualValues(t, 300, got.Speed)
+}
+
 // -------- tests: List --------
 
 func TestRockets_List_HappyPath_Returns200(t *testing.T) {
@@ -114,8 +336,8 @@ func TestRockets_List_HappyPath_Returns200(t *testing.T) {
 	items := []domain.Rocket{{Channel: "a"}, {Channel: "b"}}
 
 	listMock.
-		On("Execute", sortBy, order).
-		Return(items, nil).
+		On("Execute", domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: sortBy, Order: order}}).
+		Return(domain.Page{Items: items}, nil).
 		Once()
 
 	r := newRocketsRouter(t, getMock, listMock)
@@ -126,6 +348,24 @@ func TestRockets_List_HappyPath_Returns200(t *testing.T) {
 	listMock.AssertNumberOfCalls(t, "Execute", 1)
 }
 
+func TestRockets_List_ExtraSortKeys_Returns200(t *testing.T) {
+	for _, sortBy := range []string{h.SortByEventTime, h.SortByIngested, h.SortByAltitude, h.SortByFuelLevel, h.SortByStages} {
+		t.Run(sortBy, func(t *testing.T) {
+			listMock := &application.ListRocketsUCMock{}
+			listMock.
+				On("Execute", domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: sortBy, Order: h.OrderAsc}}).
+				Return(domain.Page{}, nil).
+				Once()
+
+			r := newRocketsRouter(t, &application.GetRocketUCMock{}, listMock)
+			w := doGET(r, fmt.Sprintf(urlList, sortBy, h.OrderAsc))
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			listMock.AssertExpectations(t)
+		})
+	}
+}
+
 func TestRockets_List_InvalidSort_Returns400_AndDoesNotCallUC(t *testing.T) {
 	getMock := &application.GetRocketUCMock{}
 	listMock := &application.ListRocketsUCMock{}
@@ -134,7 +374,7 @@ func TestRockets_List_InvalidSort_Returns400_AndDoesNotCallUC(t *testing.T) {
 	w := doGET(r, fmt.Sprintf(urlList, "oops", h.OrderAsc))
 
 	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
-	listMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+	listMock.AssertNotCalled(t, "Execute", mock.Anything)
 }
 
 func TestRockets_List_InvalidOrder_Returns400_AndDoesNotCallUC(t *testing.T) {
@@ -145,7 +385,7 @@ func TestRockets_List_InvalidOrder_Returns400_AndDoesNotCallUC(t *testing.T) {
 	w := doGET(r, fmt.Sprintf(urlList, h.SortByChannel, "down"))
 
 	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
-	listMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+	listMock.AssertNotCalled(t, "Execute", mock.Anything)
 }
 
 func TestRockets_List_InternalError_Returns500(t *testing.T) {
@@ -156,7 +396,7 @@ func TestRockets_List_InternalError_Returns500(t *testing.T) {
 	order := h.OrderAsc
 
 	listMock.
-		On("Execute", sortBy, order).
+		On("Execute", domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: sortBy, Order: order}}).
 		Return(nil, fmt.Errorf("repo error")).
 		Once()
 
@@ -166,3 +406,113 @@ func TestRockets_List_InternalError_Returns500(t *testing.T) {
 	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
 	listMock.AssertExpectations(t)
 }
+
+func TestRockets_List_Paginated_ReturnsCursors(t *testing.T) {
+	listMock := &application.ListRocketsUCMock{}
+
+	cur := domain.Cursor{SortBy: h.SortBySpeed, Order: h.OrderDesc, Channel: "b", Value: "300"}
+	next := domain.Cursor{SortBy: h.SortBySpeed, Order: h.OrderDesc, Channel: "d", Value: "100"}
+	prev := domain.Cursor{SortBy: h.SortBySpeed, Order: h.OrderDesc, Channel: "c", Value: "200", Backward: true}
+	listMock.
+		On("Execute", domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: h.SortBySpeed, Order: h.OrderDesc, Limit: 2, Cursor: &cur}}).
+		Return(domain.Page{Items: []domain.Rocket{{Channel: "c"}, {Channel: "d"}}, Next: &next, Prev: &prev}, nil).
+		Once()
+
+	r := newRocketsRouter(t, &application.GetRocketUCMock{}, listMock)
+	w := doGET(r, fmt.Sprintf(urlList, h.SortBySpeed, h.OrderDesc)+"&limit=2&cursor="+cur.Encode())
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Items      []domain.Rocket `json:"items"`
+		NextCursor string          `json:"nextCursor"`
+		PrevCursor string          `json:"prevCursor"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Items, 2)
+	require.Equal(t, next.Encode(), body.NextCursor)
+	require.Equal(t, prev.Encode(), body.PrevCursor)
+	listMock.AssertExpectations(t)
+}
+
+func TestRockets_List_InvalidPagination_Returns400_AndDoesNotCallUC(t *testing.T) {
+	other := domain.Cursor{SortBy: h.SortByChannel, Order: h.OrderAsc, Channel: "b", Value: "b"}
+	for name, query := range map[string]string{
+		"zero limit":        "&limit=0",
+		"huge limit":        "&limit=1001",
+		"text limit":        "&limit=ten",
+		"garbage cursor":    "&cursor=bm90IGpzb24",
+		"cursor other sort": "&cursor=" + other.Encode(),
+		"cursor bad pivot":  "&cursor=" + domain.Cursor{SortBy: h.SortBySpeed, Order: h.OrderDesc, Channel: "b", Value: "fast"}.Encode(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			listMock := &application.ListRocketsUCMock{}
+
+			r := newRocketsRouter(t, &application.GetRocketUCMock{}, listMock)
+			w := doGET(r, fmt.Sprintf(urlList, h.SortBySpeed, h.OrderDesc)+query)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			listMock.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	}
+}
+
+func TestRockets_List_Filters_PassedToUC(t *testing.T) {
+	listMock := &application.ListRocketsUCMock{}
+
+	minSpeed, maxSpeed := int64(100), int64(900)
+	from, _ := time.Parse(time.RFC3339, "2022-02-02T19:39:05Z")
+	want := domain.RocketQuery{
+		PageRequest: domain.PageRequest{SortBy: h.SortBySpeed, Order: h.OrderDesc},
+		Filter: domain.RocketFilter{
+			Status:        domain.StatusLaunched,
+			Type:          "Falcon-9",
+			MissionPrefix: "ARTEMIS",
+			Speed:         domain.IntRange{Min: &minSpeed, Max: &maxSpeed},
+			EventTime:     domain.TimeRange{From: from},
+		},
+	}
+	listMock.
+		On("Execute", want).
+		Return(domain.Page{}, nil).
+		Once()
+
+	r := newRocketsRouter(t, &application.GetRocketUCMock{}, listMock)
+	w := doGET(r, fmt.Sprintf(urlList, h.SortBySpeed, h.OrderDesc)+
+		"&status=LAUNCHED&type=Falcon-9&mission_prefix=ARTEMIS&speed_gte=100&speed_lte=900&event_time_gte=2022-02-02T19:39:05Z")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	listMock.AssertExpectations(t)
+}
+
+func TestRockets_List_InvalidFilter_Returns400_AndDoesNotCallUC(t *testing.T) {
+	for name, query := range map[string]string{
+		"unknown filter":      "&colour=red",
+		"unknown status":      "&status=FLYING",
+		"speed not a number":  "&speed_gte=fast",
+		"speed range swapped": "&speed_gte=10&speed_lte=5",
+		"bad time":            "&updated_at_lte=yesterday",
+		"time range swapped":  "&ingested_at_gte=2022-02-02T19:39:06Z&ingested_at_lte=2022-02-02T19:39:05Z",
+	} {
+		t.Run(name, func(t *testing.T) {
+			listMock := &application.ListRocketsUCMock{}
+
+			r := newRocketsRouter(t, &application.GetRocketUCMock{}, listMock)
+			w := doGET(r, fmt.Sprintf(urlList, h.SortBySpeed, h.OrderDesc)+query)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			listMock.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	}
+}
+
+func TestRockets_List_UnknownFilter_NamesIt(t *testing.T) {
+	r := newRocketsRouter(t, &application.GetRocketUCMock{}, &application.ListRocketsUCMock{})
+	w := doGET(r, fmt.Sprintf(urlList, h.SortBySpeed, h.OrderDesc)+"&speed_gt=5")
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var body struct {
+		Message string `json:"message"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+	require.Equal(t, `unknown query parameter "speed_gt"`, body.Message)
+}
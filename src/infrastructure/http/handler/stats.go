@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"lunar/src/application"
+	"lunar/src/infrastructure/http/httperror"
+	"lunar/src/infrastructure/http/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	KeyMinutes = "minutes"
+
+	// DefaultMinutes es la ventana de updatedRecently si no se pide otra;
+	// MaxMinutes, una semana.
+	DefaultMinutes = 5
+	MaxMinutes     = 7 * 24 * 60
+)
+
+type Stats struct {
+	get application.GetFleetStatsUCInterface
+}
+
+func NewStats(get application.GetFleetStatsUCInterface) *Stats {
+	return &Stats{get: get}
+}
+
+func (h *Stats) Get(c *gin.Context) {
+	minutes := DefaultMinutes
+	if raw, ok := c.GetQuery(KeyMinutes); ok {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > MaxMinutes {
+			response.WriteErrorResponse(c, http.StatusBadRequest, httperror.ErrInvalidMinutes)
+			return
+		}
+		minutes = n
+	}
+
+	stats, err := h.get.Execute(time.Duration(minutes) * time.Minute)
+	if err != nil {
+		response.WriteErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.WriteJSONResponse(c, http.StatusOK, stats)
+}
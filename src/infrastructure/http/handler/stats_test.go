@@ -0,0 +1,96 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	h "lunar/src/infrastructure/http/handler"
+)
+
+const pathStats = "/api/stats"
+
+func newStatsRouter(t *testing.T, uc application.GetFleetStatsUCInterface) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	hdl := h.NewStats(uc)
+	r.GET(pathStats, hdl.Get)
+	return r
+}
+
+func TestStats_Get_HappyPath_Returns200(t *testing.T) {
+	ucMock := &application.GetFleetStatsUCMock{}
+
+	stats := domain.NewFleetStats(15 * time.Minute)
+	stats.Total = 2
+	stats.ByStatus[domain.StatusLaunched] = 2
+	stats.ByType["Falcon-9"] = 2
+	stats.ByMission["ARTEMIS"] = 2
+	stats.Speed = domain.SpeedStats{Min: 100, Max: 300, Mean: 200, P50: 100, P90: 300, P95: 300, P99: 300}
+	stats.UpdatedRecently.Count = 1
+	ucMock.
+		On("Execute", 15*time.Minute).
+		Return(stats, nil).
+		Once()
+
+	r := newStatsRouter(t, ucMock)
+	w := doGET(r, pathStats+"?minutes=15")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var got domain.FleetStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, stats, got)
+	ucMock.AssertExpectations(t)
+}
+
+func TestStats_Get_DefaultWindow(t *testing.T) {
+	ucMock := &application.GetFleetStatsUCMock{}
+
+	ucMock.
+		On("Execute", h.DefaultMinutes*time.Minute).
+		Return(domain.NewFleetStats(h.DefaultMinutes*time.Minute), nil).
+		Once()
+
+	r := newStatsRouter(t, ucMock)
+	w := doGET(r, pathStats)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
+
+func TestStats_Get_InvalidMinutes_Returns400_AndDoesNotCallUC(t *testing.T) {
+	for _, raw := range []string{"abc", "0", "-5", "10081"} {
+		ucMock := &application.GetFleetStatsUCMock{}
+
+		r := newStatsRouter(t, ucMock)
+		w := doGET(r, pathStats+"?minutes="+raw)
+
+		require.Equal(t, http.StatusBadRequest, w.Code, raw)
+		ucMock.AssertNotCalled(t, "Execute", mock.Anything)
+	}
+}
+
+func TestStats_Get_StoreError_Returns500(t *testing.T) {
+	ucMock := &application.GetFleetStatsUCMock{}
+
+	ucMock.
+		On("Execute", h.DefaultMinutes*time.Minute).
+		Return(domain.FleetStats{}, errors.New("boom")).
+		Once()
+
+	r := newStatsRouter(t, ucMock)
+	w := doGET(r, pathStats)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	ucMock.AssertExpectations(t)
+}
@@ -4,6 +4,45 @@ import "errors"
 
 var (
 	ErrChannelNotFound = errors.New("channel not found")
-	ErrInvalidSort     = errors.New("ort by should be channel or speed or updated_at")
+	ErrReceiptNotFound = errors.New("receipt not found or expired")
+	ErrInvalidSort     = errors.New("sort should be channel, speed, updated_at, event_time, ingested_at, altitude, fuel_level or stages_separated")
 	ErrInvalidOrder    = errors.New("order should be asc or desc")
+	ErrInvalidAsOf     = errors.New("asOf should be a positive message number")
+	ErrInvalidAt       = errors.New("at should be an RFC3339 timestamp")
+	ErrInvalidLimit    = errors.New("limit should be a number between 1 and 1000")
+	ErrInvalidCursor   = errors.New("cursor is malformed or was issued for another sort or order")
+
+	ErrInvalidMinMessageNumber = errors.New("minMessageNumber should be a positive message number")
+	ErrInvalidWait             = errors.New("wait should be a duration between 0s and 30s")
+	ErrWaitWithoutMin          = errors.New("wait requires minMessageNumber")
+	ErrWaitWithPointInTime     = errors.New("minMessageNumber cannot be combined with asOf or at")
+
+	ErrUnknownFilter      = errors.New("unknown query parameter")
+	ErrInvalidStatus      = errors.New("status should be PENDING, LAUNCHED, IN_FLIGHT, EXPLODED or LANDED")
+	ErrInvalidSpeedFilter = errors.New("speed_gte and speed_lte should be integers")
+	ErrInvalidTimeFilter  = errors.New("updated_at, event_time and ingested_at bounds should be RFC3339 timestamps")
+	ErrInvalidRange       = errors.New("range lower bound should not be greater than its upper bound")
+
+	ErrInvalidMinutes     = errors.New("minutes should be a number between 1 and 10080")
+	ErrInvalidLastEventID = errors.New("Last-Event-ID should be the id of a previous event")
+
+	ErrInvalidSocketRequest  = errors.New("request should be a JSON object with a type")
+	ErrUnknownSocketRequest  = errors.New("type should be subscribe or unsubscribe")
+	ErrMissingSubscriptionID = errors.New("subscription id is required")
+	ErrDuplicateSubscription = errors.New("a subscription with this id already exists")
+	ErrUnknownSubscription   = errors.New("no subscription with this id")
+	ErrTooManySubscriptions  = errors.New("too many subscriptions on this connection")
+
+	ErrInvalidMode      = errors.New("mode should be async or sync")
+	ErrMessageDuplicate = errors.New("message was already processed")
+	ErrMessageStale     = errors.New("message is older than the rocket state and was ignored")
+	ErrMessageRejected  = errors.New("message was rejected")
+
+	ErrUnknownMessagesAction = errors.New("unknown action on /messages")
+	ErrInvalidBatch          = errors.New("body should be a JSON array of envelopes")
+	ErrInvalidBatchSize      = errors.New("batch should have between 1 and 1000 envelopes")
+	ErrInvalidAtomic         = errors.New("atomic should be true or false")
+	ErrBatchItemNotPublished = errors.New("not published: another envelope in the atomic batch is invalid")
+
+	ErrHistoryTrimmed = errors.New("history for that point is no longer retained")
 )
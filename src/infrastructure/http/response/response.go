@@ -1,6 +1,7 @@
 package response
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
@@ -14,8 +15,11 @@ const (
 func WriteErrorResponse(c *gin.Context, code int, err error) {
 	c.Writer.WriteHeader(code)
 	c.Writer.Header().Set(ContentType, ApplicationJson)
+	// El mensaje puede llevar texto del cliente (un parámetro desconocido,
+	// por ejemplo), así que se escapa como JSON.
+	msg, _ := json.Marshal(err.Error())
 	_, _ = c.Writer.WriteString(
-		fmt.Sprintf("{\"code\":%d,\"message\":\"%s\"}", code, err.Error()),
+		fmt.Sprintf("{\"code\":%d,\"message\":%s}", code, msg),
 	)
 }
 
@@ -2,7 +2,18 @@ package routes
 
 const (
 	PostMessagesPath = "/messages"
+	ReceiptPath      = "/messages/:id"
 	ApiGroup         = "/api"
 	ListRocketsPath  = "/rockets"
 	GetRocketPath    = "/rockets/:channel"
+	RocketEventsPath = "/rockets/:channel/events"
+	RocketGapsPath   = "/rockets/:channel/gaps"
+	StatsPath        = "/stats"
+	StreamPath       = "/rockets/stream"
+	RocketStreamPath = "/rockets/:channel/stream"
+	WebSocketPath    = "/ws"
+
+	// gin no admite ':' escapado: el comodín recoge ":batch" y el handler
+	// comprueba la acción.
+	MessagesActionPath = "/messages:action"
 )
@@ -0,0 +1,198 @@
+// Package notify reparte en memoria los cambios de cohetes que avisan los
+// stores (WithChangeListener) entre los suscriptores de la API.
+package notify
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+const (
+	DefaultHistory = 1024
+	DefaultBuffer  = 256
+)
+
+// Broker numera cada cambio, guarda los últimos history para que un cliente
+// que se reconecta pueda seguir donde lo dejó y se los pasa a los
+// suscriptores. Los números llevan el epoch del proceso: uno de un arranque
+// anterior no se confunde con uno de este.
+//
+// Publish sólo deja el cambio en una cola: los stores lo llaman con el canal
+// bloqueado y no deben esperar a los suscriptores. Una goroutine numera y
+// reparte en el mismo orden. Repartir nunca bloquea: a un suscriptor con el
+// buffer lleno se le cierra el canal y tendrá que volver a suscribirse desde
+// el último cambio que recibió.
+type Broker struct {
+	mu      sync.Mutex
+	epoch   string
+	seq     uint64
+	history []domain.RocketChange
+	buffer  int
+	subs    map[*subscription]struct{}
+
+	qmu    sync.Mutex
+	queued []domain.Rocket
+	wake   chan struct{}
+}
+
+var _ port.ChangeFeed = (*Broker)(nil)
+
+// NewBroker crea un broker que recuerda history cambios y deja a cada
+// suscriptor hasta buffer cambios pendientes de leer.
+func NewBroker(history, buffer int) *Broker {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	b := &Broker{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: make([]domain.RocketChange, history),
+		buffer:  buffer,
+		subs:    make(map[*subscription]struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+	go b.dispatch()
+	return b
+}
+
+// Epoch identifica este arranque en los ids de los cambios.
+func (b *Broker) Epoch() string {
+	return b.epoch
+}
+
+// Publish es el listener que se pasa a los stores.
+func (b *Broker) Publish(r domain.Rocket) {
+	// Processed sólo lo usa el store y puede ser grande.
+	r.Processed = domain.MessageSet{}
+
+	b.qmu.Lock()
+	b.queued = append(b.queued, r)
+	b.qmu.Unlock()
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Broker) dispatch() {
+	for range b.wake {
+		b.mu.Lock()
+		b.flush()
+		b.mu.Unlock()
+	}
+}
+
+// flush numera y reparte lo que haya en la cola. Debe llamarse con mu
+// tomado.
+func (b *Broker) flush() {
+	b.qmu.Lock()
+	queued := b.queued
+	b.queued = nil
+	b.qmu.Unlock()
+
+	for _, r := range queued {
+		b.seq++
+		c := domain.RocketChange{Epoch: b.epoch, Sequence: b.seq, Rocket: r}
+		b.history[b.slot(b.seq)] = c
+		for sub := range b.subs {
+			if sub.match != nil && !sub.match(r) {
+				continue
+			}
+			select {
+			case sub.ch <- c:
+			default:
+				b.remove(sub)
+			}
+		}
+	}
+}
+
+// Subscribe reparte antes lo que esté en cola: lo publicado antes de
+// suscribirse es pasado, no cambios nuevos.
+func (b *Broker) Subscribe(after domain.ChangeID, match func(domain.Rocket) bool) port.ChangeSubscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flush()
+
+	seq, complete := after.Sequence, true
+	if seq > 0 && (after.Epoch != b.epoch || seq > b.seq) {
+		// Es de antes de un reinicio: no se sabe qué se ha perdido.
+		seq, complete = b.seq, false
+	}
+	if oldest := b.oldest(); seq > 0 && seq+1 < oldest {
+		seq, complete = oldest-1, false
+	}
+	var replay []domain.RocketChange
+	if seq > 0 {
+		for n := seq + 1; n <= b.seq; n++ {
+			if c := b.history[b.slot(n)]; match == nil || match(c.Rocket) {
+				replay = append(replay, c)
+			}
+		}
+	}
+
+	sub := &subscription{
+		broker:   b,
+		ch:       make(chan domain.RocketChange, len(replay)+b.buffer),
+		match:    match,
+		complete: complete,
+	}
+	for _, c := range replay {
+		sub.ch <- c
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// oldest es el primer número que sigue en history.
+func (b *Broker) oldest() uint64 {
+	if n := uint64(len(b.history)); b.seq > n {
+		return b.seq - n + 1
+	}
+	return 1
+}
+
+func (b *Broker) slot(seq uint64) int {
+	return int((seq - 1) % uint64(len(b.history)))
+}
+
+// remove da de baja al suscriptor. Debe llamarse con mu tomado.
+func (b *Broker) remove(sub *subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+type subscription struct {
+	broker   *Broker
+	ch       chan domain.RocketChange
+	match    func(domain.Rocket) bool
+	complete bool
+}
+
+func (s *subscription) Changes() <-chan domain.RocketChange {
+	return s.ch
+}
+
+func (s *subscription) Complete() bool {
+	return s.complete
+}
+
+func (s *subscription) Sync() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.flush()
+}
+
+func (s *subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
@@ -0,0 +1,207 @@
+package notify_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+	"lunar/src/infrastructure/notify"
+)
+
+func TestBroker_DeliversNewChangesInOrder(t *testing.T) {
+	b := notify.NewBroker(8, 8)
+	b.Publish(rocket("old"))
+
+	sub := b.Subscribe(domain.ChangeID{}, nil)
+	defer sub.Close()
+	b.Publish(rocket("a"))
+	b.Publish(rocket("b"))
+
+	if got := receive(t, sub, 2); got != "2:a 3:b" {
+		t.Errorf("changes mismatch; got=%q", got)
+	}
+	if !sub.Complete() {
+		t.Errorf("subscription from now should be complete")
+	}
+}
+
+func TestBroker_ResumesAfterSequence(t *testing.T) {
+	b := notify.NewBroker(8, 8)
+	for _, ch := range []string{"a", "b", "c"} {
+		b.Publish(rocket(ch))
+	}
+
+	sub := b.Subscribe(domain.ChangeID{Epoch: b.Epoch(), Sequence: 1}, nil)
+	defer sub.Close()
+	b.Publish(rocket("d"))
+
+	if got := receive(t, sub, 3); got != "2:b 3:c 4:d" {
+		t.Errorf("resume mismatch; got=%q", got)
+	}
+	if !sub.Complete() {
+		t.Errorf("resume within history should be complete")
+	}
+}
+
+func TestBroker_ResumeBeyondHistoryIsIncomplete(t *testing.T) {
+	b := notify.NewBroker(2, 8)
+	for i := 0; i < 5; i++ {
+		b.Publish(rocket(fmt.Sprint(i)))
+	}
+
+	old := b.Subscribe(domain.ChangeID{Epoch: b.Epoch(), Sequence: 1}, nil)
+	defer old.Close()
+	if got := receive(t, old, 2); got != "4:3 5:4" || old.Complete() {
+		t.Errorf("expected the retained tail and an incomplete flag; got=%q complete=%v", got, old.Complete())
+	}
+
+	// Un número que el broker no ha dado viene de antes de un reinicio.
+	future := b.Subscribe(domain.ChangeID{Epoch: b.Epoch(), Sequence: 99}, nil)
+	defer future.Close()
+	if future.Complete() {
+		t.Errorf("unknown sequence should be incomplete")
+	}
+}
+
+func TestBroker_ResumeFromAnotherEpochIsIncomplete(t *testing.T) {
+	before := notify.NewBroker(8, 8)
+	before.Publish(rocket("a"))
+	first := before.Subscribe(domain.ChangeID{}, nil)
+	before.Publish(rocket("b"))
+	c := <-first.Changes()
+	first.Close()
+
+	// El proceso reinicia: los números vuelven a empezar.
+	after := notify.NewBroker(8, 8)
+	for _, ch := range []string{"x", "y", "z"} {
+		after.Publish(rocket(ch))
+	}
+	sub := after.Subscribe(c.ID(), nil)
+	defer sub.Close()
+	after.Publish(rocket("w"))
+
+	if sub.Complete() {
+		t.Errorf("an id from another epoch should be incomplete")
+	}
+	if got := receive(t, sub, 1); got != "4:w" {
+		t.Errorf("an id from another epoch should not replay; got=%q", got)
+	}
+}
+
+func TestBroker_PublishDoesNotWaitForSubscribers(t *testing.T) {
+	b := notify.NewBroker(8, 8)
+	entered, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	sub := b.Subscribe(domain.ChangeID{}, func(domain.Rocket) bool {
+		once.Do(func() {
+			close(entered)
+			<-release
+		})
+		return true
+	})
+	defer sub.Close()
+
+	b.Publish(rocket("a"))
+	<-entered
+	// El reparto está parado en match; publicar no debe esperarlo.
+	done := make(chan struct{})
+	go func() {
+		b.Publish(rocket("b"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow match")
+	}
+	close(release)
+}
+
+func TestBroker_FiltersChanges(t *testing.T) {
+	b := notify.NewBroker(8, 8)
+	b.Publish(rocket("a"))
+	b.Publish(rocket("b"))
+
+	sub := b.Subscribe(domain.ChangeID{Epoch: b.Epoch(), Sequence: 1}, func(r domain.Rocket) bool { return r.Channel == "b" })
+	defer sub.Close()
+	b.Publish(rocket("a"))
+	b.Publish(rocket("b"))
+
+	if got := receive(t, sub, 2); got != "2:b 4:b" {
+		t.Errorf("filtered changes mismatch; got=%q", got)
+	}
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	b := notify.NewBroker(8, 2)
+	slow := b.Subscribe(domain.ChangeID{}, nil)
+	fast := b.Subscribe(domain.ChangeID{}, nil)
+	defer fast.Close()
+
+	for i := 0; i < 3; i++ {
+		b.Publish(rocket(fmt.Sprint(i)))
+		<-fast.Changes()
+	}
+
+	n := 0
+	for range slow.Changes() {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("slow subscriber should keep what fit in its buffer; got=%d", n)
+	}
+	slow.Close() // cerrar dos veces no falla
+}
+
+func TestBroker_CloseStopsDelivery(t *testing.T) {
+	b := notify.NewBroker(8, 8)
+	sub := b.Subscribe(domain.ChangeID{}, nil)
+	sub.Close()
+	b.Publish(rocket("a"))
+
+	if _, ok := <-sub.Changes(); ok {
+		t.Errorf("closed subscription received a change")
+	}
+}
+
+// ---------- helpers ----------
+
+func rocket(channel string) domain.Rocket {
+	return *domain.NewRocket(channel)
+}
+
+func receive(t *testing.T, sub port.ChangeSubscription, n int) string {
+	t.Helper()
+	var got string
+	for i := 0; i < n; i++ {
+		c, ok := <-sub.Changes()
+		if !ok {
+			t.Fatalf("subscription closed after %d changes", i)
+		}
+		if got != "" {
+			got += " "
+		}
+		got += fmt.Sprintf("%d:%s", c.Sequence, c.Rocket.Channel)
+	}
+	return got
+}
+
+func TestBroker_SyncDeliversWhatIsQueued(t *testing.T) {
+	b := notify.NewBroker(8, 8)
+	sub := b.Subscribe(domain.ChangeID{}, nil)
+	defer sub.Close()
+
+	b.Publish(rocket("a"))
+	sub.Sync()
+	select {
+	case c := <-sub.Changes():
+		if c.Rocket.Channel != "a" {
+			t.Errorf("change mismatch; got=%+v", c)
+		}
+	default:
+		t.Fatalf("Sync returned before the queued change was delivered")
+	}
+}
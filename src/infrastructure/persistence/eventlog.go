@@ -0,0 +1,325 @@
+package persistence
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lunar/src/domain"
+)
+
+// Formato de cada registro en un segmento:
+//
+//	[4 bytes len][4 bytes crc32c(payload)][payload JSON del logRecord]
+//
+// Los segmentos se llaman por el offset de su primer registro (%020d.log).
+const (
+	segmentExt   = ".log"
+	headerSize   = 8
+	maxRecordLen = 16 << 20
+)
+
+var (
+	ErrCorruptSegment = errors.New("corrupt event log segment")
+	ErrLogUnusable    = errors.New("event log unusable after failed write")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// logRecord es el envelope tal cual más el instante en que se ingirió, para
+// que al reaplicarlo el cohete quede con los mismos tiempos. Va aplanado: un
+// registro antiguo (sólo el envelope) se lee con IngestedAt a cero.
+type logRecord struct {
+	domain.MessageEnvelope
+	IngestedAt time.Time `json:"ingestedAt,omitzero"`
+}
+
+type segment struct {
+	base uint64
+	path string
+}
+
+// logFile es lo que el log necesita del segmento activo; *os.File lo cumple.
+type logFile interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+type eventLog struct {
+	dir             string
+	maxSegmentBytes int64
+
+	// segMu protege segments: compact corre en segundo plano, tras un
+	// snapshot, mientras Apply puede estar rotando.
+	segMu      sync.Mutex
+	segments   []segment
+	active     logFile
+	activeSize int64
+	next       uint64
+	// err deja el log cerrado a escrituras si no se pudo deshacer una
+	// escritura fallida; al reabrirlo se trunca el registro a medias.
+	err error
+}
+
+// openEventLog abre (o crea) el log en dir y llama a fn con cada registro
+// válido en orden a partir del offset from (lo anterior ya lo cubre un
+// snapshot). Un registro incompleto o con CRC erróneo al final del último
+// segmento se considera una escritura a medias y se trunca; en cualquier
+// otro sitio es corrupción y se devuelve error.
+func openEventLog(dir string, maxSegmentBytes int64, from uint64, fn func(offset uint64, rec logRecord) error) (*eventLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	segs, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	l := &eventLog{dir: dir, maxSegmentBytes: maxSegmentBytes, segments: segs, next: from}
+	if len(segs) > 0 && segs[0].base > from {
+		return nil, fmt.Errorf("%w: log starts at %d, expected %d or earlier", ErrCorruptSegment, segs[0].base, from)
+	}
+
+	skip := func(offset uint64, rec logRecord) error {
+		if offset < from || fn == nil {
+			return nil
+		}
+		return fn(offset, rec)
+	}
+	for i, seg := range segs {
+		if i == 0 {
+			l.next = seg.base
+		}
+		if seg.base != l.next {
+			return nil, fmt.Errorf("%w: %s starts at %d, expected %d", ErrCorruptSegment, seg.path, seg.base, l.next)
+		}
+		last := i == len(segs)-1
+		n, validSize, err := readSegment(seg, skip)
+		if err != nil && !(last && errors.Is(err, ErrCorruptSegment)) {
+			return nil, err
+		}
+		if err != nil {
+			if err := os.Truncate(seg.path, validSize); err != nil {
+				return nil, err
+			}
+		}
+		l.next = seg.base + n
+		if last {
+			l.activeSize = validSize
+		}
+	}
+
+	if l.next < from {
+		return nil, fmt.Errorf("%w: log ends at %d, expected at least %d", ErrCorruptSegment, l.next, from)
+	}
+	if len(segs) == 0 {
+		if err := l.roll(); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	f, err := os.OpenFile(segs[len(segs)-1].path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l.active = f
+	return l, nil
+}
+
+// append escribe el registro, hace fsync y devuelve su offset.
+func (l *eventLog) append(rec logRecord) (uint64, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return 0, err
+	}
+	if l.activeSize > 0 && l.activeSize+headerSize+int64(len(payload)) > l.maxSegmentBytes {
+		if err := l.roll(); err != nil {
+			return 0, err
+		}
+	}
+
+	buf := encodeRecord(payload)
+	if _, err := l.active.Write(buf); err != nil {
+		return 0, l.discardTail(err)
+	}
+	if err := l.active.Sync(); err != nil {
+		return 0, l.discardTail(err)
+	}
+	l.activeSize += int64(len(buf))
+	off := l.next
+	l.next++
+	return off, nil
+}
+
+// discardTail deshace una escritura fallida. El registro no se ha confirmado
+// y no puede quedar a medias delante de los siguientes: al arrancar se
+// truncaría ahí y se perderían registros que sí se confirmaron.
+func (l *eventLog) discardTail(cause error) error {
+	if err := l.active.Truncate(l.activeSize); err != nil {
+		l.err = fmt.Errorf("%w: %v (truncate: %v)", ErrLogUnusable, cause, err)
+		return l.err
+	}
+	if err := l.active.Sync(); err != nil {
+		l.err = fmt.Errorf("%w: %v (sync: %v)", ErrLogUnusable, cause, err)
+		return l.err
+	}
+	return cause
+}
+
+func (l *eventLog) close() error {
+	if l.active == nil {
+		return nil
+	}
+	err := l.active.Close()
+	l.active = nil
+	return err
+}
+
+// compact borra los segmentos sellados cuyos registros están todos por
+// debajo de upTo. El segmento activo no se toca nunca.
+func (l *eventLog) compact(upTo uint64) error {
+	l.segMu.Lock()
+	drop := 0
+	for drop < len(l.segments)-1 && l.segments[drop+1].base <= upTo {
+		drop++
+	}
+	old := l.segments[:drop]
+	l.segments = append([]segment(nil), l.segments[drop:]...)
+	l.segMu.Unlock()
+	if len(old) == 0 {
+		return nil
+	}
+
+	for _, seg := range old {
+		if err := os.Remove(seg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return syncDir(l.dir)
+}
+
+// roll cierra el segmento activo y abre uno nuevo empezando en l.next.
+func (l *eventLog) roll() error {
+	if l.active != nil {
+		if err := l.active.Close(); err != nil {
+			return err
+		}
+	}
+	seg := segment{base: l.next, path: filepath.Join(l.dir, fmt.Sprintf("%020d%s", l.next, segmentExt))}
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.segMu.Lock()
+	l.segments = append(l.segments, seg)
+	l.segMu.Unlock()
+	l.active = f
+	l.activeSize = 0
+	return nil
+}
+
+// readSegment devuelve cuántos registros válidos tiene el segmento y hasta
+// qué byte llegan. Si encuentra un registro roto devuelve ErrCorruptSegment
+// junto con esos valores.
+func readSegment(seg segment, fn func(offset uint64, rec logRecord) error) (uint64, int64, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var (
+		n      uint64
+		pos    int64
+		header [headerSize]byte
+	)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, pos, nil
+			}
+			return n, pos, fmt.Errorf("%w: %s: truncated header at %d", ErrCorruptSegment, seg.path, pos)
+		}
+		size := binary.BigEndian.Uint32(header[0:4])
+		sum := binary.BigEndian.Uint32(header[4:8])
+		if size > maxRecordLen {
+			return n, pos, fmt.Errorf("%w: %s: record too large at %d", ErrCorruptSegment, seg.path, pos)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return n, pos, fmt.Errorf("%w: %s: truncated record at %d", ErrCorruptSegment, seg.path, pos)
+		}
+		if crc32.Checksum(payload, crcTable) != sum {
+			return n, pos, fmt.Errorf("%w: %s: crc mismatch at %d", ErrCorruptSegment, seg.path, pos)
+		}
+		var rec logRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return n, pos, fmt.Errorf("%w: %s: %v", ErrCorruptSegment, seg.path, err)
+		}
+		if fn != nil {
+			if err := fn(seg.base+n, rec); err != nil {
+				return n, pos, err
+			}
+		}
+		n++
+		pos += headerSize + int64(size)
+	}
+}
+
+func encodeRecord(payload []byte) []byte {
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[headerSize:], payload)
+	return buf
+}
+
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, segment{base: base, path: filepath.Join(dir, name)})
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].base < segs[j].base })
+	return segs, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,38 @@
+package persistence
+
+import "errors"
+
+var errInjected = errors.New("injected write failure")
+
+// faultyFile escribe sólo los primeros partial bytes de la siguiente
+// escritura y falla, como un disco lleno a mitad de registro.
+type faultyFile struct {
+	logFile
+	partial       int
+	failTruncate  bool
+	alreadyFailed bool
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.alreadyFailed {
+		return f.logFile.Write(p)
+	}
+	f.alreadyFailed = true
+	n, _ := f.logFile.Write(p[:f.partial])
+	return n, errInjected
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errInjected
+	}
+	return f.logFile.Truncate(size)
+}
+
+// TornNextWrite hace que la siguiente escritura en el log de s deje un
+// registro a medias y falle. Con failTruncate tampoco se puede deshacer.
+func TornNextWrite(s *FileStore, partial int, failTruncate bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.active = &faultyFile{logFile: s.log.active, partial: partial, failTruncate: failTruncate}
+}
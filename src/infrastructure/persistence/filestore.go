@@ -0,0 +1,161 @@
+package persistence
+
+import (
+	"sync"
+	"time"
+
+	"lunar/src/domain"
+)
+
+// FileStore persiste cada envelope aceptado en un log segmentado en disco y
+// mantiene las proyecciones en un MemoryStore. Al arrancar carga el último
+// snapshot válido y reaplica sólo la cola del log que no cubre.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+	log *eventLog
+	mem *MemoryStore
+
+	snapshotEvery int
+	sinceSnapshot int
+	// saving se cierra cuando termina el snapshot en curso; nil si no hay.
+	saving chan struct{}
+}
+
+func NewFileStore(dir string, opts ...Option) (*FileStore, error) {
+	o := buildOptions(opts)
+	mem := NewMemoryStore(opts...)
+
+	snap, ok, err := loadLatestSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		mem.restoreState(snap.State)
+	}
+
+	// Reconstruir no es cambiar: sólo se avisa de lo que llegue después.
+	mem.opts.onChange, mem.opts.onResult = nil, nil
+	log, err := openEventLog(dir, o.maxSegmentBytes, snap.Offset, func(_ uint64, rec logRecord) error {
+		// Un envelope que falló al aplicarse en vivo vuelve a fallar igual;
+		// el estado resultante es el mismo que había antes del reinicio.
+		at := rec.IngestedAt
+		if at.IsZero() {
+			at = mem.opts.now()
+		}
+		_ = mem.applyAt(rec.MessageEnvelope, at)
+		return nil
+	})
+	mem.opts.onChange, mem.opts.onResult = o.onChange, o.onResult
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{
+		dir:           dir,
+		log:           log,
+		mem:           mem,
+		snapshotEvery: o.snapshotEvery,
+		sinceSnapshot: int(log.next - snap.Offset),
+	}, nil
+}
+
+// Apply escribe primero en el log (write-ahead) y después proyecta.
+func (s *FileStore) Apply(env domain.MessageEnvelope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.mem.opts.now()
+	if _, err := s.log.append(logRecord{MessageEnvelope: env, IngestedAt: now}); err != nil {
+		return err
+	}
+	if err := s.mem.applyAt(env, now); err != nil {
+		return err
+	}
+	s.sinceSnapshot++
+	if s.snapshotEvery > 0 && s.sinceSnapshot >= s.snapshotEvery {
+		// Aquí sólo se toma la foto; se escribe en segundo plano. El envelope
+		// ya es durable: si el snapshot falla, el log sigue siendo la fuente
+		// y se reintenta en el siguiente intervalo.
+		snap, done := s.beginSnapshot()
+		go func() { _ = s.finishSnapshot(snap, done) }()
+	}
+	return nil
+}
+
+// Snapshot guarda el estado actual y compacta el log que ya no hace falta.
+func (s *FileStore) Snapshot() error {
+	s.mu.Lock()
+	snap, done := s.beginSnapshot()
+	s.mu.Unlock()
+	return s.finishSnapshot(snap, done)
+}
+
+// beginSnapshot toma la foto del estado. Debe llamarse con mu tomado, así
+// corresponde exactamente a los registros por debajo de log.next. Si aún se
+// está escribiendo el anterior lo espera: nunca hay más de uno en curso.
+// done se cierra al terminar finishSnapshot.
+func (s *FileStore) beginSnapshot() (snapshot, chan struct{}) {
+	s.waitSnapshot()
+	s.saving = make(chan struct{})
+	s.sinceSnapshot = 0
+	return snapshot{Offset: s.log.next, State: s.mem.exportState()}, s.saving
+}
+
+// finishSnapshot escribe la foto y compacta. No toma mu.
+func (s *FileStore) finishSnapshot(snap snapshot, done chan struct{}) error {
+	defer close(done)
+	if err := writeSnapshot(s.dir, snap); err != nil {
+		return err
+	}
+	// Se conservan varios snapshots por si el último resulta ilegible, así
+	// que sólo se compacta lo que cubre el más antiguo.
+	upTo, err := pruneSnapshots(s.dir, snapshotsToKeep)
+	if err != nil {
+		return err
+	}
+	return s.log.compact(upTo)
+}
+
+// waitSnapshot espera al snapshot en curso, si lo hay. Debe llamarse con mu
+// tomado.
+func (s *FileStore) waitSnapshot() {
+	if s.saving != nil {
+		<-s.saving
+		s.saving = nil
+	}
+}
+
+func (s *FileStore) Get(channel string) (domain.Rocket, bool, error) {
+	return s.mem.Get(channel)
+}
+
+func (s *FileStore) List(q domain.RocketQuery) (domain.Page, error) {
+	return s.mem.List(q)
+}
+
+func (s *FileStore) Stats(window time.Duration) (domain.FleetStats, error) {
+	return s.mem.Stats(window)
+}
+
+func (s *FileStore) Events(channel string) ([]domain.RocketEvent, bool, error) {
+	return s.mem.Events(channel)
+}
+
+func (s *FileStore) GetAsOf(channel string, point domain.PointInTime) (domain.Rocket, bool, error) {
+	return s.mem.GetAsOf(channel, point)
+}
+
+func (s *FileStore) Gaps(channel string) (domain.OrderingGaps, bool, error) {
+	return s.mem.Gaps(channel)
+}
+
+func (s *FileStore) ExpireGaps() {
+	s.mem.ExpireGaps()
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitSnapshot()
+	return s.log.close()
+}
@@ -0,0 +1,525 @@
+package persistence_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+	"lunar/src/infrastructure/persistence"
+	"lunar/src/infrastructure/persistence/persistencetest"
+)
+
+func TestFileStore_Conformance(t *testing.T) {
+	persistencetest.Run(t, func(t *testing.T) port.Persistence {
+		store, err := persistence.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("open store failed: %v", err)
+		}
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	})
+}
+
+func TestFileStore_RebuildsProjectionsOnRestart(t *testing.T) {
+	dir := t.TempDir()
+	ch := "193270a9-c9cf-404a-8f83-838e71d9ae67"
+
+	store, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05.000000+01:00",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06.000000+01:00",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06.000000+01:00",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}), // duplicado
+		makeEnv(ch, 3, "2022-02-02T19:39:07.000000+01:00",
+			domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "GEMINI"}),
+	)
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	reopened, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer reopened.Close()
+
+	got, ok, err := reopened.Get(ch)
+	if err != nil || !ok {
+		t.Fatalf("get rocket failed: %v ok=%v", err, ok)
+	}
+	if got.Speed != 800 || got.Mission != "GEMINI" || got.Type != "Falcon-9" || got.LastMsgNum != 3 {
+		t.Errorf("rebuilt rocket mismatch; got=%+v", got)
+	}
+}
+
+func TestFileStore_ReplayKeepsIngestionTime(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+	ingested := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	store, err := persistence.NewFileStore(dir, persistence.WithClock(func() time.Time { return ingested }))
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+	_ = store.Close()
+
+	later := ingested.Add(48 * time.Hour)
+	reopened, err := persistence.NewFileStore(dir, persistence.WithClock(func() time.Time { return later }))
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer reopened.Close()
+
+	got, _, _ := reopened.Get(ch)
+	if !got.IngestedAt.Equal(ingested) || !got.UpdatedAt.Equal(ingested) {
+		t.Errorf("replay restamped the rocket; ingested=%v updated=%v want=%v", got.IngestedAt, got.UpdatedAt, ingested)
+	}
+	events, _, _ := reopened.Events(ch)
+	if len(events) != 1 || !events[0].ReceivedAt.Equal(ingested) {
+		t.Errorf("replay restamped the event history; got=%+v", events)
+	}
+}
+
+func TestFileStore_ReplayDoesNotNotifyChanges(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv("c1", 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}))
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	var got, results []string
+	reopened, err := persistence.NewFileStore(dir,
+		persistence.WithChangeListener(func(r domain.Rocket) {
+			got = append(got, r.Channel)
+		}),
+		persistence.WithResultListener(func(env domain.MessageEnvelope, _ domain.MessageResult) {
+			results = append(results, env.Metadata.Channel)
+		}))
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer reopened.Close()
+	if len(got) != 0 || len(results) != 0 {
+		t.Fatalf("replay notified changes; got=%v results=%v", got, results)
+	}
+
+	applyAll(t, reopened,
+		makeEnv("c2", 1, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}))
+	if len(got) != 1 || got[0] != "c2" || len(results) != 1 || results[0] != "c2" {
+		t.Errorf("live change not notified after reopen; got=%v results=%v", got, results)
+	}
+}
+
+func TestFileStore_RotatesSegments(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+
+	store, err := persistence.NewFileStore(dir, persistence.WithMaxSegmentBytes(256))
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	for i := 1; i <= 20; i++ {
+		applyAll(t, store, makeEnv(ch, i, "2022-02-02T19:39:05Z",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+	_ = store.Close()
+
+	segs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(segs) < 2 {
+		t.Fatalf("expected several segments, got %d", len(segs))
+	}
+
+	reopened, err := persistence.NewFileStore(dir, persistence.WithMaxSegmentBytes(256))
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer reopened.Close()
+	got, _, _ := reopened.Get(ch)
+	if got.Speed != 200 {
+		t.Errorf("speed after replay; got=%d want=200", got.Speed)
+	}
+}
+
+func TestFileStore_TruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+
+	store, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 20}),
+	)
+	_ = store.Close()
+
+	// Simula un crash a mitad de escritura: basura al final del segmento.
+	segs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	f, err := os.OpenFile(segs[len(segs)-1], os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open segment failed: %v", err)
+	}
+	_, _ = f.Write([]byte{0, 0, 0, 40, 1, 2, 3, 4, '{', '"'})
+	_ = f.Close()
+
+	reopened, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopen with torn tail failed: %v", err)
+	}
+	applyAll(t, reopened,
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 30}))
+	_ = reopened.Close()
+
+	again, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopen after truncation failed: %v", err)
+	}
+	defer again.Close()
+	got, _, _ := again.Get(ch)
+	if got.Speed != 60 {
+		t.Errorf("speed after torn tail; got=%d want=60", got.Speed)
+	}
+}
+
+func TestFileStore_FailedWriteDoesNotHideLaterRecords(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+
+	store, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+
+	persistence.TornNextWrite(store, 5, false)
+	if err := store.Apply(makeEnv(ch, 2, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 20})); err == nil {
+		t.Fatalf("expected the torn write to fail")
+	}
+	// Lo que se confirma después no puede quedar detrás del registro roto.
+	applyAll(t, store,
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 30}))
+	_ = store.Close()
+
+	reopened, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+	got, _, _ := reopened.Get(ch)
+	if got.Speed != 40 || got.LastMsgNum != 3 {
+		t.Errorf("acknowledged records lost after failed write; got=%+v", got)
+	}
+}
+
+func TestFileStore_UnrecoverableWriteStopsTheLog(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+
+	store, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+
+	persistence.TornNextWrite(store, 5, true)
+	_ = store.Apply(makeEnv(ch, 2, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 20}))
+	err = store.Apply(makeEnv(ch, 3, "2022-02-02T19:39:07Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 30}))
+	if !errors.Is(err, persistence.ErrLogUnusable) {
+		t.Fatalf("expected ErrLogUnusable after an unrecoverable write; got=%v", err)
+	}
+	_ = store.Close()
+
+	// Al reabrir se trunca el registro a medias y el log vuelve a servir.
+	reopened, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+	applyAll(t, reopened,
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 30}))
+	got, _, _ := reopened.Get(ch)
+	if got.Speed != 40 {
+		t.Errorf("speed after reopen; got=%d want=40", got.Speed)
+	}
+}
+
+func TestFileStore_CorruptSealedSegmentFails(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := persistence.NewFileStore(dir, persistence.WithMaxSegmentBytes(256))
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	for i := 1; i <= 10; i++ {
+		applyAll(t, store, makeEnv("c1", i, "2022-02-02T19:39:05Z",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+	_ = store.Close()
+
+	segs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	raw, _ := os.ReadFile(segs[0])
+	raw[len(raw)-2] ^= 0xff
+	_ = os.WriteFile(segs[0], raw, 0o644)
+
+	if _, err := persistence.NewFileStore(dir, persistence.WithMaxSegmentBytes(256)); err == nil {
+		t.Fatalf("expected error opening log with corrupt sealed segment")
+	}
+}
+
+func TestFileStore_RestartFromSnapshotMatchesFullReplay(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+
+	store, err := persistence.NewFileStore(dir, persistence.WithMaxSegmentBytes(256), persistence.WithSnapshotEvery(4))
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+	for i := 2; i <= 30; i++ {
+		if i == 17 {
+			continue // queda un hueco en Processed
+		}
+		applyAll(t, store, makeEnv(ch, i, "2022-02-02T19:39:06Z",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+	applyAll(t, store, makeEnv(ch, 3, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10})) // duplicado
+	wantRocket, _, _ := store.Get(ch)
+	wantEvents, _, _ := store.Events(ch)
+	_ = store.Close()
+
+	snaps, _ := filepath.Glob(filepath.Join(dir, "*.snap"))
+	if len(snaps) != 2 {
+		t.Fatalf("expected 2 retained snapshots, got %d", len(snaps))
+	}
+	segs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if filepath.Base(segs[0]) == "00000000000000000000.log" {
+		t.Fatalf("expected the head of the log to be compacted")
+	}
+
+	reopened, err := persistence.NewFileStore(dir, persistence.WithMaxSegmentBytes(256))
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer reopened.Close()
+
+	got, _, _ := reopened.Get(ch)
+	if got.Speed != 780 || got.LastMsgNum != 30 || got.Mission != "ARTEMIS" {
+		t.Errorf("rebuilt rocket mismatch; got=%+v", got)
+	}
+	if !got.Processed.Has(3) || got.Processed.Has(17) {
+		t.Errorf("dedup state lost; processed=%+v", got.Processed)
+	}
+	// Los instantes de ingesta vienen del snapshot, no del reinicio.
+	if !reflect.DeepEqual(stripClock(got), stripClock(wantRocket)) {
+		t.Errorf("rocket after snapshot restart;\n got=%+v\nwant=%+v", got, wantRocket)
+	}
+	events, _, _ := reopened.Events(ch)
+	if len(events) != len(wantEvents) || countOutcome(events, domain.OutcomeDuplicate) != 1 {
+		t.Errorf("history after snapshot restart; got=%+v want=%+v", events, wantEvents)
+	}
+
+	// Un envelope repetido tras el reinicio sigue siendo duplicado.
+	applyAll(t, reopened, makeEnv(ch, 5, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	if again, _, _ := reopened.Get(ch); again.Speed != 780 {
+		t.Errorf("duplicate applied after restart; speed=%d", again.Speed)
+	}
+}
+
+func TestFileStore_SnapshotKeepsTrimmedHistory(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+	clock := persistence.WithClock(func() time.Time { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) })
+
+	store, err := persistence.NewFileStore(dir, persistence.WithHistoryRetention(4), clock)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store, makeEnv(ch, 1, "2022-02-02T19:39:00Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+	for n := 2; n <= 20; n++ {
+		applyAll(t, store, makeEnv(ch, n, fmt.Sprintf("2022-02-02T19:39:%02dZ", n),
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	wantEvents, _, _ := store.Events(ch)
+	wantAsOf, _, _ := store.GetAsOf(ch, domain.PointInTime{MessageNumber: 18})
+	_ = store.Close()
+
+	reopened, err := persistence.NewFileStore(dir, persistence.WithHistoryRetention(4), clock)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	events, _, _ := reopened.Events(ch)
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("retained events differ after restart; got=%+v want=%+v", events, wantEvents)
+	}
+	got, _, err := reopened.GetAsOf(ch, domain.PointInTime{MessageNumber: 18})
+	if err != nil || !reflect.DeepEqual(got, wantAsOf) {
+		t.Errorf("as of 18 differs after restart; err=%v got=%+v want=%+v", err, got, wantAsOf)
+	}
+	if _, _, err := reopened.GetAsOf(ch, domain.PointInTime{MessageNumber: 2}); !errors.Is(err, domain.ErrHistoryTrimmed) {
+		t.Errorf("trimmed point should stay unavailable after restart; got=%v", err)
+	}
+}
+
+func TestFileStore_SnapshotSizeDoesNotGrowWithHistory(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+
+	store, err := persistence.NewFileStore(dir, persistence.WithHistoryRetention(10))
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	defer store.Close()
+	latestSize := func() int64 {
+		t.Helper()
+		if err := store.Snapshot(); err != nil {
+			t.Fatalf("snapshot failed: %v", err)
+		}
+		snaps, _ := filepath.Glob(filepath.Join(dir, "*.snap"))
+		info, err := os.Stat(snaps[len(snaps)-1])
+		if err != nil {
+			t.Fatalf("stat snapshot failed: %v", err)
+		}
+		return info.Size()
+	}
+
+	n := 1
+	applyUpTo := func(last int) {
+		for ; n <= last; n++ {
+			applyAll(t, store, makeEnv(ch, n, "2022-02-02T19:39:05Z",
+				domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 1}))
+		}
+	}
+	applyUpTo(100)
+	small := latestSize()
+	applyUpTo(2000)
+	large := latestSize()
+
+	if large > small*3/2 {
+		t.Errorf("snapshot grows with history; after 100=%dB after 2000=%dB", small, large)
+	}
+}
+
+func TestFileStore_FallsBackToOlderSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+
+	store, err := persistence.NewFileStore(dir, persistence.WithMaxSegmentBytes(256), persistence.WithSnapshotEvery(5))
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	for i := 1; i <= 12; i++ {
+		applyAll(t, store, makeEnv(ch, i, "2022-02-02T19:39:05Z",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+	_ = store.Close()
+
+	snaps, _ := filepath.Glob(filepath.Join(dir, "*.snap"))
+	latest := snaps[len(snaps)-1]
+	raw, _ := os.ReadFile(latest)
+	raw[len(raw)-2] ^= 0xff
+	_ = os.WriteFile(latest, raw, 0o644)
+
+	reopened, err := persistence.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopen with corrupt snapshot failed: %v", err)
+	}
+	defer reopened.Close()
+	if got, _, _ := reopened.Get(ch); got.Speed != 120 {
+		t.Errorf("speed after fallback; got=%d want=120", got.Speed)
+	}
+}
+
+func TestFileStore_SnapshotKeepsStrictBuffer(t *testing.T) {
+	dir := t.TempDir()
+	ch := "c1"
+	strict := persistence.WithStrictOrdering(16, time.Hour)
+
+	store, err := persistence.NewFileStore(dir, strict)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}),
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 30}),
+	)
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	_ = store.Close()
+
+	reopened, err := persistence.NewFileStore(dir, strict)
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer reopened.Close()
+	applyAll(t, reopened,
+		makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 20}))
+
+	got, _, _ := reopened.Get(ch)
+	if got.Speed != 60 || got.LastMsgNum != 3 {
+		t.Errorf("buffered envelope lost across snapshot; got=%+v", got)
+	}
+}
+
+// ---------- helpers ----------
+
+func countOutcome(events []domain.RocketEvent, outcome domain.Outcome) int {
+	n := 0
+	for _, ev := range events {
+		if ev.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// stripClock deja fuera los instantes de ingesta, que dependen del reloj.
+func stripClock(r domain.Rocket) domain.Rocket {
+	r.UpdatedAt, r.IngestedAt = time.Time{}, time.Time{}
+	return r
+}
+
+func applyAll(t *testing.T, store *persistence.FileStore, envs ...domain.MessageEnvelope) {
+	t.Helper()
+	for _, env := range envs {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"time"
+
+	"lunar/src/domain"
+)
+
+// channelHistory es el histórico de un canal: los últimos eventos tal cual
+// llegaron y, si se han descartado los más antiguos por retención, el cohete
+// que resulta de plegarlos (base) junto con hasta dónde llegaban.
+type channelHistory struct {
+	events []domain.RocketEvent
+	seq    int
+
+	base        *domain.Rocket
+	droppedNum  int
+	droppedTime time.Time
+	// untimed indica que algún descartado no tenía un messageTime legible, así
+	// que ningún punto por tiempo los incluye a todos.
+	untimed bool
+}
+
+// add guarda el evento y, si el canal pasa de retention eventos (con un
+// margen para no copiar en cada Apply), pliega los sobrantes en base. Ni
+// base ni los eventos ya guardados se modifican nunca, así que un snapshot
+// puede quedarse con ellos sin copiarlos.
+func (h *channelHistory) add(ev domain.RocketEvent, channel string, retention int, eventTime bool) {
+	h.seq++
+	ev.Sequence = h.seq
+	h.events = append(h.events, ev)
+	if retention <= 0 || len(h.events) <= retention+retention/4 {
+		return
+	}
+
+	drop := len(h.events) - retention
+	base := domain.NewRocket(channel)
+	if h.base != nil {
+		c := h.base.Clone()
+		base = &c
+	}
+	for _, old := range h.events[:drop] {
+		h.fold(base, old, channel, eventTime)
+	}
+	h.base = base
+	// Copia a un array nuevo para que el viejo, con los payloads, se libere.
+	h.events = append(make([]domain.RocketEvent, 0, retention+retention/4+1), h.events[drop:]...)
+}
+
+func (h *channelHistory) fold(base *domain.Rocket, ev domain.RocketEvent, channel string, eventTime bool) {
+	// Lo que está en el histórico ya se pudo decodificar una vez.
+	_, _ = applyTo(base, ev.Envelope(channel), ev.ReceivedAt, eventTime)
+
+	if ev.MessageNumber > h.droppedNum {
+		h.droppedNum = ev.MessageNumber
+	}
+	t, err := ev.Envelope(channel).Time()
+	if err != nil {
+		h.untimed = true
+	} else if t.After(h.droppedTime) {
+		h.droppedTime = t
+	}
+}
+
+// covers indica si point incluye todo lo descartado, es decir, si se puede
+// reconstruir partiendo de base.
+func (h *channelHistory) covers(point domain.PointInTime) bool {
+	if h.base == nil {
+		return true
+	}
+	if point.MessageNumber > 0 && point.MessageNumber < h.droppedNum {
+		return false
+	}
+	if !point.Time.IsZero() && (h.untimed || h.droppedTime.After(point.Time)) {
+		return false
+	}
+	return true
+}
@@ -1,140 +1,386 @@
 package persistence
 
 import (
-	"encoding/json"
 	"lunar/src/domain"
 	"sort"
 	"sync"
 	"time"
 )
 
+// shardCount es el número de particiones del store. Cada canal vive siempre
+// en la misma, así que Apply sobre canales distintos casi nunca compite.
+const shardCount = 64
+
+// indexBatch es cuántos canales con cambios sin indexar acumula un shard
+// antes de volcarlos a los índices compartidos.
+const indexBatch = 64
+
 type MemoryStore struct {
-	mu      sync.RWMutex
-	rockets map[string]*domain.Rocket
-	seen    map[string]map[int]struct{}
+	opts    options
+	shards  [shardCount]*shard
+	indexes *sortIndexes
 }
 
-func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{
-		rockets: make(map[string]*domain.Rocket),
-		seen:    make(map[string]map[int]struct{}),
+// shard guarda los canales que le tocan por hash, con su propio lock. Los
+// cohetes publicados no se modifican nunca (fold trabaja sobre una copia), de
+// modo que List y los índices pueden quedarse con el puntero y clonar fuera.
+type shard struct {
+	mu       sync.RWMutex
+	opts     *options
+	rockets  map[string]*domain.Rocket
+	history  map[string]*channelHistory
+	ordering map[string]*channelOrder
+	indexes  *sortIndexes
+	// unindexed son los canales publicados que aún no se han volcado a
+	// indexes, con la versión que los índices tienen de ellos (nil si
+	// ninguna).
+	unindexed map[string]*domain.Rocket
+}
+
+func NewMemoryStore(opts ...Option) *MemoryStore {
+	s := &MemoryStore{opts: buildOptions(opts), indexes: newSortIndexes()}
+	for i := range s.shards {
+		s.shards[i] = newShard(&s.opts, s.indexes)
 	}
+	return s
 }
 
-// Apply implementa idempotencia + last-write-wins como comentamos.
-func (s *MemoryStore) Apply(env domain.MessageEnvelope) error {
-	ch, num, kind, raw := env.Metadata.Channel, env.Metadata.MessageNum, env.Metadata.MessageType, env.Message
+func newShard(opts *options, indexes *sortIndexes) *shard {
+	sh := &shard{opts: opts, indexes: indexes}
+	sh.reset()
+	return sh
+}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// reset deja el shard vacío. Debe llamarse con mu tomado.
+func (sh *shard) reset() {
+	sh.rockets = make(map[string]*domain.Rocket)
+	sh.history = make(map[string]*channelHistory)
+	sh.ordering = make(map[string]*channelOrder)
+	sh.unindexed = make(map[string]*domain.Rocket)
+}
 
-	// idempotencia
-	if s.seen[ch] == nil {
-		s.seen[ch] = make(map[int]struct{})
+// publish guarda r como versión actual de su canal. Los índices son de todo
+// el store y tienen su propio lock, así que no se tocan en cada Apply: el
+// canal queda pendiente y se vuelcan por lotes, juntando las versiones
+// intermedias de un mismo canal. Debe llamarse con mu tomado.
+func (sh *shard) publish(r *domain.Rocket) {
+	old := sh.rockets[r.Channel]
+	if old == r {
+		return
 	}
-	if _, dup := s.seen[ch][num]; dup {
-		return nil
+	sh.rockets[r.Channel] = r
+	if _, ok := sh.unindexed[r.Channel]; !ok {
+		sh.unindexed[r.Channel] = old
 	}
-	s.seen[ch][num] = struct{}{}
-
-	r := s.ensureRocket(ch)
+	if len(sh.unindexed) >= indexBatch {
+		sh.flushIndexes()
+	}
+}
 
-	// Para eventos no conmutativos: ignora si es más antiguo que el último aplicado
-	isNonCommutative := kind == domain.TypeLaunched || kind == domain.TypeMissionChanged || kind == domain.TypeExploded
-	if isNonCommutative && num < r.LastMsgNum {
-		return nil // no “deshacemos” estado
+// flushIndexes vuelca a los índices los canales pendientes. Debe llamarse
+// con mu tomado (de escritura).
+func (sh *shard) flushIndexes() {
+	if len(sh.unindexed) == 0 {
+		return
+	}
+	sh.indexes.mu.Lock()
+	for ch, old := range sh.unindexed {
+		sh.indexes.replace(old, sh.rockets[ch])
 	}
+	sh.indexes.mu.Unlock()
+	clear(sh.unindexed)
+}
 
-	switch kind {
-	case domain.TypeLaunched:
-		var p domain.RocketLaunchedPayload
-		if err := json.Unmarshal(raw, &p); err != nil {
-			return err
-		}
-		r.Type = p.Type
-		r.Mission = p.Mission
-		if r.Speed < p.LaunchSpeed {
-			r.Speed = p.LaunchSpeed
-		} // no reducimos velocidad
-
-	case domain.TypeSpeedIncreased:
-		var p domain.RocketSpeedDeltaPayload
-		if err := json.Unmarshal(raw, &p); err != nil {
-			return err
+// syncIndexes vuelca lo pendiente de todos los shards, para que List y Stats
+// vean todo lo aplicado antes de llamarlas.
+func (s *MemoryStore) syncIndexes() {
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		pending := len(sh.unindexed)
+		sh.mu.RUnlock()
+		if pending == 0 {
+			continue
 		}
-		r.Speed += p.By
+		sh.mu.Lock()
+		sh.flushIndexes()
+		sh.mu.Unlock()
+	}
+}
 
-	case domain.TypeSpeedDecreased:
-		var p domain.RocketSpeedDeltaPayload
-		if err := json.Unmarshal(raw, &p); err != nil {
-			return err
-		}
-		r.Speed -= p.By
+// shardFor elige el shard del canal con FNV-1a, sin reservar memoria.
+func (s *MemoryStore) shardFor(channel string) *shard {
+	h := uint32(2166136261)
+	for i := 0; i < len(channel); i++ {
+		h ^= uint32(channel[i])
+		h *= 16777619
+	}
+	return s.shards[h%shardCount]
+}
 
-	case domain.TypeMissionChanged:
-		var p domain.RocketMissionChangedPayload
-		if err := json.Unmarshal(raw, &p); err != nil {
-			return err
-		}
-		r.Mission = p.NewMission
+// Apply implementa idempotencia + last-write-wins como comentamos.
+// En modo estricto los envelopes pasan antes por el buffer de reordenación y
+// se caducan los huecos del mismo shard; los demás los cubre ExpireGaps.
+func (s *MemoryStore) Apply(env domain.MessageEnvelope) error {
+	return s.applyAt(env, s.opts.now())
+}
 
-	case domain.TypeExploded:
-		r.Status = domain.StatusExploded
+// applyAt es Apply con el instante de ingesta dado; FileStore lo usa para
+// reaplicar el log con los tiempos originales.
+func (s *MemoryStore) applyAt(env domain.MessageEnvelope, now time.Time) error {
+	sh := s.shardFor(env.Metadata.Channel)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if s.opts.strict {
+		err := sh.applyInOrder(env, now)
+		sh.expireGaps(now)
+		return err
 	}
+	return sh.applyNow(env, now)
+}
 
-	if num > r.LastMsgNum {
-		r.LastMsgNum = num
+// applyNow pliega el envelope sobre la proyección. Debe llamarse con mu tomado.
+func (sh *shard) applyNow(env domain.MessageEnvelope, now time.Time) error {
+	r, outcome, err := fold(sh.rockets[env.Metadata.Channel], env, now, sh.opts.eventTime)
+	if err != nil {
+		return err
+	}
+	sh.publish(r)
+	var reason string
+	if outcome == domain.OutcomeRejected {
+		reason = r.LastRejection.Reason
+	}
+	sh.record(env, outcome, reason, now)
+	if outcome.ChangesRocket() && sh.opts.onChange != nil {
+		sh.opts.onChange(r.Clone())
 	}
-	r.UpdatedAt = time.Now()
 	return nil
 }
 
+// fold aplica el envelope sobre cur (nil si el canal aún no existe) y
+// devuelve la nueva versión del cohete. Lo comparten Apply y la
+// reconstrucción histórica para que ambas vistas no puedan divergir; las
+// reglas están en domain.Rocket.Apply. Con eventTime, UpdatedAt sigue a
+// EventTime; si no, a IngestedAt. cur no se toca: quien ya lo tenga (List,
+// los índices) no ve cambios a medias.
+func fold(cur *domain.Rocket, env domain.MessageEnvelope, now time.Time, eventTime bool) (*domain.Rocket, domain.Outcome, error) {
+	var r *domain.Rocket
+	if cur != nil {
+		// Basta una copia superficial: Processed.Add no escribe en el array
+		// que comparte con cur.
+		c := *cur
+		r = &c
+	} else {
+		r = domain.NewRocket(env.Metadata.Channel)
+	}
+	outcome, err := applyTo(r, env, now, eventTime)
+	if err != nil {
+		return nil, "", err
+	}
+	if outcome == domain.OutcomeDuplicate && cur != nil {
+		// Nada ha cambiado: se mantiene la versión publicada.
+		return cur, outcome, nil
+	}
+	return r, outcome, nil
+}
+
+// applyTo es el paso de fold sobre un cohete que nadie más ve.
+func applyTo(r *domain.Rocket, env domain.MessageEnvelope, now time.Time, eventTime bool) (domain.Outcome, error) {
+	outcome, err := r.Apply(env)
+	if err != nil {
+		return "", err
+	}
+	if outcome == domain.OutcomeApplied {
+		r.IngestedAt = now
+		r.UpdatedAt = now
+		if eventTime {
+			r.UpdatedAt = r.EventTime
+		}
+	}
+	return outcome, nil
+}
+
 func (s *MemoryStore) Get(channel string) (domain.Rocket, bool, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	r, ok := s.rockets[channel]
+	sh := s.shardFor(channel)
+	sh.mu.RLock()
+	r, ok := sh.rockets[channel]
+	sh.mu.RUnlock()
 	if !ok {
 		return domain.Rocket{}, false, nil
 	}
-	return *r, true, nil
+	return r.Clone(), true, nil
 }
 
-func (s *MemoryStore) List(sortBy, order string) ([]domain.Rocket, error) {
-	s.mu.RLock()
-	items := make([]domain.Rocket, 0, len(s.rockets))
-	for _, r := range s.rockets {
-		items = append(items, *r)
+// List recorre el índice de la clave si lo hay: se salta hasta el cursor y
+// lee sólo la página, sin ordenar nada, descartando lo que no pase el
+// filtro. El resto de claves se ordena al vuelo.
+func (s *MemoryStore) List(q domain.RocketQuery) (domain.Page, error) {
+	req := q.PageRequest
+	less := rocketLess(req.SortBy)
+	desc := req.Descending()
+	var from func(*domain.Rocket) bool
+	if req.Cursor != nil {
+		pivot, err := req.Cursor.Pivot()
+		if err != nil {
+			return domain.Page{}, err
+		}
+		// Estrictamente después del cursor en el sentido del recorrido.
+		from = func(r *domain.Rocket) bool { return less(&pivot, r) }
+		if desc {
+			from = func(r *domain.Rocket) bool { return less(r, &pivot) }
+		}
+	}
+	want := req.Limit + 1
+	if req.Limit <= 0 {
+		want = -1
 	}
-	s.mu.RUnlock()
 
-	less := func(i, j int) bool {
-		switch sortBy {
-		case "speed":
-			if order == "desc" {
-				return items[i].Speed > items[j].Speed
+	var published []*domain.Rocket
+	if _, ok := s.indexes.byKey[req.SortBy]; ok {
+		s.syncIndexes()
+	}
+	s.indexes.mu.RLock()
+	if ix, ok := s.indexes.byKey[req.SortBy]; ok {
+		// El índice sólo guarda versiones publicadas, que no cambian: basta
+		// con su lock para tener una foto coherente.
+		if want < 0 {
+			published = make([]*domain.Rocket, 0, ix.len())
+		}
+		for it := ix.seek(desc, from); len(published) != want; {
+			r, ok := it.next()
+			if !ok {
+				break
+			}
+			if q.Filter.Matches(*r) {
+				published = append(published, r)
+			}
+		}
+		s.indexes.mu.RUnlock()
+	} else {
+		s.indexes.mu.RUnlock()
+		all := s.snapshot()
+		sort.Slice(all, func(i, j int) bool {
+			if desc {
+				return less(all[j], all[i])
 			}
-			return items[i].Speed < items[j].Speed
-		case "updated_at":
-			if order == "desc" {
-				return items[i].UpdatedAt.After(items[j].UpdatedAt)
+			return less(all[i], all[j])
+		})
+		for _, r := range all {
+			if len(published) == want {
+				break
 			}
-			return items[i].UpdatedAt.Before(items[j].UpdatedAt)
-		default: // channel
-			if order == "desc" {
-				return items[i].Channel > items[j].Channel
+			if (from == nil || from(r)) && q.Filter.Matches(*r) {
+				published = append(published, r)
 			}
-			return items[i].Channel < items[j].Channel
 		}
 	}
-	sort.Slice(items, less)
-	return items, nil
+
+	items := make([]domain.Rocket, 0, len(published))
+	for _, r := range published {
+		items = append(items, r.Clone())
+	}
+	return domain.NewPage(req, items), nil
 }
 
-func (s *MemoryStore) ensureRocket(ch string) *domain.Rocket {
-	r, ok := s.rockets[ch]
+// snapshot copia los punteros de todos los cohetes con todos los RLock
+// tomados, para una foto coherente que no bloquea mientras se clona.
+func (s *MemoryStore) snapshot() []*domain.Rocket {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		n += len(sh.rockets)
+	}
+	published := make([]*domain.Rocket, 0, n)
+	for _, sh := range s.shards {
+		for _, r := range sh.rockets {
+			published = append(published, r)
+		}
+		sh.mu.RUnlock()
+	}
+	return published
+}
+
+func (s *MemoryStore) Events(channel string) ([]domain.RocketEvent, bool, error) {
+	sh := s.shardFor(channel)
+	sh.mu.RLock()
+	h, ok := sh.history[channel]
+	var items []domain.RocketEvent
+	if ok {
+		items = append(items, h.events...)
+	}
+	sh.mu.RUnlock()
 	if !ok {
-		r = &domain.Rocket{Channel: ch, Status: domain.StatusActive}
-		s.rockets[ch] = r
+		return nil, false, nil
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].MessageNumber < items[j].MessageNumber
+	})
+	return items, true, nil
+}
+
+// GetAsOf reconstruye el cohete reaplicando, en orden de llegada, los
+// envelopes del histórico que caen dentro de point. Si la retención ya ha
+// descartado alguno que point excluye, no se puede y devuelve
+// domain.ErrHistoryTrimmed.
+func (s *MemoryStore) GetAsOf(channel string, point domain.PointInTime) (domain.Rocket, bool, error) {
+	sh := s.shardFor(channel)
+	sh.mu.RLock()
+	var (
+		r        *domain.Rocket
+		recorded []domain.RocketEvent
+	)
+	if h, ok := sh.history[channel]; ok {
+		if !h.covers(point) {
+			sh.mu.RUnlock()
+			return domain.Rocket{}, false, domain.ErrHistoryTrimmed
+		}
+		if h.base != nil {
+			c := h.base.Clone()
+			r = &c
+		}
+		recorded = append(recorded, h.events...)
+	}
+	sh.mu.RUnlock()
+
+	for _, ev := range recorded {
+		if !point.Includes(ev) {
+			continue
+		}
+		// Lo que está en el histórico ya se pudo decodificar una vez.
+		if next, _, err := fold(r, ev.Envelope(channel), ev.ReceivedAt, s.opts.eventTime); err == nil {
+			r = next
+		}
+	}
+	if r == nil {
+		return domain.Rocket{}, false, nil
+	}
+	return r.Clone(), true, nil
+}
+
+// record guarda el envelope en el histórico del canal y avisa a onResult.
+// Debe llamarse con mu tomado y con el cohete ya publicado.
+func (sh *shard) record(env domain.MessageEnvelope, outcome domain.Outcome, reason string, at time.Time) {
+	ch := env.Metadata.Channel
+	h, ok := sh.history[ch]
+	if !ok {
+		h = &channelHistory{}
+		sh.history[ch] = h
+	}
+	h.add(domain.RocketEvent{
+		MessageNumber: env.Metadata.MessageNum,
+		MessageType:   env.Metadata.MessageType,
+		MessageTime:   env.Metadata.MessageTime,
+		Payload:       env.Message,
+		Outcome:       outcome,
+		Reason:        reason,
+		ReceivedAt:    at,
+	}, ch, sh.opts.historyRetention, sh.opts.eventTime)
+	if sh.opts.onResult == nil {
+		return
+	}
+	res := domain.MessageResult{Outcome: outcome, Reason: reason}
+	if r, ok := sh.rockets[ch]; ok {
+		res.Rocket = r.Clone()
 	}
-	return r
+	sh.opts.onResult(env, res)
 }
@@ -2,108 +2,683 @@ package persistence_test
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"lunar/src/domain"
+	"lunar/src/domain/port"
 	"lunar/src/infrastructure/persistence"
+	"lunar/src/infrastructure/persistence/persistencetest"
 )
 
-func TestMissionChanged_OlderMessageDoesNotOverrideNewer(t *testing.T) {
+func TestMemoryStore_Conformance(t *testing.T) {
+	persistencetest.Run(t, func(t *testing.T) port.Persistence {
+		return persistence.NewMemoryStore()
+	})
+}
+
+func TestEvents_RecordsOutcomesInMessageOrder(t *testing.T) {
 	store := persistence.NewMemoryStore()
 	ch := "193270a9-c9cf-404a-8f83-838e71d9ae67"
 
-	// 1) Primero llega una MissionChanged más NUEVA (num=5)
-	envNew := makeEnv(ch, 5, "2022-02-02T19:39:10.000000+01:00",
-		domain.TypeMissionChanged, domain.RocketMissionChangedPayload{
-			NewMission: "MISSION_NEW",
-		})
-	if err := store.Apply(envNew); err != nil {
-		t.Fatalf("apply newer mission failed: %v", err)
+	envs := []domain.MessageEnvelope{
+		makeEnv(ch, 3, "2022-02-02T19:39:08.000000+01:00",
+			domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "MISSION_NEW"}),
+		makeEnv(ch, 1, "2022-02-02T19:39:05.000000+01:00",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06.000000+01:00",
+			domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "MISSION_OLD"}),
+		makeEnv(ch, 1, "2022-02-02T19:39:05.000000+01:00",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}),
 	}
-
-	// 2) Luego llega una MissionChanged ANTIGUA (num=3) → NO debe pisar
-	envOld := makeEnv(ch, 3, "2022-02-02T19:39:08.000000+01:00",
-		domain.TypeMissionChanged, domain.RocketMissionChangedPayload{
-			NewMission: "MISSION_OLD",
-		})
-	if err := store.Apply(envOld); err != nil {
-		t.Fatalf("apply older mission failed: %v", err)
+	for _, env := range envs {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
 	}
 
-	// 3) Verificamos que mantiene la misión nueva y el last msg num
-	got, ok, err := store.Get(ch)
-	if err != nil {
-		t.Fatalf("get rocket failed: %v", err)
+	got, ok, err := store.Events(ch)
+	if err != nil || !ok {
+		t.Fatalf("events failed: %v ok=%v", err, ok)
 	}
-	if !ok {
-		t.Fatalf("rocket not found")
+	want := []struct {
+		seq, num int
+		outcome  domain.Outcome
+	}{
+		{2, 1, domain.OutcomeApplied},
+		{4, 1, domain.OutcomeDuplicate},
+		{3, 2, domain.OutcomeStale},
+		{1, 3, domain.OutcomeApplied},
 	}
-	if got.Mission != "MISSION_NEW" {
-		t.Errorf("mission was overwritten by older message; got=%s want=MISSION_NEW", got.Mission)
+	if len(got) != len(want) {
+		t.Fatalf("events len; got=%d want=%d", len(got), len(want))
 	}
-	if got.LastMsgNum != 5 {
-		t.Errorf("LastMsgNum incorrect; got=%d want=5", got.LastMsgNum)
+	for i, w := range want {
+		if got[i].Sequence != w.seq || got[i].MessageNumber != w.num || got[i].Outcome != w.outcome {
+			t.Errorf("event %d; got=%+v want=%+v", i, got[i], w)
+		}
+	}
+
+	if _, ok, _ := store.Events("unknown"); ok {
+		t.Errorf("expected no events for unknown channel")
 	}
 }
 
-func TestIdempotency_DuplicateMessageIgnored(t *testing.T) {
+func TestGetAsOf_ReplaysHistoryUpToPoint(t *testing.T) {
 	store := persistence.NewMemoryStore()
 	ch := "193270a9-c9cf-404a-8f83-838e71d9ae67"
 
-	// Mismo mensaje (num=2) dos veces
-	env := makeEnv(ch, 2, "2022-02-02T19:39:06.000000+01:00",
-		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300})
-	if err := store.Apply(env); err != nil {
-		t.Fatalf("apply first failed: %v", err)
+	envs := []domain.MessageEnvelope{
+		makeEnv(ch, 1, "2022-02-02T19:39:05.000000+01:00",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+		makeEnv(ch, 3, "2022-02-02T19:39:07.000000+01:00",
+			domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "GEMINI"}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06.000000+01:00",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}),
+		makeEnv(ch, 4, "2022-02-02T19:39:08.000000+01:00",
+			domain.TypeExploded, struct{}{}),
 	}
-	if err := store.Apply(env); err != nil { // duplicado
-		t.Fatalf("apply duplicate failed: %v", err)
+	for _, env := range envs {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
 	}
 
-	got, ok, err := store.Get(ch)
+	// Por número de mensaje
+	got, ok, err := store.GetAsOf(ch, domain.PointInTime{MessageNumber: 2})
 	if err != nil || !ok {
-		t.Fatalf("get rocket failed: %v ok=%v", err, ok)
+		t.Fatalf("get as of failed: %v ok=%v", err, ok)
+	}
+	if got.Speed != 800 || got.Mission != "ARTEMIS" || got.LastMsgNum != 2 {
+		t.Errorf("as of msg 2 mismatch; got=%+v", got)
+	}
+
+	// Por instante: justo antes de la explosión
+	at, _ := time.Parse(time.RFC3339, "2022-02-02T19:39:07+01:00")
+	got, ok, _ = store.GetAsOf(ch, domain.PointInTime{Time: at})
+	if !ok || got.Mission != "GEMINI" || got.Status == domain.StatusExploded {
+		t.Errorf("as of time mismatch; got=%+v", got)
+	}
+
+	// Sin límite coincide con la vista en vivo
+	live, _, _ := store.Get(ch)
+	full, _, _ := store.GetAsOf(ch, domain.PointInTime{MessageNumber: 100})
+	if !reflect.DeepEqual(full, live) {
+		t.Errorf("history and live views disagree; history=%+v live=%+v", full, live)
+	}
+
+	// Antes del primer mensaje el cohete no existía
+	before, _ := time.Parse(time.RFC3339, "2022-02-02T19:00:00+01:00")
+	if _, ok, _ := store.GetAsOf(ch, domain.PointInTime{Time: before}); ok {
+		t.Errorf("expected no rocket before first message")
+	}
+}
+
+func TestList_SeesEveryApplyWithDeferredIndexes(t *testing.T) {
+	store := persistence.NewMemoryStore()
+	// Suficientes canales para que algún shard vuelque por lotes y otros no.
+	const n = 5000
+	for i := 0; i < n; i++ {
+		mustApply(t, store, makeEnv(fmt.Sprintf("ch-%05d", i), 1, "2022-02-02T19:39:05Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: int64(i), Mission: "ARTEMIS"}))
+	}
+	mustApply(t, store, makeEnv("ch-00000", 2, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10 * n}))
+	mustApply(t, store, makeEnv("ch-00000", 2, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10 * n})) // duplicado
+
+	page, err := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: "speed", Order: "desc"}})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(page.Items) != n || page.Items[0].Channel != "ch-00000" || page.Items[0].Speed != 10*n {
+		t.Fatalf("list misses applied changes; len=%d first=%+v", len(page.Items), page.Items[0])
+	}
+	for i := 1; i < len(page.Items); i++ {
+		if page.Items[i].Speed > page.Items[i-1].Speed {
+			t.Fatalf("list out of order at %d", i)
+		}
+	}
+	stats, _ := store.Stats(time.Hour)
+	if stats.Total != n || stats.Speed.Max != 10*n {
+		t.Errorf("stats miss applied changes; got=%+v", stats)
+	}
+}
+
+func TestHistoryRetention_TrimsOldestAndKeepsAsOf(t *testing.T) {
+	store := persistence.NewMemoryStore(persistence.WithHistoryRetention(4))
+	ch := "c1"
+
+	mustApply(t, store, makeEnv(ch, 1, "2022-02-02T19:39:00Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+	for n := 2; n <= 20; n++ {
+		mustApply(t, store, makeEnv(ch, n, fmt.Sprintf("2022-02-02T19:39:%02dZ", n),
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+
+	events, _, _ := store.Events(ch)
+	if len(events) < 4 || len(events) > 5 || events[len(events)-1].Sequence != 20 {
+		t.Fatalf("history not trimmed to retention; got %d events, last=%+v", len(events), events[len(events)-1])
 	}
-	// Debe sumar una sola vez
-	if got.Speed != 300 {
-		t.Errorf("idempotency fail; speed=%d want=300", got.Speed)
+
+	// Los puntos que incluyen todo lo descartado salen del cohete base.
+	live, _, _ := store.Get(ch)
+	full, ok, err := store.GetAsOf(ch, domain.PointInTime{MessageNumber: 20})
+	if err != nil || !ok || !reflect.DeepEqual(full, live) {
+		t.Errorf("as of latest should match live; err=%v got=%+v live=%+v", err, full, live)
+	}
+	got, _, err := store.GetAsOf(ch, domain.PointInTime{MessageNumber: 18})
+	if err != nil || got.Speed != 670 || got.LastMsgNum != 18 {
+		t.Errorf("as of 18 mismatch; err=%v got=%+v", err, got)
+	}
+
+	for _, point := range []domain.PointInTime{
+		{MessageNumber: 2},
+		{Time: mustTime(t, "2022-02-02T19:39:03Z")},
+	} {
+		if _, _, err := store.GetAsOf(ch, point); !errors.Is(err, domain.ErrHistoryTrimmed) {
+			t.Errorf("as of %+v should report trimmed history; got=%v", point, err)
+		}
 	}
 }
 
-func TestDeltas_CommutativeWithOutOfOrder(t *testing.T) {
+func TestEvents_RecordsRejectionsAfterExplosion(t *testing.T) {
 	store := persistence.NewMemoryStore()
 	ch := "193270a9-c9cf-404a-8f83-838e71d9ae67"
 
-	// Llegan desordenados: +500 (num=4) y -200 (num=1)
-	envPlus := makeEnv(ch, 4, time.Now().Format(time.RFC3339Nano),
-		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 500})
-	envMinus := makeEnv(ch, 1, time.Now().Add(-time.Second).Format(time.RFC3339Nano),
-		domain.TypeSpeedDecreased, domain.RocketSpeedDeltaPayload{By: 200})
+	envs := []domain.MessageEnvelope{
+		makeEnv(ch, 1, "2022-02-02T19:39:05.000000+01:00",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06.000000+01:00", domain.TypeExploded, struct{}{}),
+		makeEnv(ch, 3, "2022-02-02T19:39:07.000000+01:00",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}),
+	}
+	for _, env := range envs {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
+	}
 
-	if err := store.Apply(envPlus); err != nil {
-		t.Fatalf("apply plus failed: %v", err)
+	got, _, _ := store.Get(ch)
+	if got.Status != domain.StatusExploded || got.Speed != 500 {
+		t.Errorf("speed increase applied after explosion; got=%+v", got)
 	}
-	if err := store.Apply(envMinus); err != nil {
-		t.Fatalf("apply minus failed: %v", err)
+	if got.LastRejection == nil || got.LastRejection.MessageNumber != 3 {
+		t.Errorf("last rejection not exposed; got=%+v", got.LastRejection)
 	}
 
-	got, ok, err := store.Get(ch)
-	if err != nil || !ok {
-		t.Fatalf("get rocket failed: %v ok=%v", err, ok)
+	events, _, _ := store.Events(ch)
+	last := events[len(events)-1]
+	if last.Outcome != domain.OutcomeRejected || last.Reason == "" {
+		t.Errorf("rejection not recorded in history; got=%+v", last)
+	}
+}
+
+func TestChangeListener_NotifiesVisibleChangesOnly(t *testing.T) {
+	var got []domain.Rocket
+	store := persistence.NewMemoryStore(persistence.WithChangeListener(func(r domain.Rocket) {
+		got = append(got, r)
+	}))
+	ch := "c1"
+
+	launch := makeEnv(ch, 2, "2022-02-02T19:39:06Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"})
+	for _, env := range []domain.MessageEnvelope{
+		launch,
+		launch, // duplicado
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "OLD"}), // atrasado
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeExploded, struct{}{}),
+		makeEnv(ch, 4, "2022-02-02T19:39:08Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}), // rechazado
+	} {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected launch, explosion and rejection; got %d changes", len(got))
 	}
-	// Conmutativo: 0 + 500 - 200 = 300, sin importar el orden
-	if got.Speed != 300 {
-		t.Errorf("commutative deltas fail; speed=%d want=300", got.Speed)
+	if got[0].Status != domain.StatusLaunched || got[1].Status != domain.StatusExploded {
+		t.Errorf("changes out of order; got=%+v", got)
 	}
-	// LastMsgNum debe ser el mayor (4)
-	if got.LastMsgNum != 4 {
-		t.Errorf("LastMsgNum incorrect; got=%d want=4", got.LastMsgNum)
+	if got[2].LastRejection == nil || got[2].LastRejection.MessageNumber != 4 {
+		t.Errorf("rejection not notified; got=%+v", got[2])
+	}
+	if cur, _, _ := store.Get(ch); cur.LastMsgNum != got[2].LastMsgNum || cur.LastRejection == nil {
+		t.Errorf("last change differs from the stored rocket; got=%+v want=%+v", got[2], cur)
+	}
+}
+
+func TestResultListener_ReportsEveryEnvelope(t *testing.T) {
+	var got []string
+	store := persistence.NewMemoryStore(persistence.WithResultListener(func(env domain.MessageEnvelope, res domain.MessageResult) {
+		got = append(got, fmt.Sprintf("%s:%s:%d", env.ID, res.Outcome, res.Rocket.LastMsgNum))
+	}))
+	ch := "c1"
+
+	launch := makeEnv(ch, 2, "2022-02-02T19:39:06Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"})
+	for i, env := range []domain.MessageEnvelope{
+		launch,
+		launch,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "OLD"}),
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeExploded, struct{}{}),
+		makeEnv(ch, 4, "2022-02-02T19:39:08Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}),
+	} {
+		env.ID = fmt.Sprint("r", i)
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
+	}
+
+	want := "r0:applied:2 r1:duplicate:2 r2:stale:2 r3:applied:3 r4:rejected:3"
+	if strings.Join(got, " ") != want {
+		t.Errorf("results mismatch; got=%q want=%q", strings.Join(got, " "), want)
+	}
+}
+
+func TestResultListener_StrictOrderingReportsBufferedOnApply(t *testing.T) {
+	var got []string
+	store := persistence.NewMemoryStore(
+		persistence.WithStrictOrdering(8, time.Hour),
+		persistence.WithResultListener(func(env domain.MessageEnvelope, res domain.MessageResult) {
+			got = append(got, fmt.Sprintf("%s:%s", env.ID, res.Outcome))
+		}))
+
+	second := makeEnv("c1", 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10})
+	second.ID = "second"
+	if err := store.Apply(second); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("buffered envelope reported before being applied; got=%v", got)
+	}
+
+	first := makeEnv("c1", 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10})
+	first.ID = "first"
+	if err := store.Apply(first); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if strings.Join(got, " ") != "first:applied second:applied" {
+		t.Errorf("results mismatch; got=%v", got)
+	}
+}
+
+func TestUpdatedAt_IngestionTimeByDefault(t *testing.T) {
+	clock := newFakeClock("2030-01-01T00:00:00Z")
+	store := persistence.NewMemoryStore(persistence.WithClock(clock.Now))
+	ch := "c1"
+
+	mustApply(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+
+	got, _, _ := store.Get(ch)
+	want := clock.Now()
+	if !got.UpdatedAt.Equal(want) || !got.IngestedAt.Equal(want) {
+		t.Errorf("ingestion time; got updatedAt=%s ingestedAt=%s want=%s", got.UpdatedAt, got.IngestedAt, want)
+	}
+	if !got.EventTime.Equal(mustTime(t, "2022-02-02T19:39:05Z")) {
+		t.Errorf("event time; got=%s", got.EventTime)
+	}
+}
+
+func TestUpdatedAt_EventTimeDoesNotGoBackwards(t *testing.T) {
+	clock := newFakeClock("2030-01-01T00:00:00Z")
+	store := persistence.NewMemoryStore(persistence.WithEventTime(), persistence.WithClock(clock.Now))
+	ch := "c1"
+
+	mustApply(t, store,
+		makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 20}))
+	clock.Advance(time.Minute)
+	// Delta atrasado: se aplica, pero ocurrió antes.
+	mustApply(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+
+	got, _, _ := store.Get(ch)
+	if got.Speed != 30 {
+		t.Fatalf("speed; got=%d want=30", got.Speed)
+	}
+	if want := mustTime(t, "2022-02-02T19:39:06Z"); !got.UpdatedAt.Equal(want) || !got.EventTime.Equal(want) {
+		t.Errorf("event time; got updatedAt=%s eventTime=%s want=%s", got.UpdatedAt, got.EventTime, want)
+	}
+	if !got.IngestedAt.Equal(clock.Now()) {
+		t.Errorf("ingested at; got=%s want=%s", got.IngestedAt, clock.Now())
+	}
+}
+
+func TestList_SortsByEventOrIngestionTime(t *testing.T) {
+	clock := newFakeClock("2030-01-01T00:00:00Z")
+	store := persistence.NewMemoryStore(persistence.WithClock(clock.Now))
+
+	// "a" ocurrió el último pero se ingirió el primero.
+	for _, m := range []struct{ ch, when string }{
+		{"a", "2022-02-02T19:39:09Z"},
+		{"b", "2022-02-02T19:39:05Z"},
+		{"c", "2022-02-02T19:39:07Z"},
+	} {
+		clock.Advance(time.Second)
+		mustApply(t, store,
+			makeEnv(m.ch, 1, m.when, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+
+	page, _ := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: "event_time", Order: "asc"}})
+	if got := channels(page.Items); got != "bca" {
+		t.Errorf("event_time asc; got=%s want=bca", got)
+	}
+	page, _ = store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: "ingested_at", Order: "asc"}})
+	if got := channels(page.Items); got != "abc" {
+		t.Errorf("ingested_at asc; got=%s want=abc", got)
+	}
+}
+
+// Los índices de List tienen que dar el mismo orden que ordenar a mano lo
+// que devuelve Get, también después de muchas actualizaciones por canal.
+func TestList_IndexesMatchFullSort(t *testing.T) {
+	clock := newFakeClock("2030-01-01T00:00:00Z")
+	store := persistence.NewMemoryStore(persistence.WithClock(clock.Now))
+	rnd := rand.New(rand.NewPCG(1, 2))
+
+	const rockets = 300
+	next := make([]int, rockets)
+	for i := 0; i < 5000; i++ {
+		c := rnd.IntN(rockets)
+		next[c]++
+		kind := domain.TypeSpeedIncreased
+		if rnd.IntN(3) == 0 {
+			kind = domain.TypeSpeedDecreased
+		}
+		// Pocos valores distintos para que haya empates que desempatar por canal.
+		env := makeEnv(fmt.Sprintf("ch-%03d", c), next[c], "2022-02-02T19:39:05Z",
+			kind, domain.RocketSpeedDeltaPayload{By: int64(rnd.IntN(4)+1) * 100})
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+		if rnd.IntN(2) == 0 {
+			clock.Advance(time.Second)
+		}
+	}
+
+	for _, sortBy := range []string{"channel", "speed", "updated_at", "fuel_level"} {
+		for _, order := range []string{"asc", "desc"} {
+			page, err := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: sortBy, Order: order}})
+			got := page.Items
+			if err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			if len(got) != rockets {
+				t.Fatalf("%s %s: got %d rockets want %d", sortBy, order, len(got), rockets)
+			}
+			want := append([]domain.Rocket(nil), got...)
+			sort.Slice(want, func(i, j int) bool {
+				a, b := want[i], want[j]
+				if order == "desc" {
+					a, b = b, a
+				}
+				switch sortBy {
+				case "speed":
+					if a.Speed != b.Speed {
+						return a.Speed < b.Speed
+					}
+				case "updated_at":
+					if !a.UpdatedAt.Equal(b.UpdatedAt) {
+						return a.UpdatedAt.Before(b.UpdatedAt)
+					}
+				}
+				return a.Channel < b.Channel
+			})
+			if channels(got) != channels(want) {
+				t.Errorf("%s %s: order differs from a full sort", sortBy, order)
+			}
+			for _, r := range got {
+				cur, _, _ := store.Get(r.Channel)
+				if cur.Speed != r.Speed || cur.LastMsgNum != r.LastMsgNum {
+					t.Fatalf("%s %s: stale rocket in index; got=%+v want=%+v", sortBy, order, r, cur)
+				}
+			}
+		}
+	}
+}
+
+func TestStats_MatchFullScan(t *testing.T) {
+	clock := newFakeClock("2030-01-01T00:00:00Z")
+	store := persistence.NewMemoryStore(persistence.WithClock(clock.Now))
+	rnd := rand.New(rand.NewPCG(3, 4))
+
+	const rockets = 200
+	next := make([]int, rockets)
+	for i := 0; i < 3000; i++ {
+		c := rnd.IntN(rockets)
+		next[c]++
+		var env domain.MessageEnvelope
+		switch ch := fmt.Sprintf("ch-%03d", c); rnd.IntN(4) {
+		case 0:
+			env = makeEnv(ch, next[c], "2022-02-02T19:39:05Z", domain.TypeMissionChanged,
+				domain.RocketMissionChangedPayload{NewMission: fmt.Sprintf("M-%d", rnd.IntN(5))})
+		case 1:
+			env = makeEnv(ch, next[c], "2022-02-02T19:39:05Z", domain.TypeSpeedDecreased,
+				domain.RocketSpeedDeltaPayload{By: int64(rnd.IntN(4)+1) * 100})
+		default:
+			env = makeEnv(ch, next[c], "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased,
+				domain.RocketSpeedDeltaPayload{By: int64(rnd.IntN(4)+1) * 100})
+		}
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+		clock.Advance(time.Second)
+	}
+
+	const window = 10 * time.Minute
+	got, err := store.Stats(window)
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+
+	page, _ := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: "speed", Order: "asc"}})
+	want := domain.NewFleetStats(window)
+	want.Total = len(page.Items)
+	var sum int64
+	for _, r := range page.Items {
+		want.ByStatus[r.Status]++
+		if r.Mission != "" {
+			want.ByMission[r.Mission]++
+		}
+		sum += r.Speed
+		if !r.UpdatedAt.Before(clock.Now().Add(-window)) {
+			want.UpdatedRecently.Count++
+		}
+	}
+	want.Speed.Min = page.Items[0].Speed
+	want.Speed.Max = page.Items[len(page.Items)-1].Speed
+	want.Speed.Mean = float64(sum) / float64(want.Total)
+	want.Speed.SetPercentiles(want.Total, func(k int) int64 { return page.Items[k].Speed })
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incremental stats differ from a full scan;\n got=%+v\nwant=%+v", got, want)
+	}
+}
+
+// Benchmarks de contención: envelopes de muchos canales aplicados desde
+// varias goroutines, solos o mezclados con List.
+//
+//	go test ./src/infrastructure/persistence -run '^$' -bench 'Apply' -cpu 1,4,8
+const benchChannels = 1024
+
+func BenchmarkApplyParallel(b *testing.B) {
+	store := persistence.NewMemoryStore()
+	next := benchEnvelopes()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := store.Apply(next()); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkApplyWithList hace un List de cada 64 operaciones.
+func BenchmarkApplyWithList(b *testing.B) {
+	store := persistence.NewMemoryStore()
+	next := benchEnvelopes()
+	for i := 0; i < benchChannels; i++ {
+		_ = store.Apply(next())
+	}
+	var ops atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if ops.Add(1)%64 == 0 {
+				if _, err := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: "speed", Order: "desc"}}); err != nil {
+					b.Error(err)
+					return
+				}
+				continue
+			}
+			if err := store.Apply(next()); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkListIndexed lee la flota entera ya ordenada por un índice.
+func BenchmarkListIndexed(b *testing.B) {
+	store := persistence.NewMemoryStore()
+	next := benchEnvelopes()
+	for i := 0; i < 4*benchChannels; i++ {
+		_ = store.Apply(next())
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: "speed", Order: "desc"}}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkListPage lee una página de 50 a mitad de la flota.
+func BenchmarkListPage(b *testing.B) {
+	store := persistence.NewMemoryStore()
+	next := benchEnvelopes()
+	for i := 0; i < 4*benchChannels; i++ {
+		_ = store.Apply(next())
+	}
+	cur := &domain.Cursor{SortBy: "channel", Order: "asc", Channel: fmt.Sprintf("ch-%04d", benchChannels/2)}
+	cur.Value = cur.Channel
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: "channel", Order: "asc", Limit: 50, Cursor: cur}}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkApplyHeap mide la memoria que retiene el store tras n mensajes
+// repartidos entre benchChannels canales. Con retención crece con los
+// canales; sin ella, con los mensajes.
+//
+//	go test ./src/infrastructure/persistence -run '^$' -bench ApplyHeap -benchtime 1x
+func BenchmarkApplyHeap(b *testing.B) {
+	cases := []struct {
+		name string
+		opts []persistence.Option
+		n    int
+	}{
+		{"retain100/1000000", []persistence.Option{persistence.WithHistoryRetention(100)}, 1_000_000},
+		{"retain100/4000000", []persistence.Option{persistence.WithHistoryRetention(100)}, 4_000_000},
+		{"default/4000000", nil, 4_000_000},
+		{"unbounded/1000000", []persistence.Option{persistence.WithHistoryRetention(0)}, 1_000_000},
+		{"unbounded/4000000", []persistence.Option{persistence.WithHistoryRetention(0)}, 4_000_000},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				before := heapInUse()
+				store := persistence.NewMemoryStore(tc.opts...)
+				next := benchEnvelopes()
+				for j := 0; j < tc.n; j++ {
+					if err := store.Apply(next()); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(heapInUse()-before), "retained-B")
+				runtime.KeepAlive(store)
+			}
+		})
+	}
+}
+
+func heapInUse() int64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return int64(ms.HeapInuse)
+}
+
+// benchEnvelopes reparte messageNumbers consecutivos entre benchChannels
+// canales; es seguro llamarla desde varias goroutines.
+func benchEnvelopes() func() domain.MessageEnvelope {
+	names := make([]string, benchChannels)
+	for i := range names {
+		names[i] = fmt.Sprintf("ch-%04d", i)
+	}
+	raw, _ := json.Marshal(domain.RocketSpeedDeltaPayload{By: 1})
+	var seq atomic.Int64
+	return func() domain.MessageEnvelope {
+		n := seq.Add(1) - 1
+		var env domain.MessageEnvelope
+		env.Metadata.Channel = names[n%benchChannels]
+		env.Metadata.MessageNum = int(n/benchChannels) + 1
+		env.Metadata.MessageTime = "2022-02-02T19:39:05Z"
+		env.Metadata.MessageType = domain.TypeSpeedIncreased
+		env.Message = raw
+		return env
 	}
 }
 
 // ---------- helpers ----------
 
+type fakeClock struct{ now time.Time }
+
+func newFakeClock(start string) *fakeClock {
+	t, _ := time.Parse(time.RFC3339, start)
+	return &fakeClock{now: t}
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func mustTime(t *testing.T, raw string) time.Time {
+	t.Helper()
+	v, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+	return v
+}
+
+func channels(items []domain.Rocket) string {
+	var out string
+	for _, r := range items {
+		out += r.Channel
+	}
+	return out
+}
+
 func makeEnv(channel string, num int, when string, kind string, payload any) domain.MessageEnvelope {
 	raw, _ := json.Marshal(payload)
 	env := domain.MessageEnvelope{}
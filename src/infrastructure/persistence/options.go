@@ -0,0 +1,128 @@
+package persistence
+
+import (
+	"time"
+
+	"lunar/src/domain"
+)
+
+const (
+	defaultMaxSegmentBytes   int64 = 64 << 20
+	defaultReorderBufferSize       = 1024
+	defaultGapTimeout              = 30 * time.Second
+	defaultHistoryRetention        = 1000
+)
+
+type options struct {
+	maxSegmentBytes int64
+	snapshotEvery   int
+
+	strict            bool
+	reorderBufferSize int
+	gapTimeout        time.Duration
+
+	historyRetention int
+
+	eventTime bool
+	now       func() time.Time
+	onChange  func(domain.Rocket)
+	onResult  func(domain.MessageEnvelope, domain.MessageResult)
+}
+
+type Option func(*options)
+
+// WithMaxSegmentBytes fija el tamaño a partir del cual el log rota de segmento.
+func WithMaxSegmentBytes(n int64) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxSegmentBytes = n
+		}
+	}
+}
+
+// WithSnapshotEvery hace que el FileStore guarde un snapshot de las
+// proyecciones cada n envelopes y compacte el log que ya cubren.
+func WithSnapshotEvery(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.snapshotEvery = n
+		}
+	}
+}
+
+// WithStrictOrdering aplica los mensajes de cada canal en orden estricto de
+// messageNumber. Los que llegan adelantados esperan en un buffer de hasta
+// bufferSize envelopes; si el hueco no se rellena en gapTimeout (o el buffer
+// se llena) se declara el hueco y se sigue adelante.
+func WithStrictOrdering(bufferSize int, gapTimeout time.Duration) Option {
+	return func(o *options) {
+		o.strict = true
+		if bufferSize > 0 {
+			o.reorderBufferSize = bufferSize
+		}
+		if gapTimeout > 0 {
+			o.gapTimeout = gapTimeout
+		}
+	}
+}
+
+// WithHistoryRetention fija cuántos eventos se guardan por canal para /events
+// y asOf (por defecto 1000). Los más antiguos se pliegan en un cohete base:
+// asOf sigue funcionando para puntos que los incluyen a todos; para puntos
+// anteriores devuelve domain.ErrHistoryTrimmed. Con n <= 0 no se descarta
+// nada.
+func WithHistoryRetention(n int) Option {
+	return func(o *options) {
+		o.historyRetention = n
+	}
+}
+
+// WithEventTime hace que UpdatedAt sea el messageTime del último mensaje
+// aplicado (EventTime) en lugar del momento en que lo procesamos (IngestedAt).
+func WithEventTime() Option {
+	return func(o *options) {
+		o.eventTime = true
+	}
+}
+
+// WithClock sustituye time.Now; útil en tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		if now != nil {
+			o.now = now
+		}
+	}
+}
+
+// WithChangeListener hace que Apply llame a fn con la nueva versión del
+// cohete cada vez que un envelope lo cambia. Se llama con el canal bloqueado,
+// en el orden en que se aplican sus mensajes, así que fn no debe bloquear.
+func WithChangeListener(fn func(domain.Rocket)) Option {
+	return func(o *options) {
+		o.onChange = fn
+	}
+}
+
+// WithResultListener hace que Apply llame a fn con lo que hizo con cada
+// envelope, incluidos duplicados y atrasados. En modo estricto, los que
+// esperan en el buffer se notifican al aplicarse. Igual que con
+// WithChangeListener, fn no debe bloquear.
+func WithResultListener(fn func(domain.MessageEnvelope, domain.MessageResult)) Option {
+	return func(o *options) {
+		o.onResult = fn
+	}
+}
+
+func buildOptions(opts []Option) options {
+	o := options{
+		maxSegmentBytes:   defaultMaxSegmentBytes,
+		reorderBufferSize: defaultReorderBufferSize,
+		gapTimeout:        defaultGapTimeout,
+		historyRetention:  defaultHistoryRetention,
+		now:               time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
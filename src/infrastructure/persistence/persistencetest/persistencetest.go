@@ -0,0 +1,539 @@
+// Package persistencetest contiene la batería de pruebas que cualquier
+// implementación de port.Persistence debe pasar. Cada adaptador la llama
+// desde sus propios tests con una factoría que le da un store vacío:
+//
+//	func TestConformance(t *testing.T) {
+//		persistencetest.Run(t, func(t *testing.T) port.Persistence {
+//			return persistence.NewMemoryStore()
+//		})
+//	}
+package persistencetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+)
+
+// Factory devuelve un store vacío y aislado de los demás. Si hay que
+// liberar algo al acabar, la factoría lo registra con t.Cleanup.
+type Factory func(t *testing.T) port.Persistence
+
+// Run ejecuta toda la batería, cada caso con un store nuevo. Si el store
+// implementa también port.EventReader se comprueba además el histórico, y si
+// implementa port.StatsReader, los agregados.
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, port.Persistence)
+	}{
+		{"DuplicateMessageIgnored", testDuplicates},
+		{"OlderMissionDoesNotOverrideNewer", testOutOfOrderMission},
+		{"DeltasCommutativeOutOfOrder", testOutOfOrderDeltas},
+		{"LaunchAfterIncreaseKeepsSpeed", testLaunchAfterIncrease},
+		{"ExplosionRejectsLaterMessages", testExplosion},
+		{"UnknownChannelNotFound", testUnknownChannel},
+		{"ListSortsByEveryKey", testSortKeys},
+		{"ListPagesWithCursors", testPagination},
+		{"ListFilters", testFilters},
+		{"StatsFollowUpdates", testStats},
+		{"ConcurrentApplyAndList", testConcurrentApplyAndList},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+func testDuplicates(t *testing.T, store port.Persistence) {
+	ch := "c1"
+	env := Envelope(ch, 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300})
+	MustApply(t, store, env, env)
+
+	got := mustGet(t, store, ch)
+	if got.Speed != 300 {
+		t.Errorf("duplicate applied twice; speed=%d want=300", got.Speed)
+	}
+	if events, ok := store.(port.EventReader); ok {
+		evs, _, err := events.Events(ch)
+		if err != nil {
+			t.Fatalf("events failed: %v", err)
+		}
+		if len(evs) != 2 || evs[0].Outcome != domain.OutcomeApplied || evs[1].Outcome != domain.OutcomeDuplicate {
+			t.Errorf("duplicate not recorded as such; events=%+v", evs)
+		}
+	}
+}
+
+func testOutOfOrderMission(t *testing.T, store port.Persistence) {
+	ch := "193270a9-c9cf-404a-8f83-838e71d9ae67"
+	MustApply(t, store,
+		Envelope(ch, 5, "2022-02-02T19:39:10.000000+01:00",
+			domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "MISSION_NEW"}),
+		Envelope(ch, 3, "2022-02-02T19:39:08.000000+01:00",
+			domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "MISSION_OLD"}),
+	)
+
+	got := mustGet(t, store, ch)
+	if got.Mission != "MISSION_NEW" {
+		t.Errorf("mission was overwritten by older message; got=%s want=MISSION_NEW", got.Mission)
+	}
+	if got.LastMsgNum != 5 {
+		t.Errorf("LastMsgNum incorrect; got=%d want=5", got.LastMsgNum)
+	}
+}
+
+func testOutOfOrderDeltas(t *testing.T, store port.Persistence) {
+	ch := "c1"
+	MustApply(t, store,
+		Envelope(ch, 4, "2022-02-02T19:39:08Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 500}),
+		Envelope(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedDecreased, domain.RocketSpeedDeltaPayload{By: 200}),
+		Envelope(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 50}),
+	)
+
+	got := mustGet(t, store, ch)
+	if got.Speed != 350 {
+		t.Errorf("commutative deltas fail; speed=%d want=350", got.Speed)
+	}
+	if got.LastMsgNum != 4 {
+		t.Errorf("LastMsgNum incorrect; got=%d want=4", got.LastMsgNum)
+	}
+}
+
+func testLaunchAfterIncrease(t *testing.T, store port.Persistence) {
+	ch := "c1"
+	MustApply(t, store,
+		Envelope(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 700}),
+		Envelope(ch, 2, "2022-02-02T19:39:06Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+	)
+
+	got := mustGet(t, store, ch)
+	if got.Speed != 700 || got.Type != "Falcon-9" || got.Mission != "ARTEMIS" || got.Status != domain.StatusLaunched {
+		t.Errorf("launch after increase; got=%+v", got)
+	}
+}
+
+func testExplosion(t *testing.T, store port.Persistence) {
+	ch := "c1"
+	MustApply(t, store,
+		Envelope(ch, 1, "2022-02-02T19:39:05Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+		Envelope(ch, 2, "2022-02-02T19:39:06Z", domain.TypeExploded, struct{}{}),
+		Envelope(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}),
+		Envelope(ch, 4, "2022-02-02T19:39:08Z", domain.TypeLanded, struct{}{}),
+	)
+
+	got := mustGet(t, store, ch)
+	if got.Status != domain.StatusExploded || got.Speed != 500 {
+		t.Errorf("messages applied after explosion; got=%+v", got)
+	}
+	if got.LastRejection == nil || got.LastRejection.MessageNumber != 4 {
+		t.Errorf("last rejection not exposed; got=%+v", got.LastRejection)
+	}
+	if events, ok := store.(port.EventReader); ok {
+		evs, _, err := events.Events(ch)
+		if err != nil {
+			t.Fatalf("events failed: %v", err)
+		}
+		if len(evs) != 4 || evs[2].Outcome != domain.OutcomeRejected || evs[2].Reason == "" {
+			t.Errorf("rejection not recorded in history; events=%+v", evs)
+		}
+	}
+}
+
+func testUnknownChannel(t *testing.T, store port.Persistence) {
+	if _, ok, err := store.Get("unknown"); err != nil || ok {
+		t.Errorf("unknown channel; ok=%v err=%v", ok, err)
+	}
+	page, err := store.List(query("channel", "asc", 10))
+	if err != nil || len(page.Items) != 0 || page.Next != nil || page.Prev != nil {
+		t.Errorf("empty store list; page=%+v err=%v", page, err)
+	}
+}
+
+// testSortKeys elige los valores para que ninguna clave ordene igual que el
+// canal, de modo que un store que ignore sortBy no pase por casualidad.
+func testSortKeys(t *testing.T, store port.Persistence) {
+	rockets := []struct {
+		ch                    string
+		when                  string
+		speed, altitude, fuel int64
+		stages                int
+	}{
+		{"b", "2022-02-02T19:39:09Z", 300, 200, 50, 1},
+		{"c", "2022-02-02T19:39:07Z", 100, 100, 70, 3},
+		{"a", "2022-02-02T19:39:05Z", 200, 300, 90, 2},
+	}
+	for _, r := range rockets {
+		MustApply(t, store,
+			Envelope(r.ch, 1, r.when, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: r.speed}),
+			Envelope(r.ch, 2, r.when, domain.TypeAltitudeChanged, domain.RocketAltitudeChangedPayload{By: r.altitude}),
+			Envelope(r.ch, 3, r.when, domain.TypeFuelLevelChanged, domain.RocketFuelLevelChangedPayload{By: r.fuel}),
+			Envelope(r.ch, 4, r.when, domain.TypeStageSeparated, domain.RocketStageSeparatedPayload{Stage: r.stages}),
+		)
+		// Algunos stores guardan los instantes con resolución de
+		// microsegundos; así el orden de ingesta no empata.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	tests := []struct {
+		sortBy, asc string
+	}{
+		{"channel", "abc"},
+		{"speed", "cab"},
+		{"updated_at", "bca"},
+		{"event_time", "acb"},
+		{"ingested_at", "bca"},
+		{"altitude", "cba"},
+		{"fuel_level", "bca"},
+		{"stages_separated", "bac"},
+	}
+	for _, tc := range tests {
+		for _, order := range []string{"asc", "desc"} {
+			want := tc.asc
+			if order == "desc" {
+				want = reverse(want)
+			}
+			if got := channels(listAll(t, store, tc.sortBy, order)); got != want {
+				t.Errorf("list %s %s; got=%s want=%s", tc.sortBy, order, got, want)
+			}
+			if got := walkPages(t, store, tc.sortBy, order, 2); got != want {
+				t.Errorf("pages %s %s; got=%s want=%s", tc.sortBy, order, got, want)
+			}
+		}
+	}
+}
+
+// testPagination recorre la flota página a página en los dos sentidos y
+// comprueba que un cohete nuevo delante del cursor no corre las páginas.
+func testPagination(t *testing.T, store port.Persistence) {
+	for i, ch := range []string{"b", "d", "f", "h", "j"} {
+		MustApply(t, store,
+			Envelope(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: int64(100 * (5 - i))}))
+	}
+
+	for _, limit := range []int{1, 2, 5, 10} {
+		if got := walkPages(t, store, "speed", "asc", limit); got != "jhfdb" {
+			t.Errorf("speed asc limit %d; got=%s want=jhfdb", limit, got)
+		}
+		if got := walkPages(t, store, "channel", "desc", limit); got != "jhfdb" {
+			t.Errorf("channel desc limit %d; got=%s want=jhfdb", limit, got)
+		}
+	}
+
+	req := query("channel", "asc", 2)
+	first := mustList(t, store, req)
+	if channels(first.Items) != "bd" || first.Prev != nil || first.Next == nil {
+		t.Fatalf("first page; got=%s prev=%v next=%v", channels(first.Items), first.Prev, first.Next)
+	}
+	// Entra un cohete que va antes del cursor y otro que va después.
+	MustApply(t, store,
+		Envelope("a", 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 1}),
+		Envelope("e", 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 1}))
+
+	req.Cursor = first.Next
+	second := mustList(t, store, req)
+	if channels(second.Items) != "ef" || second.Prev == nil || second.Next == nil {
+		t.Fatalf("second page; got=%s prev=%v next=%v", channels(second.Items), second.Prev, second.Next)
+	}
+	req.Cursor = second.Prev
+	back := mustList(t, store, req)
+	if channels(back.Items) != "bd" || back.Prev == nil {
+		t.Errorf("back from second page; got=%s prev=%v", channels(back.Items), back.Prev)
+	}
+	req.Cursor = back.Prev
+	if got := mustList(t, store, req); channels(got.Items) != "a" || got.Prev != nil {
+		t.Errorf("page before the first; got=%s prev=%v", channels(got.Items), got.Prev)
+	}
+}
+
+func testFilters(t *testing.T, store port.Persistence) {
+	MustApply(t, store,
+		launch("a", 1, "2022-02-02T19:39:05Z", "Falcon-9", "ARTEMIS-1", 500),
+		launch("b", 1, "2022-02-02T19:39:06Z", "Falcon-9", "ARTEMIS-2", 1000),
+		Envelope("b", 2, "2022-02-02T19:39:07Z", domain.TypeExploded, struct{}{}),
+		launch("c", 1, "2022-02-02T19:39:08Z", "Saturn-V", "APOLLO", 200),
+		Envelope("d", 1, "2022-02-02T19:39:09Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 50}),
+		// Comodines de LIKE en la misión: el prefijo tiene que ser literal.
+		launch("e", 1, "2022-02-02T19:39:10Z", "Electron", "100%_SAFE", 10),
+		launch("f", 1, "2022-02-02T19:39:11Z", "Electron", "100XXSAFE", 10),
+	)
+	at := func(raw string) time.Time {
+		v, _ := time.Parse(time.RFC3339, raw)
+		return v
+	}
+	num := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		name   string
+		filter domain.RocketFilter
+		want   string
+	}{
+		{"none", domain.RocketFilter{}, "abcdef"},
+		{"status", domain.RocketFilter{Status: domain.StatusLaunched}, "acef"},
+		{"status pending", domain.RocketFilter{Status: domain.StatusPending}, "d"},
+		{"type", domain.RocketFilter{Type: "Falcon-9"}, "ab"},
+		{"mission", domain.RocketFilter{Mission: "ARTEMIS-1"}, "a"},
+		{"mission prefix", domain.RocketFilter{MissionPrefix: "ARTEMIS"}, "ab"},
+		{"mission prefix is case sensitive", domain.RocketFilter{MissionPrefix: "artemis"}, ""},
+		{"mission prefix is literal", domain.RocketFilter{MissionPrefix: "100%_"}, "e"},
+		{"speed range", domain.RocketFilter{Speed: domain.IntRange{Min: num(200), Max: num(500)}}, "ac"},
+		{"speed min", domain.RocketFilter{Speed: domain.IntRange{Min: num(501)}}, "b"},
+		{"event time", domain.RocketFilter{EventTime: domain.TimeRange{
+			From: at("2022-02-02T19:39:07Z"), To: at("2022-02-02T19:39:09Z")}}, "bcd"},
+		{"updated since long ago", domain.RocketFilter{UpdatedAt: domain.TimeRange{From: at("2000-01-01T00:00:00Z")}}, "abcdef"},
+		{"ingested in the future", domain.RocketFilter{IngestedAt: domain.TimeRange{From: at("2999-01-01T00:00:00Z")}}, ""},
+		{"combined", domain.RocketFilter{Type: "Falcon-9", Speed: domain.IntRange{Min: num(600)}}, "b"},
+	}
+	for _, tc := range tests {
+		q := query("channel", "asc", 0)
+		q.Filter = tc.filter
+		if got := channels(mustList(t, store, q).Items); got != tc.want {
+			t.Errorf("%s; got=%s want=%s", tc.name, got, tc.want)
+		}
+	}
+
+	// El filtro se aplica antes de cortar la página.
+	q := query("speed", "desc", 1)
+	q.Filter = domain.RocketFilter{Type: "Falcon-9"}
+	first := mustList(t, store, q)
+	q.Cursor = first.Next
+	second := mustList(t, store, q)
+	if channels(first.Items) != "b" || channels(second.Items) != "a" || second.Next != nil {
+		t.Errorf("filtered pages; got=%s,%s next=%v", channels(first.Items), channels(second.Items), second.Next)
+	}
+}
+
+func testStats(t *testing.T, store port.Persistence) {
+	stats, ok := store.(port.StatsReader)
+	if !ok {
+		t.Skip("store does not implement port.StatsReader")
+	}
+	if got := mustStats(t, stats, time.Hour); got.Total != 0 || got.Speed != (domain.SpeedStats{}) {
+		t.Errorf("empty fleet; got=%+v", got)
+	}
+
+	MustApply(t, store,
+		launch("a", 1, "2022-02-02T19:39:05Z", "Falcon-9", "ARTEMIS-1", 500),
+		launch("b", 1, "2022-02-02T19:39:06Z", "Falcon-9", "ARTEMIS-2", 1000),
+		Envelope("b", 2, "2022-02-02T19:39:07Z", domain.TypeExploded, struct{}{}),
+		launch("c", 1, "2022-02-02T19:39:08Z", "Saturn-V", "APOLLO", 200),
+		Envelope("d", 1, "2022-02-02T19:39:09Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 50}),
+		// Cambios sobre un cohete ya contado: se descuenta lo que tenía.
+		Envelope("a", 2, "2022-02-02T19:39:10Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}),
+		Envelope("a", 3, "2022-02-02T19:39:11Z", domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "GATEWAY"}),
+	)
+
+	got := mustStats(t, stats, time.Hour)
+	want := domain.FleetStats{
+		Total: 4,
+		ByStatus: map[domain.RocketStatus]int{
+			domain.StatusLaunched: 1, domain.StatusInFlight: 1, domain.StatusExploded: 1, domain.StatusPending: 1,
+		},
+		ByType:          map[string]int{"Falcon-9": 2, "Saturn-V": 1},
+		ByMission:       map[string]int{"GATEWAY": 1, "ARTEMIS-2": 1, "APOLLO": 1},
+		Speed:           domain.SpeedStats{Min: 50, Max: 1000, Mean: 462.5, P50: 200, P90: 1000, P95: 1000, P99: 1000},
+		UpdatedRecently: domain.RecentUpdates{Minutes: 60, Count: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stats mismatch;\n got=%+v\nwant=%+v", got, want)
+	}
+	// Una ventana que acaba antes de ahora no deja a nadie dentro.
+	if got := mustStats(t, stats, -time.Hour); got.UpdatedRecently.Count != 0 {
+		t.Errorf("rockets updated in the future; got=%d", got.UpdatedRecently.Count)
+	}
+}
+
+func testConcurrentApplyAndList(t *testing.T, store port.Persistence) {
+	const (
+		numChannels = 8
+		messages    = 25
+	)
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+		errs = make(chan error, numChannels+1)
+	)
+	for c := 0; c < numChannels; c++ {
+		wg.Add(1)
+		go func(ch string) {
+			defer wg.Done()
+			for n := 1; n <= messages; n++ {
+				env := Envelope(ch, n, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 1})
+				if err := store.Apply(env); err != nil {
+					errs <- fmt.Errorf("apply %s/%d: %w", ch, n, err)
+					return
+				}
+			}
+		}(fmt.Sprintf("ch-%02d", c))
+	}
+
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			page, err := store.List(query("speed", "desc", 0))
+			if err != nil {
+				errs <- fmt.Errorf("list: %w", err)
+				return
+			}
+			for _, r := range page.Items {
+				if r.Speed < 0 || r.Speed > messages {
+					errs <- fmt.Errorf("list saw impossible speed %d on %s", r.Speed, r.Channel)
+					return
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	items := listAll(t, store, "channel", "asc")
+	if len(items) != numChannels {
+		t.Fatalf("list len; got=%d want=%d", len(items), numChannels)
+	}
+	for _, r := range items {
+		if r.Speed != messages || r.LastMsgNum != messages {
+			t.Errorf("%s after concurrent apply; speed=%d last=%d want=%d", r.Channel, r.Speed, r.LastMsgNum, messages)
+		}
+	}
+}
+
+// ---------- helpers ----------
+
+// Envelope construye un envelope con el payload serializado.
+func Envelope(channel string, num int, when string, kind string, payload any) domain.MessageEnvelope {
+	raw, _ := json.Marshal(payload)
+	env := domain.MessageEnvelope{}
+	env.Metadata.Channel = channel
+	env.Metadata.MessageNum = num
+	env.Metadata.MessageTime = when
+	env.Metadata.MessageType = kind
+	env.Message = raw
+	return env
+}
+
+// MustApply aplica los envelopes en orden y falla el test al primer error.
+func MustApply(t *testing.T, store port.MessageWriter, envs ...domain.MessageEnvelope) {
+	t.Helper()
+	for _, env := range envs {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %s/%d failed: %v", env.Metadata.Channel, env.Metadata.MessageNum, err)
+		}
+	}
+}
+
+func launch(ch string, num int, when, kind, mission string, speed int64) domain.MessageEnvelope {
+	return Envelope(ch, num, when, domain.TypeLaunched,
+		domain.RocketLaunchedPayload{Type: kind, LaunchSpeed: speed, Mission: mission})
+}
+
+func mustStats(t *testing.T, store port.StatsReader, window time.Duration) domain.FleetStats {
+	t.Helper()
+	st, err := store.Stats(window)
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	return st
+}
+
+func mustGet(t *testing.T, store port.RocketReader, channel string) domain.Rocket {
+	t.Helper()
+	got, ok, err := store.Get(channel)
+	if err != nil || !ok {
+		t.Fatalf("get %s failed: %v ok=%v", channel, err, ok)
+	}
+	return got
+}
+
+func query(sortBy, order string, limit int) domain.RocketQuery {
+	return domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: sortBy, Order: order, Limit: limit}}
+}
+
+func mustList(t *testing.T, store port.RocketReader, q domain.RocketQuery) domain.Page {
+	t.Helper()
+	page, err := store.List(q)
+	if err != nil {
+		t.Fatalf("list %+v failed: %v", q, err)
+	}
+	return page
+}
+
+func listAll(t *testing.T, store port.RocketReader, sortBy, order string) []domain.Rocket {
+	t.Helper()
+	return mustList(t, store, query(sortBy, order, 0)).Items
+}
+
+// walkPages recorre el listado hacia delante siguiendo Next y luego hacia
+// atrás siguiendo Prev, y devuelve los canales si ambos recorridos coinciden.
+func walkPages(t *testing.T, store port.RocketReader, sortBy, order string, limit int) string {
+	t.Helper()
+	req := query(sortBy, order, limit)
+	var pages []string
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatalf("%s %s: pagination does not end", sortBy, order)
+		}
+		page := mustList(t, store, req)
+		if len(page.Items) > limit {
+			t.Fatalf("%s %s: page of %d items with limit %d", sortBy, order, len(page.Items), limit)
+		}
+		pages = append(pages, channels(page.Items))
+		if page.Next == nil {
+			req.Cursor = page.Prev
+			break
+		}
+		req.Cursor = page.Next
+	}
+	forward := strings.Join(pages, "")
+
+	for i := len(pages) - 2; i >= 0 && req.Cursor != nil; i-- {
+		page := mustList(t, store, req)
+		if got := channels(page.Items); got != pages[i] {
+			t.Errorf("%s %s limit %d: going back got=%s want=%s", sortBy, order, limit, got, pages[i])
+		}
+		req.Cursor = page.Prev
+	}
+	if req.Cursor != nil {
+		t.Errorf("%s %s limit %d: first page still has a previous one", sortBy, order, limit)
+	}
+	return forward
+}
+
+func channels(items []domain.Rocket) string {
+	var out string
+	for _, r := range items {
+		out += r.Channel
+	}
+	return out
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
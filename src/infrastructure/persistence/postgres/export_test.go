@@ -0,0 +1,9 @@
+package postgres
+
+import "database/sql"
+
+// NewStoreOn monta un Store sobre db sin migrar, para probar Apply contra un
+// driver de mentira.
+func NewStoreOn(db *sql.DB, opts ...Option) *Store {
+	return &Store{db: db, opts: buildOptions(opts)}
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrationLockID identifica el advisory lock con el que las instancias que
+// arrancan a la vez se turnan para migrar.
+const migrationLockID = 0x6c756e6172 // "lunar"
+
+// migrations se aplican en orden y una sola vez; nunca se editan las ya
+// publicadas, se añade una nueva al final.
+var migrations = []string{
+	// 1: esquema inicial
+	`CREATE TABLE rockets (
+		channel          TEXT        PRIMARY KEY,
+		type             TEXT        NOT NULL DEFAULT '',
+		mission          TEXT        NOT NULL DEFAULT '',
+		speed            BIGINT      NOT NULL DEFAULT 0,
+		status           TEXT        NOT NULL,
+		altitude         BIGINT      NOT NULL DEFAULT 0,
+		fuel_level       BIGINT      NOT NULL DEFAULT 0,
+		stages_separated INTEGER     NOT NULL DEFAULT 0,
+		last_msg_num     INTEGER     NOT NULL DEFAULT 0,
+		updated_at       TIMESTAMPTZ,
+		event_time       TIMESTAMPTZ,
+		ingested_at      TIMESTAMPTZ,
+		last_rejection   JSONB,
+		version          BIGINT      NOT NULL
+	);
+
+	CREATE TABLE events (
+		channel        TEXT        NOT NULL,
+		sequence       INTEGER     NOT NULL,
+		message_number INTEGER     NOT NULL,
+		message_type   TEXT        NOT NULL,
+		message_time   TEXT        NOT NULL,
+		payload        JSON,
+		outcome        TEXT        NOT NULL,
+		reason         TEXT        NOT NULL DEFAULT '',
+		received_at    TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (channel, sequence)
+	);
+
+	CREATE TABLE processed_messages (
+		channel        TEXT    NOT NULL,
+		message_number INTEGER NOT NULL,
+		CONSTRAINT processed_messages_channel_message_number_key UNIQUE (channel, message_number)
+	);`,
+}
+
+// migrate aplica las migraciones pendientes, cada una en su transacción.
+func migrate(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER     PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"time"
+
+	"lunar/src/domain"
+)
+
+const defaultMaxAttempts = 5
+
+type options struct {
+	eventTime   bool
+	now         func() time.Time
+	maxAttempts int
+	onChange    func(domain.Rocket)
+	onResult    func(domain.MessageEnvelope, domain.MessageResult)
+}
+
+type Option func(*options)
+
+// WithEventTime hace que UpdatedAt sea el messageTime del último mensaje
+// aplicado, igual que persistence.WithEventTime.
+func WithEventTime() Option {
+	return func(o *options) {
+		o.eventTime = true
+	}
+}
+
+// WithClock sustituye time.Now; útil en tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		if now != nil {
+			o.now = now
+		}
+	}
+}
+
+// WithMaxAttempts fija cuántas veces se reintenta Apply cuando otra
+// instancia ha modificado el mismo cohete entretanto.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// WithChangeListener hace que Apply llame a fn con la nueva versión del
+// cohete cada vez que un envelope lo cambia, después de confirmar la
+// transacción. fn no debe bloquear.
+func WithChangeListener(fn func(domain.Rocket)) Option {
+	return func(o *options) {
+		o.onChange = fn
+	}
+}
+
+// WithResultListener hace que Apply llame a fn con lo que hizo con cada
+// envelope, después de confirmar la transacción. fn no debe bloquear.
+func WithResultListener(fn func(domain.MessageEnvelope, domain.MessageResult)) Option {
+	return func(o *options) {
+		o.onResult = fn
+	}
+}
+
+func buildOptions(opts []Option) options {
+	o := options{now: time.Now, maxAttempts: defaultMaxAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,421 @@
+// Package postgres implementa la persistencia sobre PostgreSQL para el
+// despliegue en cluster: varias instancias de lunar pueden consumir el mismo
+// topic porque cada Apply hace compare-and-swap sobre la fila del cohete.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"lunar/src/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// uniqueViolation es el SQLSTATE de una clave única repetida.
+const uniqueViolation = "23505"
+
+var (
+	ErrConcurrentUpdate = errors.New("rocket was modified concurrently, retries exhausted")
+
+	// errConflict indica que otra instancia se adelantó; Apply reintenta.
+	errConflict = errors.New("write conflict")
+)
+
+type Store struct {
+	db   *sql.DB
+	opts options
+}
+
+// NewStore se conecta a dsn y aplica las migraciones pendientes.
+func NewStore(dsn string, opts ...Option) (*Store, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrate(context.Background(), db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrate postgres schema: %w", err)
+	}
+	return &Store{db: db, opts: buildOptions(opts)}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Apply aplica el envelope con las reglas del dominio. Si otra instancia ha
+// tocado el cohete (o ha registrado ese mismo messageNumber) entre la lectura
+// y la escritura, se descarta la transacción y se vuelve a intentar.
+func (s *Store) Apply(env domain.MessageEnvelope) error {
+	for attempt := 0; attempt < s.opts.maxAttempts; attempt++ {
+		err := s.tryApply(env)
+		if !errors.Is(err, errConflict) {
+			return err
+		}
+	}
+	return ErrConcurrentUpdate
+}
+
+func (s *Store) tryApply(env domain.MessageEnvelope) error {
+	ch, num := env.Metadata.Channel, env.Metadata.MessageNum
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	r, version, err := getRocketVersion(tx, ch)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		r = *domain.NewRocket(ch)
+	}
+	// Rocket.Apply sólo consulta y marca num; basta con cargar ese.
+	var seen bool
+	if err := tx.QueryRow(`SELECT EXISTS (
+		SELECT 1 FROM processed_messages WHERE channel = $1 AND message_number = $2)`, ch, num).Scan(&seen); err != nil {
+		return err
+	}
+	if seen {
+		r.Processed.Add(num)
+	}
+
+	now := s.opts.now()
+	outcome, err := r.Apply(env)
+	if err != nil {
+		return err
+	}
+
+	var reason string
+	switch outcome {
+	case domain.OutcomeApplied:
+		r.IngestedAt = now
+		r.UpdatedAt = now
+		if s.opts.eventTime {
+			r.UpdatedAt = r.EventTime
+		}
+	case domain.OutcomeRejected:
+		reason = r.LastRejection.Reason
+	}
+
+	if outcome != domain.OutcomeDuplicate {
+		if _, err := tx.Exec(`INSERT INTO processed_messages (channel, message_number) VALUES ($1, $2)`, ch, num); err != nil {
+			return conflictOr(err)
+		}
+		if err := putRocket(tx, r, version); err != nil {
+			return err
+		}
+	}
+	if err := insertEvent(tx, env, outcome, reason, now); err != nil {
+		return conflictOr(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return conflictOr(err)
+	}
+	// Sólo se avisa de lo que aplica esta instancia, no de las demás.
+	if outcome.ChangesRocket() && s.opts.onChange != nil {
+		s.opts.onChange(r)
+	}
+	if s.opts.onResult != nil {
+		s.opts.onResult(env, domain.MessageResult{Outcome: outcome, Reason: reason, Rocket: r})
+	}
+	return nil
+}
+
+func (s *Store) Get(channel string) (domain.Rocket, bool, error) {
+	r, version, err := getRocketVersion(s.db, channel)
+	if err != nil || version == 0 {
+		return domain.Rocket{}, false, err
+	}
+	return r, true, nil
+}
+
+// List delega el filtro, la ordenación y el cursor en SQL; a igualdad
+// desempata por canal. ORDER BY no admite parámetros, así que la columna sale
+// siempre de sortColumns.
+func (s *Store) List(q domain.RocketQuery) (domain.Page, error) {
+	req := q.PageRequest
+	col, ok := sortColumns[req.SortBy]
+	if !ok {
+		col = "channel"
+	}
+	dir, cmp := "ASC", ">"
+	if req.Descending() {
+		dir, cmp = "DESC", "<"
+	}
+	w := filterWhere(q.Filter)
+	if req.Cursor != nil {
+		pivot, err := req.Cursor.Pivot()
+		if err != nil {
+			return domain.Page{}, err
+		}
+		w.add(`(`+col+`, channel) `+cmp+` (?, ?)`, pivot.SortValue(req.SortBy), pivot.Channel)
+	}
+	query, args := selectRocket+w.String()+` ORDER BY `+col+` `+dir+`, channel `+dir, w.args
+	if req.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT $%d`, len(args)+1)
+		args = append(args, req.Limit+1)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return domain.Page{}, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.Rocket, 0)
+	for rows.Next() {
+		r, _, err := scanRocket(rows)
+		if err != nil {
+			return domain.Page{}, err
+		}
+		items = append(items, r)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Page{}, err
+	}
+	return domain.NewPage(req, items), nil
+}
+
+func (s *Store) Events(channel string) ([]domain.RocketEvent, bool, error) {
+	items, err := queryEvents(s.db, channel, "message_number, sequence")
+	if err != nil || len(items) == 0 {
+		return nil, false, err
+	}
+	return items, true, nil
+}
+
+// GetAsOf reconstruye el cohete reaplicando, en orden de llegada, los
+// eventos guardados que caen dentro de point.
+func (s *Store) GetAsOf(channel string, point domain.PointInTime) (domain.Rocket, bool, error) {
+	recorded, err := queryEvents(s.db, channel, "sequence")
+	if err != nil {
+		return domain.Rocket{}, false, err
+	}
+	var r *domain.Rocket
+	for _, ev := range recorded {
+		if !point.Includes(ev) {
+			continue
+		}
+		if r == nil {
+			r = domain.NewRocket(channel)
+		}
+		// Lo que está en el histórico ya se pudo decodificar una vez.
+		if outcome, _ := r.Apply(ev.Envelope(channel)); outcome == domain.OutcomeApplied {
+			r.IngestedAt = ev.ReceivedAt
+			r.UpdatedAt = ev.ReceivedAt
+			if s.opts.eventTime {
+				r.UpdatedAt = r.EventTime
+			}
+		}
+	}
+	if r == nil {
+		return domain.Rocket{}, false, nil
+	}
+	return r.Clone(), true, nil
+}
+
+// Gaps informa de los messageNumbers que faltan por debajo del último
+// aplicado. Este store no tiene modo estricto, así que no hay buffer.
+func (s *Store) Gaps(channel string) (domain.OrderingGaps, bool, error) {
+	r, ok, err := s.Get(channel)
+	if err != nil || !ok {
+		return domain.OrderingGaps{}, false, err
+	}
+	rows, err := s.db.Query(`SELECT message_number FROM processed_messages
+		WHERE channel = $1 AND message_number <= $2 ORDER BY message_number`, channel, r.LastMsgNum)
+	if err != nil {
+		return domain.OrderingGaps{}, false, err
+	}
+	defer rows.Close()
+	var processed domain.MessageSet
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return domain.OrderingGaps{}, false, err
+		}
+		processed.Add(n)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.OrderingGaps{}, false, err
+	}
+	return domain.OrderingGaps{
+		Channel:      channel,
+		NextExpected: r.LastMsgNum + 1,
+		Missing:      processed.Missing(r.LastMsgNum),
+		Buffered:     []int{},
+	}, true, nil
+}
+
+// ---------- filas ----------
+
+// sortColumns traduce las claves de ordenación de la API a expresiones. Los
+// instantes sin valor cuentan como el cero de time.Time.
+var sortColumns = map[string]string{
+	"channel":          "channel",
+	"speed":            "speed",
+	"updated_at":       "COALESCE(updated_at, '0001-01-01 00:00:00+00')",
+	"event_time":       "COALESCE(event_time, '0001-01-01 00:00:00+00')",
+	"ingested_at":      "COALESCE(ingested_at, '0001-01-01 00:00:00+00')",
+	"altitude":         "altitude",
+	"fuel_level":       "fuel_level",
+	"stages_separated": "stages_separated",
+}
+
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+const selectRocket = `SELECT channel, type, mission, speed, status, altitude, fuel_level,
+	stages_separated, last_msg_num, updated_at, event_time, ingested_at, last_rejection, version
+	FROM rockets`
+
+// getRocketVersion devuelve versión 0 si el cohete no existe.
+func getRocketVersion(q queryer, channel string) (domain.Rocket, int64, error) {
+	r, version, err := scanRocket(q.QueryRow(selectRocket+` WHERE channel = $1`, channel))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Rocket{}, 0, nil
+	}
+	return r, version, err
+}
+
+func scanRocket(sc scanner) (domain.Rocket, int64, error) {
+	var (
+		r                                domain.Rocket
+		status                           string
+		updatedAt, eventTime, ingestedAt sql.NullTime
+		rejection                        sql.NullString
+		version                          int64
+	)
+	err := sc.Scan(&r.Channel, &r.Type, &r.Mission, &r.Speed, &status, &r.Altitude, &r.FuelLevel,
+		&r.StagesSeparated, &r.LastMsgNum, &updatedAt, &eventTime, &ingestedAt, &rejection, &version)
+	if err != nil {
+		return domain.Rocket{}, 0, err
+	}
+	r.Status = domain.RocketStatus(status)
+	r.UpdatedAt = updatedAt.Time
+	r.EventTime = eventTime.Time
+	r.IngestedAt = ingestedAt.Time
+	if rejection.Valid {
+		r.LastRejection = &domain.Rejection{}
+		if err := json.Unmarshal([]byte(rejection.String), r.LastRejection); err != nil {
+			return domain.Rocket{}, 0, err
+		}
+	}
+	return r, version, nil
+}
+
+// putRocket inserta el cohete (version 0) o lo actualiza sólo si sigue en la
+// versión leída; si no, devuelve errConflict.
+func putRocket(tx *sql.Tx, r domain.Rocket, version int64) error {
+	var rejection sql.NullString
+	if r.LastRejection != nil {
+		raw, err := json.Marshal(r.LastRejection)
+		if err != nil {
+			return err
+		}
+		rejection = sql.NullString{String: string(raw), Valid: true}
+	}
+	args := []any{r.Channel, r.Type, r.Mission, r.Speed, string(r.Status), r.Altitude, r.FuelLevel,
+		r.StagesSeparated, r.LastMsgNum, nullTime(r.UpdatedAt), nullTime(r.EventTime), nullTime(r.IngestedAt),
+		rejection}
+
+	var (
+		res sql.Result
+		err error
+	)
+	if version == 0 {
+		res, err = tx.Exec(`INSERT INTO rockets (channel, type, mission, speed, status, altitude, fuel_level,
+				stages_separated, last_msg_num, updated_at, event_time, ingested_at, last_rejection, version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, 1)
+			ON CONFLICT (channel) DO NOTHING`, args...)
+	} else {
+		res, err = tx.Exec(`UPDATE rockets SET type = $2, mission = $3, speed = $4, status = $5,
+				altitude = $6, fuel_level = $7, stages_separated = $8, last_msg_num = $9,
+				updated_at = $10, event_time = $11, ingested_at = $12, last_rejection = $13,
+				version = version + 1
+			WHERE channel = $1 AND version = $14`, append(args, version)...)
+	}
+	if err != nil {
+		return conflictOr(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errConflict
+	}
+	return nil
+}
+
+// insertEvent numera el evento con la siguiente secuencia del canal; si otra
+// instancia se adelanta, la clave primaria lo detecta. Los parámetros van
+// con tipo porque en un INSERT ... SELECT postgres no los infiere.
+func insertEvent(tx *sql.Tx, env domain.MessageEnvelope, outcome domain.Outcome, reason string, at time.Time) error {
+	var payload sql.NullString
+	if len(env.Message) > 0 {
+		payload = sql.NullString{String: string(env.Message), Valid: true}
+	}
+	_, err := tx.Exec(`INSERT INTO events (channel, sequence, message_number, message_type, message_time,
+			payload, outcome, reason, received_at)
+		SELECT $1, COALESCE(MAX(sequence), 0) + 1, $2::integer, $3::text, $4::text, $5::json, $6::text, $7::text, $8::timestamptz
+		FROM events WHERE channel = $1`,
+		env.Metadata.Channel, env.Metadata.MessageNum, env.Metadata.MessageType, env.Metadata.MessageTime,
+		payload, string(outcome), reason, at)
+	return err
+}
+
+func queryEvents(q queryer, channel, orderBy string) ([]domain.RocketEvent, error) {
+	rows, err := q.Query(`SELECT sequence, message_number, message_type, message_time, payload, outcome, reason, received_at
+		FROM events WHERE channel = $1 ORDER BY `+orderBy, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.RocketEvent
+	for rows.Next() {
+		var (
+			ev      domain.RocketEvent
+			payload sql.NullString
+			outcome string
+		)
+		if err := rows.Scan(&ev.Sequence, &ev.MessageNumber, &ev.MessageType, &ev.MessageTime,
+			&payload, &outcome, &ev.Reason, &ev.ReceivedAt); err != nil {
+			return nil, err
+		}
+		if payload.Valid {
+			ev.Payload = json.RawMessage(payload.String)
+		}
+		ev.Outcome = domain.Outcome(outcome)
+		items = append(items, ev)
+	}
+	return items, rows.Err()
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// conflictOr traduce las violaciones de unicidad (otra instancia registró el
+// mismo messageNumber o la misma secuencia) a errConflict.
+func conflictOr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errConflict
+	}
+	return err
+}
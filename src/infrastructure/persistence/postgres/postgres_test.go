@@ -0,0 +1,293 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+	"lunar/src/infrastructure/persistence/persistencetest"
+	"lunar/src/infrastructure/persistence/postgres"
+)
+
+// Los tests usan LUNAR_POSTGRES_DSN si está definida; si no, arrancan un
+// cluster temporal con initdb/pg_ctl del PATH (o de LUNAR_POSTGRES_BIN). Sin
+// ninguna de las dos cosas fallan: sólo se saltan si se pide explícitamente
+// con LUNAR_POSTGRES_SKIP. `make test` levanta el postgres de docker-compose.
+// Los reintentos de Apply se prueban además sin servidor en retry_test.go.
+const (
+	envDSN  = "LUNAR_POSTGRES_DSN"
+	envBin  = "LUNAR_POSTGRES_BIN"
+	envSkip = "LUNAR_POSTGRES_SKIP"
+)
+
+var (
+	baseDSN  string
+	skipWhy  = "no postgres available: set " + envDSN + " or put initdb/pg_ctl on PATH"
+	schemaID int
+	schemaMu sync.Mutex
+)
+
+func TestMain(m *testing.M) {
+	if dsn := os.Getenv(envDSN); dsn != "" {
+		baseDSN = dsn
+		os.Exit(m.Run())
+	}
+	stop, dsn, err := startLocalPostgres()
+	if err != nil {
+		skipWhy = err.Error()
+		os.Exit(m.Run())
+	}
+	baseDSN = dsn
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+func TestStore_Conformance(t *testing.T) {
+	persistencetest.Run(t, func(t *testing.T) port.Persistence {
+		return openStore(t, newSchema(t))
+	})
+}
+
+func TestStore_MigrationsAreIdempotent(t *testing.T) {
+	dsn := newSchema(t)
+	for i := 0; i < 2; i++ {
+		store, err := postgres.NewStore(dsn)
+		if err != nil {
+			t.Fatalf("open store (%d) failed: %v", i, err)
+		}
+		_ = store.Close()
+	}
+}
+
+func TestStore_EventsAndRejections(t *testing.T) {
+	store := openStore(t, newSchema(t))
+	ch := "c1"
+
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeExploded, struct{}{}),
+		makeEnv(ch, 4, "2022-02-02T19:39:08Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}),
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+	)
+
+	got, _, _ := store.Get(ch)
+	if got.Status != domain.StatusExploded || got.Speed != 500 {
+		t.Errorf("speed increase applied after explosion; got=%+v", got)
+	}
+	if got.LastRejection == nil || got.LastRejection.MessageNumber != 4 {
+		t.Errorf("last rejection not stored; got=%+v", got.LastRejection)
+	}
+
+	events, _, _ := store.Events(ch)
+	want := []domain.Outcome{domain.OutcomeApplied, domain.OutcomeDuplicate, domain.OutcomeApplied, domain.OutcomeRejected}
+	if len(events) != len(want) {
+		t.Fatalf("events len; got=%d want=%d", len(events), len(want))
+	}
+	for i, w := range want {
+		if events[i].Outcome != w {
+			t.Errorf("event %d; got=%s want=%s", i, events[i].Outcome, w)
+		}
+	}
+
+	gaps, _, _ := store.Gaps(ch)
+	if len(gaps.Missing) != 1 || gaps.Missing[0] != 2 {
+		t.Errorf("gaps mismatch; got=%+v", gaps)
+	}
+	past, ok, _ := store.GetAsOf(ch, domain.PointInTime{MessageNumber: 1})
+	if !ok || past.Status != domain.StatusLaunched {
+		t.Errorf("as of msg 1 mismatch; got=%+v", past)
+	}
+}
+
+// Dos instancias aplican a la vez sobre el mismo cohete, cada envelope
+// repetido en ambas: el compare-and-swap y la clave única deben dejar cada
+// delta aplicado exactamente una vez.
+func TestStore_ConcurrentInstancesApplyEachMessageOnce(t *testing.T) {
+	dsn := newSchema(t)
+	stores := []*postgres.Store{
+		openStore(t, dsn, postgres.WithMaxAttempts(1000)),
+		openStore(t, dsn, postgres.WithMaxAttempts(1000)),
+	}
+	ch := "c1"
+	const n = 40
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for _, store := range stores {
+		for i := 1; i <= n; i++ {
+			wg.Add(1)
+			go func(store *postgres.Store, i int) {
+				defer wg.Done()
+				errs <- store.Apply(makeEnv(ch, i, "2022-02-02T19:39:05Z",
+					domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+			}(store, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent apply failed: %v", err)
+		}
+	}
+
+	got, _, _ := stores[0].Get(ch)
+	if got.Speed != 10*n || got.LastMsgNum != n {
+		t.Errorf("concurrent deltas; got speed=%d last=%d want speed=%d last=%d", got.Speed, got.LastMsgNum, 10*n, n)
+	}
+	events, _, _ := stores[1].Events(ch)
+	applied := 0
+	for _, ev := range events {
+		if ev.Outcome == domain.OutcomeApplied {
+			applied++
+		}
+	}
+	if len(events) != 2*n || applied != n {
+		t.Errorf("events; got %d (%d applied) want %d (%d applied)", len(events), applied, 2*n, n)
+	}
+}
+
+// ---------- helpers ----------
+
+// newSchema crea un esquema vacío para el test y devuelve un DSN que apunta a él.
+func newSchema(t *testing.T) string {
+	t.Helper()
+	if baseDSN == "" {
+		if os.Getenv(envSkip) != "" {
+			t.Skip(skipWhy)
+		}
+		t.Fatal(skipWhy + " (or set " + envSkip + "=1 to skip these tests)")
+	}
+	schemaMu.Lock()
+	schemaID++
+	name := fmt.Sprintf("lunar_test_%d_%d", os.Getpid(), schemaID)
+	schemaMu.Unlock()
+
+	db, err := sql.Open("pgx", baseDSN)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE SCHEMA ` + name); err != nil {
+		t.Fatalf("create schema failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if db, err := sql.Open("pgx", baseDSN); err == nil {
+			_, _ = db.Exec(`DROP SCHEMA ` + name + ` CASCADE`)
+			_ = db.Close()
+		}
+	})
+	return withParam(baseDSN, "search_path", name)
+}
+
+func withParam(dsn, key, value string) string {
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + key + "=" + value
+	}
+	return dsn + " " + key + "=" + value
+}
+
+// startLocalPostgres arranca un cluster desechable escuchando sólo en un
+// socket unix dentro de un directorio temporal.
+func startLocalPostgres() (func(), string, error) {
+	initdb, pgctl := "initdb", "pg_ctl"
+	if bin := os.Getenv(envBin); bin != "" {
+		initdb, pgctl = filepath.Join(bin, initdb), filepath.Join(bin, pgctl)
+	}
+	if _, err := exec.LookPath(initdb); err != nil {
+		return nil, "", fmt.Errorf("no postgres available: set %s or put initdb/pg_ctl on PATH", envDSN)
+	}
+
+	dir, err := os.MkdirTemp("", "lunar-pg")
+	if err != nil {
+		return nil, "", err
+	}
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput(); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, "", fmt.Errorf("initdb failed: %v: %s", err, out)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, "", err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses='' -c fsync=off", port, dir)
+	start := exec.Command(pgctl, "-D", data, "-o", opts, "-l", filepath.Join(dir, "log"), "-w", "-t", "30", "start")
+	if out, err := start.CombinedOutput(); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, "", fmt.Errorf("pg_ctl start failed: %v: %s", err, out)
+	}
+	stop := func() {
+		_ = exec.Command(pgctl, "-D", data, "-m", "immediate", "-w", "stop").Run()
+		_ = os.RemoveAll(dir)
+	}
+	dsn := fmt.Sprintf("host=%s port=%d user=postgres dbname=postgres sslmode=disable", dir, port)
+	return stop, dsn, waitReady(dsn)
+}
+
+func waitReady(dsn string) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if err = db.Ping(); err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func openStore(t *testing.T, dsn string, opts ...postgres.Option) *postgres.Store {
+	t.Helper()
+	store, err := postgres.NewStore(dsn, opts...)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func applyAll(t *testing.T, store *postgres.Store, envs ...domain.MessageEnvelope) {
+	t.Helper()
+	for _, env := range envs {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
+	}
+}
+
+func makeEnv(channel string, num int, when string, kind string, payload any) domain.MessageEnvelope {
+	raw, _ := json.Marshal(payload)
+	env := domain.MessageEnvelope{}
+	env.Metadata.Channel = channel
+	env.Metadata.MessageNum = num
+	env.Metadata.MessageTime = when
+	env.Metadata.MessageType = kind
+	env.Message = raw
+	return env
+}
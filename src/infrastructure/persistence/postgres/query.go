@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"lunar/src/domain"
+)
+
+// where acumula las condiciones del WHERE de List con sus argumentos. Cada
+// ? de una condición se numera ($1, $2...) según el orden de los argumentos.
+type where struct {
+	conds []string
+	args  []any
+}
+
+func (w *where) add(cond string, args ...any) {
+	for _, arg := range args {
+		w.args = append(w.args, arg)
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(w.args)), 1)
+	}
+	w.conds = append(w.conds, cond)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(w.conds, ` AND `)
+}
+
+// filterWhere traduce el filtro a condiciones sobre las columnas de rockets.
+// Los instantes usan las mismas expresiones que la ordenación.
+func filterWhere(f domain.RocketFilter) *where {
+	w := &where{}
+	if f.Status != "" {
+		w.add(`status = ?`, string(f.Status))
+	}
+	if f.Type != "" {
+		w.add(`type = ?`, f.Type)
+	}
+	if f.Mission != "" {
+		w.add(`mission = ?`, f.Mission)
+	}
+	if f.MissionPrefix != "" {
+		w.add(`starts_with(mission, ?)`, f.MissionPrefix)
+	}
+	if f.Speed.Min != nil {
+		w.add(`speed >= ?`, *f.Speed.Min)
+	}
+	if f.Speed.Max != nil {
+		w.add(`speed <= ?`, *f.Speed.Max)
+	}
+	timeRange(w, sortColumns["updated_at"], f.UpdatedAt)
+	timeRange(w, sortColumns["event_time"], f.EventTime)
+	timeRange(w, sortColumns["ingested_at"], f.IngestedAt)
+	return w
+}
+
+func timeRange(w *where, expr string, r domain.TimeRange) {
+	if !r.From.IsZero() {
+		w.add(expr+` >= ?`, r.From)
+	}
+	if !r.To.IsZero() {
+		w.add(expr+` <= ?`, r.To)
+	}
+}
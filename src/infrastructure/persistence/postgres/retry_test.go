@@ -0,0 +1,125 @@
+package postgres_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"lunar/src/domain"
+	"lunar/src/infrastructure/persistence/postgres"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Estos tests no necesitan servidor: corren Apply contra stubDB para que el
+// compare-and-swap y los reintentos se prueben siempre.
+
+func launch(ch string) domain.MessageEnvelope {
+	return makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"})
+}
+
+func speedUp(ch string, num int, by int64) domain.MessageEnvelope {
+	return makeEnv(ch, num, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: by})
+}
+
+// failFirst devuelve un onExec que hace fallar con err las primeras n
+// sentencias que empiezan por prefix.
+func failFirst(prefix string, n int, err error) func(string) error {
+	return func(query string) error {
+		if n > 0 && strings.HasPrefix(strings.TrimSpace(query), prefix) {
+			n--
+			return err
+		}
+		return nil
+	}
+}
+
+func TestApply_UniqueViolationIsRetried(t *testing.T) {
+	stub, db := newStubDB()
+	var changes int
+	store := postgres.NewStoreOn(db, postgres.WithChangeListener(func(domain.Rocket) { changes++ }))
+	defer store.Close()
+
+	stub.onExec = failFirst("INSERT INTO processed_messages", 1, &pgconn.PgError{Code: "23505"})
+	if err := store.Apply(launch("c1")); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	if got := stub.beginCount(); got != 2 {
+		t.Errorf("attempts; got=%d want=2", got)
+	}
+	if changes != 1 {
+		t.Errorf("change listener calls; got=%d want=1", changes)
+	}
+	st := stub.state()
+	if st.events != 1 || len(st.processed) != 1 || st.rockets["c1"].version != 1 {
+		t.Errorf("state after retry; events=%d processed=%d version=%d", st.events, len(st.processed), st.rockets["c1"].version)
+	}
+}
+
+func TestApply_StaleVersionIsRetriedOnTheNewOne(t *testing.T) {
+	stub, db := newStubDB()
+	store := postgres.NewStoreOn(db)
+	defer store.Close()
+	if err := store.Apply(launch("c1")); err != nil {
+		t.Fatalf("launch failed: %v", err)
+	}
+
+	// Otra instancia confirma una versión nueva justo antes del UPDATE.
+	raced := false
+	stub.onExec = func(query string) error {
+		if !raced && strings.HasPrefix(strings.TrimSpace(query), "UPDATE rockets") {
+			raced = true
+			stub.bump("c1")
+		}
+		return nil
+	}
+	if err := store.Apply(speedUp("c1", 2, 300)); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	if got := stub.beginCount(); got != 3 {
+		t.Errorf("attempts; got=%d want=3 (launch + conflict + retry)", got)
+	}
+	if st := stub.state(); st.rockets["c1"].version != 3 || st.events != 2 {
+		t.Errorf("state after retry; version=%d events=%d", st.rockets["c1"].version, st.events)
+	}
+	got, ok, err := store.Get("c1")
+	if err != nil || !ok || got.Speed != 800 {
+		t.Errorf("rocket after retry; got=%+v ok=%v err=%v", got, ok, err)
+	}
+}
+
+func TestApply_GivesUpAfterMaxAttempts(t *testing.T) {
+	stub, db := newStubDB()
+	store := postgres.NewStoreOn(db, postgres.WithMaxAttempts(3))
+	defer store.Close()
+
+	stub.onExec = failFirst("INSERT INTO events", 10, &pgconn.PgError{Code: "23505"})
+	err := store.Apply(launch("c1"))
+	if !errors.Is(err, postgres.ErrConcurrentUpdate) {
+		t.Fatalf("expected ErrConcurrentUpdate; got %v", err)
+	}
+	if got := stub.beginCount(); got != 3 {
+		t.Errorf("attempts; got=%d want=3", got)
+	}
+	if st := stub.state(); len(st.rockets) != 0 || len(st.processed) != 0 || st.events != 0 {
+		t.Errorf("rolled back attempts left state behind: %+v", st)
+	}
+}
+
+func TestApply_OtherErrorsAreNotRetried(t *testing.T) {
+	stub, db := newStubDB()
+	store := postgres.NewStoreOn(db)
+	defer store.Close()
+
+	fk := &pgconn.PgError{Code: "23503"}
+	stub.onExec = failFirst("INSERT INTO processed_messages", 1, fk)
+	if err := store.Apply(launch("c1")); !errors.Is(err, fk) {
+		t.Fatalf("expected the foreign key error; got %v", err)
+	}
+	if got := stub.beginCount(); got != 1 {
+		t.Errorf("attempts; got=%d want=1", got)
+	}
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lunar/src/domain"
+)
+
+// Stats calcula los agregados en una sola transacción REPEATABLE READ, para
+// que todos salgan de la misma foto. Aquí no se mantienen al aplicar como en
+// MemoryStore: cada llamada recorre la tabla.
+func (s *Store) Stats(window time.Duration) (domain.FleetStats, error) {
+	st := domain.NewFleetStats(window)
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return st, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = tx.QueryRow(`SELECT COUNT(*), COALESCE(MIN(speed), 0), COALESCE(MAX(speed), 0),
+		COALESCE(AVG(speed), 0)::float8 FROM rockets`).
+		Scan(&st.Total, &st.Speed.Min, &st.Speed.Max, &st.Speed.Mean)
+	if err != nil {
+		return st, err
+	}
+	for _, g := range []struct {
+		col string
+		add func(k string, n int)
+	}{
+		{"status", func(k string, n int) { st.ByStatus[domain.RocketStatus(k)] = n }},
+		{"type", func(k string, n int) { st.ByType[k] = n }},
+		{"mission", func(k string, n int) { st.ByMission[k] = n }},
+	} {
+		if err := countBy(tx, g.col, g.add); err != nil {
+			return st, err
+		}
+	}
+	if err := speedPercentiles(tx, &st); err != nil {
+		return st, err
+	}
+	cutoff := s.opts.now().Add(-window)
+	err = tx.QueryRow(`SELECT COUNT(*) FROM rockets WHERE updated_at >= $1`, cutoff).
+		Scan(&st.UpdatedRecently.Count)
+	return st, err
+}
+
+// countBy cuenta los cohetes por los valores no vacíos de col.
+func countBy(tx *sql.Tx, col string, add func(k string, n int)) error {
+	rows, err := tx.Query(`SELECT ` + col + `, COUNT(*) FROM rockets WHERE ` + col + ` <> '' GROUP BY ` + col)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			k string
+			n int
+		)
+		if err := rows.Scan(&k, &n); err != nil {
+			return err
+		}
+		add(k, n)
+	}
+	return rows.Err()
+}
+
+// speedPercentiles lee de una pasada las velocidades en las posiciones de
+// los percentiles.
+func speedPercentiles(tx *sql.Tx, st *domain.FleetStats) error {
+	if st.Total == 0 {
+		return nil
+	}
+	byRank := make(map[int]int64, len(domain.SpeedPercentiles))
+	args := make([]any, 0, len(domain.SpeedPercentiles))
+	marks := make([]string, 0, len(domain.SpeedPercentiles))
+	for _, p := range domain.SpeedPercentiles {
+		args = append(args, domain.PercentileRank(p, st.Total))
+		marks = append(marks, fmt.Sprintf("$%d", len(args)))
+	}
+	rows, err := tx.Query(`SELECT k, speed FROM (
+			SELECT speed, ROW_NUMBER() OVER (ORDER BY speed) - 1 AS k FROM rockets
+		) AS ranked WHERE k IN (`+strings.Join(marks, ", ")+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			k     int
+			speed int64
+		)
+		if err := rows.Scan(&k, &speed); err != nil {
+			return err
+		}
+		byRank[k] = speed
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	st.Speed.SetPercentiles(st.Total, func(k int) int64 { return byRank[k] })
+	return nil
+}
@@ -0,0 +1,208 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// stubDB es un postgres de mentira en memoria con lo justo para que Apply
+// funcione: guarda filas de rockets, processed_messages y cuenta eventos,
+// con transacciones que se confirman o se descartan enteras. onExec se llama
+// antes de cada sentencia y permite inyectar un error o simular que otra
+// instancia ha escrito entretanto.
+type stubDB struct {
+	mu        sync.Mutex
+	committed stubState
+	tx        *stubState
+	begins    int
+	onExec    func(query string) error
+}
+
+type stubState struct {
+	rockets   map[string]stubRow
+	processed map[string]bool
+	events    int
+}
+
+// stubRow son las columnas de selectRocket salvo version, en el mismo orden.
+type stubRow struct {
+	values  []driver.Value
+	version int64
+}
+
+func newStubDB() (*stubDB, *sql.DB) {
+	s := &stubDB{committed: stubState{rockets: map[string]stubRow{}, processed: map[string]bool{}}}
+	db := sql.OpenDB(stubConnector{s})
+	db.SetMaxOpenConns(1)
+	return s, db
+}
+
+func (st stubState) clone() *stubState {
+	return &stubState{rockets: maps.Clone(st.rockets), processed: maps.Clone(st.processed), events: st.events}
+}
+
+// bump simula que otra instancia ha confirmado una versión nueva del cohete.
+// Se llama desde onExec, con mu tomado.
+func (s *stubDB) bump(channel string) {
+	row := s.committed.rockets[channel]
+	row.version++
+	s.committed.rockets[channel] = row
+	if s.tx != nil {
+		s.tx.rockets[channel] = row
+	}
+}
+
+func (s *stubDB) state() stubState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.committed.clone()
+}
+
+func (s *stubDB) beginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.begins
+}
+
+func processedKey(args []driver.NamedValue) string {
+	return fmt.Sprint(args[0].Value, "#", args[1].Value)
+}
+
+func (s *stubDB) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.onExec != nil {
+		if err := s.onExec(query); err != nil {
+			return nil, err
+		}
+	}
+	st := s.tx
+	if st == nil {
+		st = &s.committed
+	}
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+
+	switch q := strings.TrimSpace(query); {
+	case strings.HasPrefix(q, "INSERT INTO processed_messages"):
+		key := processedKey(args)
+		if st.processed[key] {
+			return nil, errors.New("stub: duplicate processed message")
+		}
+		st.processed[key] = true
+	case strings.HasPrefix(q, "INSERT INTO rockets"):
+		if _, ok := st.rockets[values[0].(string)]; ok {
+			return driver.RowsAffected(0), nil
+		}
+		st.rockets[values[0].(string)] = stubRow{values: values, version: 1}
+	case strings.HasPrefix(q, "UPDATE rockets"):
+		row, ok := st.rockets[values[0].(string)]
+		if !ok || row.version != values[13].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		st.rockets[values[0].(string)] = stubRow{values: values[:13], version: row.version + 1}
+	case strings.HasPrefix(q, "INSERT INTO events"):
+		st.events++
+	default:
+		return nil, errors.New("stub: unexpected exec: " + q)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stubDB) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.tx
+	if st == nil {
+		st = &s.committed
+	}
+
+	switch q := strings.TrimSpace(query); {
+	case strings.HasPrefix(q, "SELECT EXISTS"):
+		return &stubRows{cols: []string{"exists"}, rows: [][]driver.Value{{st.processed[processedKey(args)]}}}, nil
+	case strings.HasPrefix(q, "SELECT channel"):
+		rows := &stubRows{cols: make([]string, 14)}
+		if row, ok := st.rockets[args[0].Value.(string)]; ok {
+			rows.rows = [][]driver.Value{append(append([]driver.Value{}, row.values...), row.version)}
+		}
+		return rows, nil
+	default:
+		return nil, errors.New("stub: unexpected query: " + q)
+	}
+}
+
+type stubConnector struct{ s *stubDB }
+
+func (c stubConnector) Connect(context.Context) (driver.Conn, error) { return stubConn(c), nil }
+func (c stubConnector) Driver() driver.Driver                        { return stubDriver{} }
+
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("stub: use the connector")
+}
+
+type stubConn struct{ s *stubDB }
+
+func (c stubConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("stub: prepared statements not supported")
+}
+func (c stubConn) Close() error { return nil }
+
+func (c stubConn) Begin() (driver.Tx, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.begins++
+	c.s.tx = c.s.committed.clone()
+	return c, nil
+}
+
+func (c stubConn) Commit() error {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if c.s.tx != nil {
+		c.s.committed, c.s.tx = *c.s.tx, nil
+	}
+	return nil
+}
+
+func (c stubConn) Rollback() error {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.tx = nil
+	return nil
+}
+
+func (c stubConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.s.exec(query, args)
+}
+
+func (c stubConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.s.query(query, args)
+}
+
+type stubRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string { return r.cols }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
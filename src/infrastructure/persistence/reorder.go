@@ -0,0 +1,178 @@
+package persistence
+
+import (
+	"sort"
+	"time"
+
+	"lunar/src/domain"
+)
+
+// channelOrder es el estado de reordenación de un canal en modo estricto.
+type channelOrder struct {
+	next         int
+	pending      map[int]domain.MessageEnvelope
+	waitingSince time.Time
+	missing      map[int]struct{}
+}
+
+func newChannelOrder() *channelOrder {
+	return &channelOrder{
+		next:    1,
+		pending: make(map[int]domain.MessageEnvelope),
+		missing: make(map[int]struct{}),
+	}
+}
+
+// applyInOrder aplica el envelope si es el siguiente esperado (y después todo
+// lo contiguo que haya en el buffer); si llega adelantado lo guarda. Si el
+// esperado no se puede aplicar, llegó igualmente: queda registrado como
+// rechazado, el canal sigue y el error vuelve a quien lo mandó. Debe
+// llamarse con el mu del shard tomado.
+func (sh *shard) applyInOrder(env domain.MessageEnvelope, now time.Time) error {
+	ch, num := env.Metadata.Channel, env.Metadata.MessageNum
+	co, ok := sh.ordering[ch]
+	if !ok {
+		co = newChannelOrder()
+		sh.ordering[ch] = co
+	}
+
+	switch {
+	case num < co.next:
+		// Ya aplicado (duplicado) o relleno tardío de un hueco declarado; en
+		// ese caso se aplica con las reglas normales de desorden.
+		delete(co.missing, num)
+		return sh.applyNow(env, now)
+
+	case num > co.next:
+		if _, dup := co.pending[num]; dup {
+			sh.record(env, domain.OutcomeDuplicate, "", now)
+			return nil
+		}
+		co.pending[num] = env
+		if co.waitingSince.IsZero() {
+			co.waitingSince = now
+		}
+		if len(co.pending) > sh.opts.reorderBufferSize {
+			sh.declareGap(co, now)
+		}
+		return nil
+	}
+
+	err := sh.applyNow(env, now)
+	if err != nil {
+		sh.record(env, domain.OutcomeRejected, err.Error(), now)
+	}
+	co.next++
+	sh.drain(co, now)
+	return err
+}
+
+// drain aplica los envelopes contiguos que esperaban en el buffer. Un
+// envelope del buffer que no se puede aplicar no es asunto de quien ha
+// desbloqueado el canal: queda registrado como rechazado y se sigue.
+func (sh *shard) drain(co *channelOrder, now time.Time) {
+	for {
+		env, ok := co.pending[co.next]
+		if !ok {
+			break
+		}
+		delete(co.pending, co.next)
+		co.next++
+		if err := sh.applyNow(env, now); err != nil {
+			sh.record(env, domain.OutcomeRejected, err.Error(), now)
+		}
+	}
+	co.waitingSince = time.Time{}
+	if len(co.pending) > 0 {
+		co.waitingSince = now
+	}
+}
+
+// declareGap da por perdidos los números entre el esperado y el menor que
+// hay en buffer, y continúa aplicando desde ahí.
+func (sh *shard) declareGap(co *channelOrder, now time.Time) {
+	lowest := 0
+	for n := range co.pending {
+		if lowest == 0 || n < lowest {
+			lowest = n
+		}
+	}
+	if lowest == 0 {
+		co.waitingSince = time.Time{}
+		return
+	}
+	for n := co.next; n < lowest; n++ {
+		co.missing[n] = struct{}{}
+	}
+	co.next = lowest
+	sh.drain(co, now)
+}
+
+// expireGaps declara los huecos que llevan más de gapTimeout abiertos. Como
+// mucho uno por canal y llamada: lo que quede en el buffer vuelve a esperar
+// desde now.
+func (sh *shard) expireGaps(now time.Time) {
+	for _, co := range sh.ordering {
+		if !co.waitingSince.IsZero() && now.Sub(co.waitingSince) >= sh.opts.gapTimeout {
+			sh.declareGap(co, now)
+		}
+	}
+}
+
+// ExpireGaps declara los huecos caducados sin esperar a que llegue tráfico
+// nuevo. Pensado para llamarse periódicamente en modo estricto.
+func (s *MemoryStore) ExpireGaps() {
+	if !s.opts.strict {
+		return
+	}
+	now := s.opts.now()
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		sh.expireGaps(now)
+		sh.mu.Unlock()
+	}
+}
+
+func (s *MemoryStore) Gaps(channel string) (domain.OrderingGaps, bool, error) {
+	sh := s.shardFor(channel)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if s.opts.strict {
+		sh.expireGaps(s.opts.now())
+	}
+	r, exists := sh.rockets[channel]
+	co, ok := sh.ordering[channel]
+	if !ok && !exists {
+		return domain.OrderingGaps{}, false, nil
+	}
+	if !ok {
+		// Sin modo estricto no hay buffer: lo que falte por debajo del último
+		// número aplicado puede seguir llegando, pero se informa igualmente.
+		co = newChannelOrder()
+		co.next = r.LastMsgNum + 1
+		for _, n := range r.Processed.Missing(r.LastMsgNum) {
+			co.missing[n] = struct{}{}
+		}
+	}
+	gaps := domain.OrderingGaps{
+		Channel:      channel,
+		NextExpected: co.next,
+		Missing:      sortedKeys(co.missing),
+		Buffered:     make([]int, 0, len(co.pending)),
+	}
+	for n := range co.pending {
+		gaps.Buffered = append(gaps.Buffered, n)
+	}
+	sort.Ints(gaps.Buffered)
+	return gaps, true, nil
+}
+
+func sortedKeys(m map[int]struct{}) []int {
+	out := make([]int, 0, len(m))
+	for n := range m {
+		out = append(out, n)
+	}
+	sort.Ints(out)
+	return out
+}
@@ -0,0 +1,226 @@
+package persistence_test
+
+import (
+	"testing"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/infrastructure/persistence"
+)
+
+func TestStrict_BuffersUntilContiguous(t *testing.T) {
+	store := persistence.NewMemoryStore(persistence.WithStrictOrdering(10, time.Hour))
+	ch := "c1"
+
+	// El 3 y el 2 llegan antes que el lanzamiento: se quedan en buffer.
+	mustApply(t, store, makeEnv(ch, 3, "2022-02-02T19:39:07Z",
+		domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "GEMINI"}))
+	mustApply(t, store, makeEnv(ch, 2, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}))
+
+	if _, ok, _ := store.Get(ch); ok {
+		t.Fatalf("rocket should not exist before message 1 arrives")
+	}
+	gaps, ok, _ := store.Gaps(ch)
+	if !ok || gaps.NextExpected != 1 || len(gaps.Buffered) != 2 {
+		t.Fatalf("unexpected gaps before launch; got=%+v", gaps)
+	}
+
+	mustApply(t, store, makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+
+	got, ok, _ := store.Get(ch)
+	if !ok || got.Speed != 800 || got.Mission != "GEMINI" || got.LastMsgNum != 3 {
+		t.Errorf("rocket after drain mismatch; got=%+v", got)
+	}
+	gaps, _, _ = store.Gaps(ch)
+	if gaps.NextExpected != 4 || len(gaps.Buffered) != 0 || len(gaps.Missing) != 0 {
+		t.Errorf("unexpected gaps after drain; got=%+v", gaps)
+	}
+}
+
+func TestStrict_DeclaresGapAfterTimeout(t *testing.T) {
+	store := persistence.NewMemoryStore(persistence.WithStrictOrdering(10, time.Millisecond))
+	ch := "c1"
+
+	mustApply(t, store, makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+	mustApply(t, store, makeEnv(ch, 4, "2022-02-02T19:39:08Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}))
+
+	time.Sleep(5 * time.Millisecond)
+	store.ExpireGaps()
+
+	got, _, _ := store.Get(ch)
+	if got.Speed != 600 || got.LastMsgNum != 4 {
+		t.Errorf("buffered message not applied after gap; got=%+v", got)
+	}
+	gaps, _, _ := store.Gaps(ch)
+	if len(gaps.Missing) != 2 || gaps.Missing[0] != 2 || gaps.Missing[1] != 3 || gaps.NextExpected != 5 {
+		t.Fatalf("unexpected gaps; got=%+v", gaps)
+	}
+
+	// Un reenvío tardío de un hueco se aplica y deja de figurar como perdido.
+	mustApply(t, store, makeEnv(ch, 2, "2022-02-02T19:39:06Z",
+		domain.TypeSpeedDecreased, domain.RocketSpeedDeltaPayload{By: 50}))
+	got, _, _ = store.Get(ch)
+	if got.Speed != 550 {
+		t.Errorf("late fill not applied; speed=%d want=550", got.Speed)
+	}
+	gaps, _, _ = store.Gaps(ch)
+	if len(gaps.Missing) != 1 || gaps.Missing[0] != 3 {
+		t.Errorf("late fill still reported missing; got=%+v", gaps)
+	}
+}
+
+func TestStrict_BufferOverflowDeclaresGap(t *testing.T) {
+	store := persistence.NewMemoryStore(persistence.WithStrictOrdering(2, time.Hour))
+	ch := "c1"
+
+	for _, num := range []int{3, 4, 5} {
+		mustApply(t, store, makeEnv(ch, num, "2022-02-02T19:39:05Z",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+
+	got, ok, _ := store.Get(ch)
+	if !ok || got.Speed != 30 {
+		t.Errorf("overflow should flush the buffer; got=%+v ok=%v", got, ok)
+	}
+	gaps, _, _ := store.Gaps(ch)
+	if len(gaps.Missing) != 2 || gaps.NextExpected != 6 {
+		t.Errorf("unexpected gaps; got=%+v", gaps)
+	}
+}
+
+func TestStrict_DuplicatesStillIgnored(t *testing.T) {
+	store := persistence.NewMemoryStore(persistence.WithStrictOrdering(10, time.Hour))
+	ch := "c1"
+
+	env1 := makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10})
+	env3 := makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10})
+	for _, env := range []domain.MessageEnvelope{env1, env3, env1, env3} {
+		mustApply(t, store, env)
+	}
+	mustApply(t, store, makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+
+	got, _, _ := store.Get(ch)
+	if got.Speed != 30 {
+		t.Errorf("duplicates applied in strict mode; speed=%d want=30", got.Speed)
+	}
+}
+
+func TestGaps_NonStrictReportsUnseenNumbers(t *testing.T) {
+	store := persistence.NewMemoryStore()
+	ch := "c1"
+
+	for _, num := range []int{1, 4} {
+		mustApply(t, store, makeEnv(ch, num, "2022-02-02T19:39:05Z",
+			domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 10}))
+	}
+
+	gaps, ok, _ := store.Gaps(ch)
+	if !ok || len(gaps.Missing) != 2 || gaps.NextExpected != 5 {
+		t.Errorf("unexpected gaps; got=%+v", gaps)
+	}
+	if _, ok, _ := store.Gaps("unknown"); ok {
+		t.Errorf("expected no gaps for unknown channel")
+	}
+}
+
+func TestStrict_BufferedApplyErrorIsRecordedNotReturned(t *testing.T) {
+	outcomes := map[int]domain.Outcome{}
+	store := persistence.NewMemoryStore(
+		persistence.WithStrictOrdering(10, time.Hour),
+		persistence.WithResultListener(func(env domain.MessageEnvelope, res domain.MessageResult) {
+			outcomes[env.Metadata.MessageNum] = res.Outcome
+		}),
+	)
+	ch := "c1"
+
+	bad := makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, nil)
+	bad.Message = []byte("{")
+	mustApply(t, store, bad)
+
+	// El error del 2 no es del 1: quien desbloquea el canal no lo recibe.
+	mustApply(t, store, makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+
+	if outcomes[2] != domain.OutcomeRejected {
+		t.Errorf("buffered bad payload should be recorded as rejected; got=%q", outcomes[2])
+	}
+	gaps, _, _ := store.Gaps(ch)
+	if gaps.NextExpected != 3 || len(gaps.Buffered) != 0 {
+		t.Errorf("unexpected gaps; got=%+v", gaps)
+	}
+}
+
+func TestStrict_ExpireGapsTerminatesOnBadBufferedPayload(t *testing.T) {
+	store := persistence.NewMemoryStore(persistence.WithStrictOrdering(10, time.Millisecond))
+	ch := "c1"
+
+	mustApply(t, store, makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+	bad := makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, nil)
+	bad.Message = []byte("{")
+	mustApply(t, store, bad)
+
+	time.Sleep(5 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		store.ExpireGaps()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ExpireGaps did not return")
+	}
+
+	gaps, _, _ := store.Gaps(ch)
+	if gaps.NextExpected != 4 || len(gaps.Buffered) != 0 || len(gaps.Missing) != 1 || gaps.Missing[0] != 2 {
+		t.Errorf("unexpected gaps; got=%+v", gaps)
+	}
+}
+
+func TestStrict_ExpectedApplyErrorAdvancesTheChannel(t *testing.T) {
+	outcomes := map[int]domain.Outcome{}
+	store := persistence.NewMemoryStore(
+		persistence.WithStrictOrdering(10, time.Hour),
+		persistence.WithResultListener(func(env domain.MessageEnvelope, res domain.MessageResult) {
+			outcomes[env.Metadata.MessageNum] = res.Outcome
+		}),
+	)
+	ch := "c1"
+
+	mustApply(t, store, makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+		domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}))
+	mustApply(t, store, makeEnv(ch, 3, "2022-02-02T19:39:07Z",
+		domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}))
+
+	// El 2 es el esperado y no se puede aplicar: su error es de quien lo manda.
+	bad := makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeSpeedIncreased, nil)
+	bad.Message = []byte("{")
+	if err := store.Apply(bad); err == nil {
+		t.Fatalf("expected the apply error for message 2")
+	}
+
+	if outcomes[2] != domain.OutcomeRejected {
+		t.Errorf("expected message 2 recorded as rejected; got=%q", outcomes[2])
+	}
+	gaps, _, _ := store.Gaps(ch)
+	if gaps.NextExpected != 4 || len(gaps.Buffered) != 0 || len(gaps.Missing) != 0 {
+		t.Errorf("channel should move past message 2 without a gap; got=%+v", gaps)
+	}
+	if got, _, _ := store.Get(ch); got.Speed != 800 {
+		t.Errorf("buffered message 3 not applied; got=%+v", got)
+	}
+}
+
+// ---------- helpers ----------
+
+func mustApply(t *testing.T, store *persistence.MemoryStore, env domain.MessageEnvelope) {
+	t.Helper()
+	if err := store.Apply(env); err != nil {
+		t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+	}
+}
@@ -0,0 +1,283 @@
+package persistence
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"lunar/src/domain"
+)
+
+// Un snapshot es un único registro con el mismo formato que los del log,
+// guardado en %020d.snap, donde el número es el offset del primer registro
+// del log que NO cubre.
+const (
+	snapshotExt     = ".snap"
+	snapshotsToKeep = 2
+)
+
+var ErrCorruptSnapshot = errors.New("corrupt snapshot")
+
+type snapshot struct {
+	Offset uint64      `json:"offset"`
+	State  memoryState `json:"state"`
+}
+
+// memoryState es todo lo que hace falta para reconstruir un MemoryStore: las
+// proyecciones con sus messageNumbers procesados, el histórico retenido de
+// cada canal (lo usan /events y asOf) y, en modo estricto, los buffers de
+// reordenación. Events es el formato antiguo, con el histórico completo; sólo
+// se lee.
+type memoryState struct {
+	Rockets  []rocketState                   `json:"rockets"`
+	History  map[string]historyState         `json:"history,omitempty"`
+	Events   map[string][]domain.RocketEvent `json:"events,omitempty"`
+	Ordering map[string]orderState           `json:"ordering,omitempty"`
+}
+
+type historyState struct {
+	Events      []domain.RocketEvent `json:"events"`
+	Seq         int                  `json:"seq"`
+	Base        *rocketState         `json:"base,omitempty"`
+	DroppedNum  int                  `json:"droppedNum,omitempty"`
+	DroppedTime time.Time            `json:"droppedTime,omitzero"`
+	Untimed     bool                 `json:"untimed,omitempty"`
+}
+
+type rocketState struct {
+	domain.Rocket
+	Processed domain.MessageSet `json:"processed"`
+}
+
+type orderState struct {
+	Next         int                      `json:"next"`
+	Pending      []domain.MessageEnvelope `json:"pending,omitempty"`
+	WaitingSince time.Time                `json:"waitingSince"`
+	Missing      []int                    `json:"missing,omitempty"`
+}
+
+type snapshotFile struct {
+	offset uint64
+	path   string
+}
+
+// exportState toma una foto del store. Los cohetes publicados, los eventos
+// del histórico y su base no cambian nunca, así que se comparten en lugar de
+// copiarse y la foto es barata; sólo se copian los buffers de reordenación.
+// Cada shard se recorre bajo su lock; quien necesite una foto coherente entre
+// canales (FileStore) debe impedir Apply mientras tanto.
+func (s *MemoryStore) exportState() memoryState {
+	st := memoryState{
+		History:  make(map[string]historyState),
+		Ordering: make(map[string]orderState),
+	}
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		for _, r := range sh.rockets {
+			st.Rockets = append(st.Rockets, rocketState{Rocket: *r, Processed: r.Processed})
+		}
+		for ch, h := range sh.history {
+			hs := historyState{
+				Events:      h.events[:len(h.events):len(h.events)],
+				Seq:         h.seq,
+				DroppedNum:  h.droppedNum,
+				DroppedTime: h.droppedTime,
+				Untimed:     h.untimed,
+			}
+			if h.base != nil {
+				hs.Base = &rocketState{Rocket: *h.base, Processed: h.base.Processed}
+			}
+			st.History[ch] = hs
+		}
+		for ch, co := range sh.ordering {
+			ord := orderState{Next: co.next, WaitingSince: co.waitingSince}
+			for _, env := range co.pending {
+				ord.Pending = append(ord.Pending, env)
+			}
+			sort.Slice(ord.Pending, func(i, j int) bool {
+				return ord.Pending[i].Metadata.MessageNum < ord.Pending[j].Metadata.MessageNum
+			})
+			for n := range co.missing {
+				ord.Missing = append(ord.Missing, n)
+			}
+			sort.Ints(ord.Missing)
+			st.Ordering[ch] = ord
+		}
+		sh.mu.RUnlock()
+	}
+	return st
+}
+
+// restoreState sustituye el estado del store por st, repartiendo cada canal
+// en su shard. Toma todos los locks, siempre en el mismo orden.
+func (s *MemoryStore) restoreState(st memoryState) {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		defer sh.mu.Unlock()
+		sh.reset()
+	}
+	s.indexes.mu.Lock()
+	s.indexes.reset()
+	s.indexes.mu.Unlock()
+
+	for _, rs := range st.Rockets {
+		r := rs.Rocket
+		r.Processed = rs.Processed
+		s.shardFor(r.Channel).publish(&r)
+	}
+	for ch, evs := range st.Events {
+		s.shardFor(ch).history[ch] = &channelHistory{events: evs, seq: len(evs)}
+	}
+	for ch, hs := range st.History {
+		h := &channelHistory{
+			events:      hs.Events,
+			seq:         hs.Seq,
+			droppedNum:  hs.DroppedNum,
+			droppedTime: hs.DroppedTime,
+			untimed:     hs.Untimed,
+		}
+		if hs.Base != nil {
+			r := hs.Base.Rocket
+			r.Processed = hs.Base.Processed
+			h.base = &r
+		}
+		s.shardFor(ch).history[ch] = h
+	}
+	for ch, ord := range st.Ordering {
+		co := newChannelOrder()
+		co.next = ord.Next
+		co.waitingSince = ord.WaitingSince
+		for _, env := range ord.Pending {
+			co.pending[env.Metadata.MessageNum] = env
+		}
+		for _, n := range ord.Missing {
+			co.missing[n] = struct{}{}
+		}
+		s.shardFor(ch).ordering[ch] = co
+	}
+}
+
+// writeSnapshot escribe el snapshot en un temporal y lo renombra, de modo que
+// un crash nunca deja a medias un .snap.
+func writeSnapshot(dir string, snap snapshot) error {
+	payload, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%020d%s", snap.Offset, snapshotExt))
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(encodeRecord(payload)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// loadLatestSnapshot devuelve el snapshot válido más reciente. Los que no se
+// pueden leer se saltan: el log anterior sigue ahí mientras haya un snapshot
+// más antiguo que lo cubra.
+func loadLatestSnapshot(dir string) (snapshot, bool, error) {
+	files, err := listSnapshots(dir)
+	if err != nil {
+		return snapshot{}, false, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		snap, err := readSnapshot(files[i])
+		if err == nil {
+			return snap, true, nil
+		}
+		if !errors.Is(err, ErrCorruptSnapshot) {
+			return snapshot{}, false, err
+		}
+	}
+	return snapshot{}, false, nil
+}
+
+func readSnapshot(file snapshotFile) (snapshot, error) {
+	raw, err := os.ReadFile(file.path)
+	if err != nil {
+		return snapshot{}, err
+	}
+	if len(raw) < headerSize {
+		return snapshot{}, fmt.Errorf("%w: %s: truncated header", ErrCorruptSnapshot, file.path)
+	}
+	size := binary.BigEndian.Uint32(raw[0:4])
+	sum := binary.BigEndian.Uint32(raw[4:8])
+	payload := raw[headerSize:]
+	if uint32(len(payload)) != size || crc32.Checksum(payload, crcTable) != sum {
+		return snapshot{}, fmt.Errorf("%w: %s: crc mismatch", ErrCorruptSnapshot, file.path)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(payload, &snap); err != nil {
+		return snapshot{}, fmt.Errorf("%w: %s: %v", ErrCorruptSnapshot, file.path, err)
+	}
+	if snap.Offset != file.offset {
+		return snapshot{}, fmt.Errorf("%w: %s: covers %d", ErrCorruptSnapshot, file.path, snap.Offset)
+	}
+	return snap, nil
+}
+
+// pruneSnapshots deja sólo los keep más recientes y devuelve el offset del
+// más antiguo que queda, hasta donde se puede compactar el log.
+func pruneSnapshots(dir string, keep int) (uint64, error) {
+	files, err := listSnapshots(dir)
+	if err != nil || len(files) == 0 {
+		return 0, err
+	}
+	if len(files) > keep {
+		for _, f := range files[:len(files)-keep] {
+			if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return 0, err
+			}
+		}
+		files = files[len(files)-keep:]
+	}
+	return files[0].offset, nil
+}
+
+func listSnapshots(dir string) ([]snapshotFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []snapshotFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		offset, err := strconv.ParseUint(strings.TrimSuffix(name, snapshotExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, snapshotFile{offset: offset, path: filepath.Join(dir, name)})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].offset < files[j].offset })
+	return files, nil
+}
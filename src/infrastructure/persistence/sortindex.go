@@ -0,0 +1,267 @@
+package persistence
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"lunar/src/domain"
+)
+
+// indexedKeys son las claves de ordenación que cada shard mantiene al día en
+// Apply. El resto se ordena al vuelo en List.
+var indexedKeys = []string{"channel", "speed", "updated_at"}
+
+// rocketLess ordena por sortBy y, a igualdad, por canal; así el orden es total
+// y coincide con el ORDER BY de los adaptadores SQL. Una clave desconocida
+// ordena por canal.
+func rocketLess(sortBy string) func(a, b *domain.Rocket) bool {
+	var byKey func(a, b *domain.Rocket) int
+	switch sortBy {
+	case "speed":
+		byKey = func(a, b *domain.Rocket) int { return cmpInt(a.Speed, b.Speed) }
+	case "updated_at":
+		byKey = func(a, b *domain.Rocket) int { return a.UpdatedAt.Compare(b.UpdatedAt) }
+	case "event_time":
+		byKey = func(a, b *domain.Rocket) int { return a.EventTime.Compare(b.EventTime) }
+	case "ingested_at":
+		byKey = func(a, b *domain.Rocket) int { return a.IngestedAt.Compare(b.IngestedAt) }
+	case "altitude":
+		byKey = func(a, b *domain.Rocket) int { return cmpInt(a.Altitude, b.Altitude) }
+	case "fuel_level":
+		byKey = func(a, b *domain.Rocket) int { return cmpInt(a.FuelLevel, b.FuelLevel) }
+	case "stages_separated":
+		byKey = func(a, b *domain.Rocket) int { return cmpInt(a.StagesSeparated, b.StagesSeparated) }
+	default:
+		return func(a, b *domain.Rocket) bool { return a.Channel < b.Channel }
+	}
+	return func(a, b *domain.Rocket) bool {
+		if c := byKey(a, b); c != 0 {
+			return c < 0
+		}
+		return a.Channel < b.Channel
+	}
+}
+
+func cmpInt[T ~int | ~int64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// sortIndexes agrupa los índices de todas las claves de indexedKeys y los
+// contadores de la flota. Tiene su propio lock, que los shards toman para
+// volcar los cambios que han ido acumulando (ver shard.publish), List para
+// recorrer la página pedida y Stats para leer los agregados.
+type sortIndexes struct {
+	mu     sync.RWMutex
+	byKey  map[string]*sortIndex
+	counts fleetCounts
+}
+
+func newSortIndexes() *sortIndexes {
+	x := &sortIndexes{}
+	x.reset()
+	return x
+}
+
+// reset vacía los índices. Debe llamarse con mu tomado.
+func (x *sortIndexes) reset() {
+	x.byKey = make(map[string]*sortIndex, len(indexedKeys))
+	for _, key := range indexedKeys {
+		x.byKey[key] = &sortIndex{less: rocketLess(key)}
+	}
+	x.counts = newFleetCounts()
+}
+
+// replace sustituye old por r en todos los índices. Debe llamarse con mu
+// tomado.
+func (x *sortIndexes) replace(old, r *domain.Rocket) {
+	for _, ix := range x.byKey {
+		ix.replace(old, r)
+	}
+	if old != nil {
+		x.counts.add(old, -1)
+	}
+	x.counts.add(r, 1)
+}
+
+// sortIndex es un treap con los cohetes publicados. Guarda los punteros tal
+// cual: fold nunca modifica un cohete ya publicado, así que la clave de cada
+// nodo no cambia mientras está en el árbol. Cada nodo sabe cuántos cuelgan
+// de él, para buscar por posición (nth, rank) en O(log n).
+type sortIndex struct {
+	less func(a, b *domain.Rocket) bool
+	root *treapNode
+}
+
+type treapNode struct {
+	rocket      *domain.Rocket
+	prio        uint32
+	size        int
+	left, right *treapNode
+}
+
+func size(n *treapNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *treapNode) resize() {
+	n.size = 1 + size(n.left) + size(n.right)
+}
+
+func (ix *sortIndex) len() int {
+	return size(ix.root)
+}
+
+// replace quita old (si no es nil) y mete r, reaprovechando el nodo. Debe
+// llamarse con el mu de sortIndexes tomado.
+func (ix *sortIndex) replace(old, r *domain.Rocket) {
+	if old != nil && !ix.less(old, r) && !ix.less(r, old) {
+		// Misma clave: el nodo se queda donde está.
+		for n := ix.root; n != nil; {
+			if n.rocket == old {
+				n.rocket = r
+				return
+			}
+			if ix.less(old, n.rocket) {
+				n = n.left
+			} else {
+				n = n.right
+			}
+		}
+	}
+	var node *treapNode
+	if old != nil {
+		lower, rest := ix.split(ix.root, old, false)
+		node, rest = ix.split(rest, old, true)
+		ix.root = merge(lower, rest)
+	}
+	if node == nil {
+		node = &treapNode{prio: rand.Uint32()}
+	}
+	node.rocket, node.left, node.right, node.size = r, nil, nil, 1
+	lower, upper := ix.split(ix.root, r, false)
+	ix.root = merge(merge(lower, node), upper)
+}
+
+// split separa los nodos menores que pivot (o iguales, con orEqual) del resto.
+func (ix *sortIndex) split(n *treapNode, pivot *domain.Rocket, orEqual bool) (*treapNode, *treapNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if ix.less(n.rocket, pivot) || orEqual && !ix.less(pivot, n.rocket) {
+		l, r := ix.split(n.right, pivot, orEqual)
+		n.right = l
+		n.resize()
+		return n, r
+	}
+	l, r := ix.split(n.left, pivot, orEqual)
+	n.left = r
+	n.resize()
+	return l, n
+}
+
+// merge une dos treaps en los que todo a va antes que todo b.
+func merge(a, b *treapNode) *treapNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.prio > b.prio:
+		a.right = merge(a.right, b)
+		a.resize()
+		return a
+	}
+	b.left = merge(a, b.left)
+	b.resize()
+	return b
+}
+
+// nth devuelve el cohete en la posición k (desde 0) en orden ascendente.
+func (ix *sortIndex) nth(k int) *domain.Rocket {
+	for n := ix.root; n != nil; {
+		switch left := size(n.left); {
+		case k < left:
+			n = n.left
+		case k == left:
+			return n.rocket
+		default:
+			k -= left + 1
+			n = n.right
+		}
+	}
+	return nil
+}
+
+// rank cuenta los cohetes anteriores al primero para el que from es cierto.
+// from tiene que ser monótona en orden ascendente, como en seek.
+func (ix *sortIndex) rank(from func(*domain.Rocket) bool) int {
+	count := 0
+	for n := ix.root; n != nil; {
+		if from(n.rocket) {
+			n = n.left
+		} else {
+			count += size(n.left) + 1
+			n = n.right
+		}
+	}
+	return count
+}
+
+// indexIter recorre un sortIndex en orden (o al revés con desc) con una pila
+// explícita, para poder parar en cualquier punto.
+type indexIter struct {
+	stack []*treapNode
+	desc  bool
+}
+
+// seek coloca el iterador en el primer cohete para el que from es cierto.
+// from tiene que ser monótona en el sentido del recorrido (falsa y luego
+// cierta); nil empieza por el principio.
+func (ix *sortIndex) seek(desc bool, from func(*domain.Rocket) bool) *indexIter {
+	it := &indexIter{desc: desc}
+	for n := ix.root; n != nil; {
+		if from == nil || from(n.rocket) {
+			it.stack = append(it.stack, n)
+			n = it.toward(n)
+		} else {
+			n = it.away(n)
+		}
+	}
+	return it
+}
+
+func (it *indexIter) next() (*domain.Rocket, bool) {
+	if len(it.stack) == 0 {
+		return nil, false
+	}
+	n := it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+	for c := it.away(n); c != nil; c = it.toward(c) {
+		it.stack = append(it.stack, c)
+	}
+	return n.rocket, true
+}
+
+// toward baja hacia los nodos que se visitan antes; away, hacia los de después.
+func (it *indexIter) toward(n *treapNode) *treapNode {
+	if it.desc {
+		return n.right
+	}
+	return n.left
+}
+
+func (it *indexIter) away(n *treapNode) *treapNode {
+	if it.desc {
+		return n.left
+	}
+	return n.right
+}
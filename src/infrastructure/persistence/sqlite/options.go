@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"time"
+
+	"lunar/src/domain"
+)
+
+type options struct {
+	eventTime bool
+	now       func() time.Time
+	onChange  func(domain.Rocket)
+	onResult  func(domain.MessageEnvelope, domain.MessageResult)
+}
+
+type Option func(*options)
+
+// WithEventTime hace que UpdatedAt sea el messageTime del último mensaje
+// aplicado, igual que persistence.WithEventTime.
+func WithEventTime() Option {
+	return func(o *options) {
+		o.eventTime = true
+	}
+}
+
+// WithClock sustituye time.Now; útil en tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		if now != nil {
+			o.now = now
+		}
+	}
+}
+
+// WithChangeListener hace que Apply llame a fn con la nueva versión del
+// cohete cada vez que un envelope lo cambia, después de confirmar la
+// transacción. fn no debe bloquear.
+func WithChangeListener(fn func(domain.Rocket)) Option {
+	return func(o *options) {
+		o.onChange = fn
+	}
+}
+
+// WithResultListener hace que Apply llame a fn con lo que hizo con cada
+// envelope, después de confirmar la transacción. fn no debe bloquear.
+func WithResultListener(fn func(domain.MessageEnvelope, domain.MessageResult)) Option {
+	return func(o *options) {
+		o.onResult = fn
+	}
+}
+
+func buildOptions(opts []Option) options {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
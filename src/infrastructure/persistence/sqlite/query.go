@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"lunar/src/domain"
+)
+
+// where acumula las condiciones del WHERE de List con sus argumentos.
+type where struct {
+	conds []string
+	args  []any
+}
+
+func (w *where) add(cond string, args ...any) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(w.conds, ` AND `)
+}
+
+// filterWhere traduce el filtro a condiciones sobre las columnas de rockets.
+func filterWhere(f domain.RocketFilter) *where {
+	w := &where{}
+	if f.Status != "" {
+		w.add(`status = ?`, string(f.Status))
+	}
+	if f.Type != "" {
+		w.add(`type = ?`, f.Type)
+	}
+	if f.Mission != "" {
+		w.add(`mission = ?`, f.Mission)
+	}
+	if f.MissionPrefix != "" {
+		// LIKE no distingue mayúsculas en SQLite; substr sí.
+		w.add(`substr(mission, 1, ?) = ?`, utf8.RuneCountInString(f.MissionPrefix), f.MissionPrefix)
+	}
+	if f.Speed.Min != nil {
+		w.add(`speed >= ?`, *f.Speed.Min)
+	}
+	if f.Speed.Max != nil {
+		w.add(`speed <= ?`, *f.Speed.Max)
+	}
+	timeRange(w, "updated_at", f.UpdatedAt)
+	timeRange(w, "event_time", f.EventTime)
+	timeRange(w, "ingested_at", f.IngestedAt)
+	return w
+}
+
+func timeRange(w *where, col string, r domain.TimeRange) {
+	if !r.From.IsZero() {
+		w.add(col+` >= ?`, boundNanos(r.From))
+	}
+	if !r.To.IsZero() {
+		w.add(col+` <= ?`, boundNanos(r.To))
+	}
+}
+
+// boundNanos es toNanos para los extremos de un rango, que pueden caer fuera
+// de lo que cabe en nanosegundos Unix (años 1678 a 2262).
+func boundNanos(t time.Time) int64 {
+	switch {
+	case t.Before(time.Unix(0, math.MinInt64)):
+		return math.MinInt64
+	case t.After(time.Unix(0, math.MaxInt64)):
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
+
+// sortArg adapta el valor de la clave de ordenación al tipo de la columna.
+func sortArg(v any) any {
+	if t, ok := v.(time.Time); ok {
+		return toNanos(t)
+	}
+	return v
+}
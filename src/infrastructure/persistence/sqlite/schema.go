@@ -0,0 +1,52 @@
+package sqlite
+
+// Los instantes se guardan como nanosegundos Unix (0 = sin valor) para que
+// ORDER BY los compare bien sea cual sea su zona horaria.
+const schema = `
+CREATE TABLE IF NOT EXISTS rockets (
+	channel          TEXT    PRIMARY KEY,
+	type             TEXT    NOT NULL DEFAULT '',
+	mission          TEXT    NOT NULL DEFAULT '',
+	speed            INTEGER NOT NULL DEFAULT 0,
+	status           TEXT    NOT NULL,
+	altitude         INTEGER NOT NULL DEFAULT 0,
+	fuel_level       INTEGER NOT NULL DEFAULT 0,
+	stages_separated INTEGER NOT NULL DEFAULT 0,
+	last_msg_num     INTEGER NOT NULL DEFAULT 0,
+	updated_at       INTEGER NOT NULL DEFAULT 0,
+	event_time       INTEGER NOT NULL DEFAULT 0,
+	ingested_at      INTEGER NOT NULL DEFAULT 0,
+	last_rejection   TEXT
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	channel        TEXT    NOT NULL,
+	sequence       INTEGER NOT NULL,
+	message_number INTEGER NOT NULL,
+	message_type   TEXT    NOT NULL,
+	message_time   TEXT    NOT NULL,
+	payload        BLOB,
+	outcome        TEXT    NOT NULL,
+	reason         TEXT    NOT NULL DEFAULT '',
+	received_at    INTEGER NOT NULL,
+	PRIMARY KEY (channel, sequence)
+);
+
+CREATE TABLE IF NOT EXISTS processed_messages (
+	channel        TEXT    NOT NULL,
+	message_number INTEGER NOT NULL,
+	PRIMARY KEY (channel, message_number)
+);
+`
+
+// sortColumns traduce las claves de ordenación de la API a columnas.
+var sortColumns = map[string]string{
+	"channel":          "channel",
+	"speed":            "speed",
+	"updated_at":       "updated_at",
+	"event_time":       "event_time",
+	"ingested_at":      "ingested_at",
+	"altitude":         "altitude",
+	"fuel_level":       "fuel_level",
+	"stages_separated": "stages_separated",
+}
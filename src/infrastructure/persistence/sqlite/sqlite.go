@@ -0,0 +1,362 @@
+// Package sqlite implementa la persistencia sobre SQLite embebido (driver
+// modernc.org/sqlite, sin cgo), pensado para despliegues pequeños sin una
+// base de datos aparte.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"lunar/src/domain"
+
+	_ "modernc.org/sqlite"
+)
+
+type Store struct {
+	db   *sql.DB
+	opts options
+}
+
+// NewStore abre (o crea) la base de datos en path y aplica el esquema.
+func NewStore(path string, opts ...Option) (*Store, error) {
+	dsn := path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite admite un único escritor; con una sola conexión las
+	// transacciones se serializan aquí en vez de fallar con SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("apply sqlite schema: %w", err)
+	}
+	return &Store{db: db, opts: buildOptions(opts)}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Apply carga el cohete, le aplica el envelope con las reglas del dominio y
+// guarda cohete, messageNumber procesado y evento en la misma transacción.
+func (s *Store) Apply(env domain.MessageEnvelope) error {
+	ch, num := env.Metadata.Channel, env.Metadata.MessageNum
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	r, ok, err := getRocket(tx, ch)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r = *domain.NewRocket(ch)
+	}
+	// Rocket.Apply sólo consulta y marca num; basta con cargar ese.
+	var seen bool
+	err = tx.QueryRow(`SELECT 1 FROM processed_messages WHERE channel = ? AND message_number = ?`, ch, num).Scan(new(int))
+	switch {
+	case err == nil:
+		seen = true
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+	if seen {
+		r.Processed.Add(num)
+	}
+
+	now := s.opts.now()
+	outcome, err := r.Apply(env)
+	if err != nil {
+		return err
+	}
+
+	var reason string
+	switch outcome {
+	case domain.OutcomeApplied:
+		r.IngestedAt = now
+		r.UpdatedAt = now
+		if s.opts.eventTime {
+			r.UpdatedAt = r.EventTime
+		}
+	case domain.OutcomeRejected:
+		reason = r.LastRejection.Reason
+	}
+
+	if outcome != domain.OutcomeDuplicate {
+		if _, err := tx.Exec(`INSERT INTO processed_messages (channel, message_number) VALUES (?, ?)`, ch, num); err != nil {
+			return err
+		}
+		if err := putRocket(tx, r); err != nil {
+			return err
+		}
+	}
+	if err := insertEvent(tx, env, outcome, reason, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if outcome.ChangesRocket() && s.opts.onChange != nil {
+		s.opts.onChange(r)
+	}
+	if s.opts.onResult != nil {
+		s.opts.onResult(env, domain.MessageResult{Outcome: outcome, Reason: reason, Rocket: r})
+	}
+	return nil
+}
+
+func (s *Store) Get(channel string) (domain.Rocket, bool, error) {
+	return getRocket(s.db, channel)
+}
+
+// List delega el filtro, la ordenación y el cursor en SQL; a igualdad
+// desempata por canal. ORDER BY no admite parámetros, así que la columna sale
+// siempre de sortColumns.
+func (s *Store) List(q domain.RocketQuery) (domain.Page, error) {
+	req := q.PageRequest
+	col, ok := sortColumns[req.SortBy]
+	if !ok {
+		col = "channel"
+	}
+	dir, cmp := "ASC", ">"
+	if req.Descending() {
+		dir, cmp = "DESC", "<"
+	}
+	w := filterWhere(q.Filter)
+	if req.Cursor != nil {
+		pivot, err := req.Cursor.Pivot()
+		if err != nil {
+			return domain.Page{}, err
+		}
+		w.add(`(`+col+`, channel) `+cmp+` (?, ?)`, sortArg(pivot.SortValue(req.SortBy)), pivot.Channel)
+	}
+	query, args := selectRocket+w.String()+` ORDER BY `+col+` `+dir+`, channel `+dir, w.args
+	if req.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, req.Limit+1)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return domain.Page{}, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.Rocket, 0)
+	for rows.Next() {
+		r, err := scanRocket(rows)
+		if err != nil {
+			return domain.Page{}, err
+		}
+		items = append(items, r)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Page{}, err
+	}
+	return domain.NewPage(req, items), nil
+}
+
+func (s *Store) Events(channel string) ([]domain.RocketEvent, bool, error) {
+	items, err := queryEvents(s.db, channel, "message_number, sequence")
+	if err != nil || len(items) == 0 {
+		return nil, false, err
+	}
+	return items, true, nil
+}
+
+// GetAsOf reconstruye el cohete reaplicando, en orden de llegada, los
+// eventos guardados que caen dentro de point.
+func (s *Store) GetAsOf(channel string, point domain.PointInTime) (domain.Rocket, bool, error) {
+	recorded, err := queryEvents(s.db, channel, "sequence")
+	if err != nil {
+		return domain.Rocket{}, false, err
+	}
+	var r *domain.Rocket
+	for _, ev := range recorded {
+		if !point.Includes(ev) {
+			continue
+		}
+		if r == nil {
+			r = domain.NewRocket(channel)
+		}
+		// Lo que está en el histórico ya se pudo decodificar una vez.
+		if outcome, _ := r.Apply(ev.Envelope(channel)); outcome == domain.OutcomeApplied {
+			r.IngestedAt = ev.ReceivedAt
+			r.UpdatedAt = ev.ReceivedAt
+			if s.opts.eventTime {
+				r.UpdatedAt = r.EventTime
+			}
+		}
+	}
+	if r == nil {
+		return domain.Rocket{}, false, nil
+	}
+	return r.Clone(), true, nil
+}
+
+// Gaps informa de los messageNumbers que faltan por debajo del último
+// aplicado. Este store no tiene modo estricto, así que no hay buffer.
+func (s *Store) Gaps(channel string) (domain.OrderingGaps, bool, error) {
+	r, ok, err := s.Get(channel)
+	if err != nil || !ok {
+		return domain.OrderingGaps{}, false, err
+	}
+	rows, err := s.db.Query(`SELECT message_number FROM processed_messages WHERE channel = ? ORDER BY message_number`, channel)
+	if err != nil {
+		return domain.OrderingGaps{}, false, err
+	}
+	defer rows.Close()
+	var processed domain.MessageSet
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return domain.OrderingGaps{}, false, err
+		}
+		processed.Add(n)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.OrderingGaps{}, false, err
+	}
+	return domain.OrderingGaps{
+		Channel:      channel,
+		NextExpected: r.LastMsgNum + 1,
+		Missing:      processed.Missing(r.LastMsgNum),
+		Buffered:     []int{},
+	}, true, nil
+}
+
+// ---------- filas ----------
+
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+const selectRocket = `SELECT channel, type, mission, speed, status, altitude, fuel_level,
+	stages_separated, last_msg_num, updated_at, event_time, ingested_at, last_rejection
+	FROM rockets`
+
+func getRocket(q queryer, channel string) (domain.Rocket, bool, error) {
+	r, err := scanRocket(q.QueryRow(selectRocket+` WHERE channel = ?`, channel))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Rocket{}, false, nil
+	}
+	if err != nil {
+		return domain.Rocket{}, false, err
+	}
+	return r, true, nil
+}
+
+func scanRocket(sc scanner) (domain.Rocket, error) {
+	var (
+		r                                domain.Rocket
+		status                           string
+		updatedAt, eventTime, ingestedAt int64
+		rejection                        sql.NullString
+	)
+	err := sc.Scan(&r.Channel, &r.Type, &r.Mission, &r.Speed, &status, &r.Altitude, &r.FuelLevel,
+		&r.StagesSeparated, &r.LastMsgNum, &updatedAt, &eventTime, &ingestedAt, &rejection)
+	if err != nil {
+		return domain.Rocket{}, err
+	}
+	r.Status = domain.RocketStatus(status)
+	r.UpdatedAt = fromNanos(updatedAt)
+	r.EventTime = fromNanos(eventTime)
+	r.IngestedAt = fromNanos(ingestedAt)
+	if rejection.Valid {
+		r.LastRejection = &domain.Rejection{}
+		if err := json.Unmarshal([]byte(rejection.String), r.LastRejection); err != nil {
+			return domain.Rocket{}, err
+		}
+	}
+	return r, nil
+}
+
+func putRocket(tx *sql.Tx, r domain.Rocket) error {
+	var rejection sql.NullString
+	if r.LastRejection != nil {
+		raw, err := json.Marshal(r.LastRejection)
+		if err != nil {
+			return err
+		}
+		rejection = sql.NullString{String: string(raw), Valid: true}
+	}
+	_, err := tx.Exec(`INSERT INTO rockets (channel, type, mission, speed, status, altitude, fuel_level,
+			stages_separated, last_msg_num, updated_at, event_time, ingested_at, last_rejection)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (channel) DO UPDATE SET
+			type = excluded.type, mission = excluded.mission, speed = excluded.speed,
+			status = excluded.status, altitude = excluded.altitude, fuel_level = excluded.fuel_level,
+			stages_separated = excluded.stages_separated, last_msg_num = excluded.last_msg_num,
+			updated_at = excluded.updated_at, event_time = excluded.event_time,
+			ingested_at = excluded.ingested_at, last_rejection = excluded.last_rejection`,
+		r.Channel, r.Type, r.Mission, r.Speed, string(r.Status), r.Altitude, r.FuelLevel,
+		r.StagesSeparated, r.LastMsgNum, toNanos(r.UpdatedAt), toNanos(r.EventTime), toNanos(r.IngestedAt), rejection)
+	return err
+}
+
+func insertEvent(tx *sql.Tx, env domain.MessageEnvelope, outcome domain.Outcome, reason string, at time.Time) error {
+	_, err := tx.Exec(`INSERT INTO events (channel, sequence, message_number, message_type, message_time,
+			payload, outcome, reason, received_at)
+		SELECT ?, COALESCE(MAX(sequence), 0) + 1, ?, ?, ?, ?, ?, ?, ?
+		FROM events WHERE channel = ?`,
+		env.Metadata.Channel, env.Metadata.MessageNum, env.Metadata.MessageType, env.Metadata.MessageTime,
+		[]byte(env.Message), string(outcome), reason, toNanos(at), env.Metadata.Channel)
+	return err
+}
+
+func queryEvents(q queryer, channel, orderBy string) ([]domain.RocketEvent, error) {
+	rows, err := q.Query(`SELECT sequence, message_number, message_type, message_time, payload, outcome, reason, received_at
+		FROM events WHERE channel = ? ORDER BY `+orderBy, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.RocketEvent
+	for rows.Next() {
+		var (
+			ev         domain.RocketEvent
+			payload    []byte
+			outcome    string
+			receivedAt int64
+		)
+		if err := rows.Scan(&ev.Sequence, &ev.MessageNumber, &ev.MessageType, &ev.MessageTime,
+			&payload, &outcome, &ev.Reason, &receivedAt); err != nil {
+			return nil, err
+		}
+		if len(payload) > 0 {
+			ev.Payload = json.RawMessage(payload)
+		}
+		ev.Outcome = domain.Outcome(outcome)
+		ev.ReceivedAt = fromNanos(receivedAt)
+		items = append(items, ev)
+	}
+	return items, rows.Err()
+}
+
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromNanos(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
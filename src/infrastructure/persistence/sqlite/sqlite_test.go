@@ -0,0 +1,225 @@
+package sqlite_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+	"lunar/src/infrastructure/persistence/persistencetest"
+	"lunar/src/infrastructure/persistence/sqlite"
+)
+
+func TestStore_Conformance(t *testing.T) {
+	persistencetest.Run(t, func(t *testing.T) port.Persistence {
+		return openStore(t, filepath.Join(t.TempDir(), "rockets.db"))
+	})
+}
+
+func TestStore_DedupAndDeltasSurviveReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rockets.db")
+	ch := "c1"
+
+	store := openStore(t, path)
+	applyAll(t, store,
+		makeEnv(ch, 4, "2022-02-02T19:39:08Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 500}),
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedDecreased, domain.RocketSpeedDeltaPayload{By: 200}),
+	)
+	_ = store.Close()
+
+	reopened := openStore(t, path)
+	applyAll(t, reopened,
+		makeEnv(ch, 4, "2022-02-02T19:39:08Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 500}))
+
+	got, _, _ := reopened.Get(ch)
+	if got.Speed != 300 || got.LastMsgNum != 4 {
+		t.Errorf("dedup or deltas broken across reopen; got=%+v", got)
+	}
+	gaps, ok, _ := reopened.Gaps(ch)
+	if !ok || len(gaps.Missing) != 2 || gaps.Missing[0] != 2 || gaps.Missing[1] != 3 {
+		t.Errorf("gaps mismatch; got=%+v", gaps)
+	}
+}
+
+func TestStore_EventsRecordOutcomes(t *testing.T) {
+	store := openStore(t, filepath.Join(t.TempDir(), "rockets.db"))
+	ch := "c1"
+
+	applyAll(t, store,
+		makeEnv(ch, 3, "2022-02-02T19:39:08Z", domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "NEW"}),
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeMissionChanged, domain.RocketMissionChangedPayload{NewMission: "OLD"}),
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100}),
+	)
+
+	got, ok, err := store.Events(ch)
+	if err != nil || !ok {
+		t.Fatalf("events failed: %v ok=%v", err, ok)
+	}
+	want := []struct {
+		seq, num int
+		outcome  domain.Outcome
+	}{
+		{2, 1, domain.OutcomeApplied},
+		{4, 1, domain.OutcomeDuplicate},
+		{3, 2, domain.OutcomeStale},
+		{1, 3, domain.OutcomeApplied},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events len; got=%d want=%d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Sequence != w.seq || got[i].MessageNumber != w.num || got[i].Outcome != w.outcome {
+			t.Errorf("event %d; got=%+v want=%+v", i, got[i], w)
+		}
+	}
+	if _, ok, _ := store.Events("unknown"); ok {
+		t.Errorf("expected no events for unknown channel")
+	}
+}
+
+func TestStore_RejectionAndHistory(t *testing.T) {
+	store := openStore(t, filepath.Join(t.TempDir(), "rockets.db"))
+	ch := "c1"
+
+	applyAll(t, store,
+		makeEnv(ch, 1, "2022-02-02T19:39:05Z",
+			domain.TypeLaunched, domain.RocketLaunchedPayload{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}),
+		makeEnv(ch, 2, "2022-02-02T19:39:06Z", domain.TypeExploded, struct{}{}),
+		makeEnv(ch, 3, "2022-02-02T19:39:07Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 300}),
+	)
+
+	got, _, _ := store.Get(ch)
+	if got.Status != domain.StatusExploded || got.Speed != 500 {
+		t.Errorf("speed increase applied after explosion; got=%+v", got)
+	}
+	if got.LastRejection == nil || got.LastRejection.MessageNumber != 3 {
+		t.Errorf("last rejection not stored; got=%+v", got.LastRejection)
+	}
+
+	past, ok, err := store.GetAsOf(ch, domain.PointInTime{MessageNumber: 1})
+	if err != nil || !ok {
+		t.Fatalf("get as of failed: %v ok=%v", err, ok)
+	}
+	if past.Status != domain.StatusLaunched || past.Speed != 500 {
+		t.Errorf("as of msg 1 mismatch; got=%+v", past)
+	}
+}
+
+func TestStore_InvalidPayloadLeavesNoTrace(t *testing.T) {
+	store := openStore(t, filepath.Join(t.TempDir(), "rockets.db"))
+
+	env := makeEnv("c1", 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, nil)
+	env.Message = json.RawMessage(`{"by":`)
+	if err := store.Apply(env); err == nil {
+		t.Fatalf("expected error for malformed payload")
+	}
+	if _, ok, _ := store.Get("c1"); ok {
+		t.Errorf("rocket created by a failed apply")
+	}
+	if _, ok, _ := store.Events("c1"); ok {
+		t.Errorf("event recorded by a failed apply")
+	}
+}
+
+func TestStore_ChangeListenerSeesCommittedRocket(t *testing.T) {
+	var (
+		got     []domain.Rocket
+		results []domain.Outcome
+	)
+	store := openStore(t, filepath.Join(t.TempDir(), "rockets.db"),
+		sqlite.WithChangeListener(func(r domain.Rocket) { got = append(got, r) }),
+		sqlite.WithResultListener(func(_ domain.MessageEnvelope, res domain.MessageResult) {
+			results = append(results, res.Outcome)
+		}))
+	ch := "c1"
+
+	inc := makeEnv(ch, 1, "2022-02-02T19:39:05Z", domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: 100})
+	applyAll(t, store, inc, inc)
+
+	if len(got) != 1 {
+		t.Fatalf("expected one change for an applied message and its duplicate; got=%d", len(got))
+	}
+	if len(results) != 2 || results[0] != domain.OutcomeApplied || results[1] != domain.OutcomeDuplicate {
+		t.Errorf("expected a result for every message; got=%v", results)
+	}
+	if cur, _, _ := store.Get(ch); got[0].Speed != cur.Speed || got[0].LastMsgNum != cur.LastMsgNum {
+		t.Errorf("notified rocket differs from the stored one; got=%+v want=%+v", got[0], cur)
+	}
+}
+
+func TestStore_ListSortsInSQL(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2030-01-01T00:00:00Z")
+	store := openStore(t, filepath.Join(t.TempDir(), "rockets.db"),
+		sqlite.WithClock(func() time.Time { now = now.Add(time.Second); return now }))
+
+	for _, m := range []struct {
+		ch    string
+		speed int64
+		when  string
+	}{
+		{"a", 300, "2022-02-02T19:39:09Z"},
+		{"b", 100, "2022-02-02T19:39:05Z"},
+		{"c", 200, "2022-02-02T19:39:07Z"},
+	} {
+		applyAll(t, store, makeEnv(m.ch, 1, m.when, domain.TypeSpeedIncreased, domain.RocketSpeedDeltaPayload{By: m.speed}))
+	}
+
+	tests := []struct {
+		sortBy, order, want string
+	}{
+		{"channel", "desc", "cba"},
+		{"speed", "asc", "bca"},
+		{"speed", "desc", "acb"},
+		{"event_time", "asc", "bca"},
+		{"ingested_at", "desc", "cba"},
+		{"unknown", "asc", "abc"},
+	}
+	for _, tc := range tests {
+		page, err := store.List(domain.RocketQuery{PageRequest: domain.PageRequest{SortBy: tc.sortBy, Order: tc.order}})
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		var got string
+		for _, r := range page.Items {
+			got += r.Channel
+		}
+		if got != tc.want {
+			t.Errorf("%s %s; got=%s want=%s", tc.sortBy, tc.order, got, tc.want)
+		}
+	}
+}
+
+// ---------- helpers ----------
+
+func openStore(t *testing.T, path string, opts ...sqlite.Option) *sqlite.Store {
+	t.Helper()
+	store, err := sqlite.NewStore(path, opts...)
+	if err != nil {
+		t.Fatalf("open store failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func applyAll(t *testing.T, store *sqlite.Store, envs ...domain.MessageEnvelope) {
+	t.Helper()
+	for _, env := range envs {
+		if err := store.Apply(env); err != nil {
+			t.Fatalf("apply %d failed: %v", env.Metadata.MessageNum, err)
+		}
+	}
+}
+
+func makeEnv(channel string, num int, when string, kind string, payload any) domain.MessageEnvelope {
+	raw, _ := json.Marshal(payload)
+	env := domain.MessageEnvelope{}
+	env.Metadata.Channel = channel
+	env.Metadata.MessageNum = num
+	env.Metadata.MessageTime = when
+	env.Metadata.MessageType = kind
+	env.Message = raw
+	return env
+}
@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"time"
+
+	"lunar/src/domain"
+)
+
+// fleetCounts son los contadores que se mantienen al día al volcar un cohete
+// a los índices: se resta la versión vieja y se suma la nueva. Junto con los
+// índices de speed y updated_at bastan para Stats sin recorrer la flota.
+type fleetCounts struct {
+	total     int
+	speedSum  int64
+	byStatus  map[domain.RocketStatus]int
+	byType    map[string]int
+	byMission map[string]int
+}
+
+func newFleetCounts() fleetCounts {
+	return fleetCounts{
+		byStatus:  map[domain.RocketStatus]int{},
+		byType:    map[string]int{},
+		byMission: map[string]int{},
+	}
+}
+
+// add suma (delta 1) o resta (delta -1) el cohete r.
+func (c *fleetCounts) add(r *domain.Rocket, delta int) {
+	c.total += delta
+	c.speedSum += int64(delta) * r.Speed
+	bump(c.byStatus, r.Status, delta)
+	if r.Type != "" {
+		bump(c.byType, r.Type, delta)
+	}
+	if r.Mission != "" {
+		bump(c.byMission, r.Mission, delta)
+	}
+}
+
+// bump no deja claves a cero, para que no se acumulen tipos o misiones que
+// ya no tiene nadie.
+func bump[K comparable](m map[K]int, k K, delta int) {
+	if n := m[k] + delta; n != 0 {
+		m[k] = n
+	} else {
+		delete(m, k)
+	}
+}
+
+// Stats lee los agregados mantenidos por Apply: los contadores, los extremos
+// y percentiles del índice de speed y el rango del índice de updated_at. No
+// depende del tamaño de la flota salvo por copiar los mapas.
+func (s *MemoryStore) Stats(window time.Duration) (domain.FleetStats, error) {
+	cutoff := s.opts.now().Add(-window)
+	st := domain.NewFleetStats(window)
+
+	s.syncIndexes()
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+	c := &s.indexes.counts
+	st.Total = c.total
+	for k, n := range c.byStatus {
+		st.ByStatus[k] = n
+	}
+	for k, n := range c.byType {
+		st.ByType[k] = n
+	}
+	for k, n := range c.byMission {
+		st.ByMission[k] = n
+	}
+	if c.total > 0 {
+		speed := s.indexes.byKey["speed"]
+		st.Speed.Min = speed.nth(0).Speed
+		st.Speed.Max = speed.nth(c.total - 1).Speed
+		st.Speed.Mean = float64(c.speedSum) / float64(c.total)
+		st.Speed.SetPercentiles(c.total, func(k int) int64 { return speed.nth(k).Speed })
+	}
+	updated := s.indexes.byKey["updated_at"]
+	st.UpdatedRecently.Count = updated.len() - updated.rank(func(r *domain.Rocket) bool {
+		return !r.UpdatedAt.Before(cutoff)
+	})
+	return st, nil
+}
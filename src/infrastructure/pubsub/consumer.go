@@ -39,22 +39,47 @@ func (c *Consumer) Subscribe(ctx context.Context) error {
 	}
 	go func() {
 		for msg := range msgs {
-			var env domain.MessageEnvelope
-			if err = json.Unmarshal(msg.Payload, &env); err != nil {
-				// NACK: Watermill gochannel reentrega (o se pierde según config);
-				// aquí ACK para no bloquear; loguea el error real.
-				c.log.Error(err.Error())
-				msg.Ack()
-				continue
-			}
-			if err = c.applyUC.Execute(env); err != nil {
-				// Puedes Nack() si quieres reintentar; en gochannel no hay persistencia.
-				c.log.Error(err.Error())
-				msg.Ack()
-				continue
+			if msg.Metadata.Get(metaBatch) != "" {
+				c.applyBatch(msg)
+			} else {
+				c.applyOne(msg)
 			}
+			// NACK: Watermill gochannel reentrega (o se pierde según config);
+			// aquí siempre ACK para no bloquear; los errores se loguean.
 			msg.Ack()
 		}
 	}()
 	return nil
 }
+
+func (c *Consumer) applyOne(msg *message.Message) {
+	var env domain.MessageEnvelope
+	if err := json.Unmarshal(msg.Payload, &env); err != nil {
+		c.log.Error(err.Error())
+		return
+	}
+	env.ID = msg.UUID
+	c.apply(env)
+}
+
+// applyBatch aplica los envelopes del lote uno a uno y en orden; el error de
+// uno no impide aplicar los siguientes.
+func (c *Consumer) applyBatch(msg *message.Message) {
+	var entries []batchEntry
+	if err := json.Unmarshal(msg.Payload, &entries); err != nil {
+		c.log.Error(err.Error())
+		return
+	}
+	for _, e := range entries {
+		env := e.MessageEnvelope
+		env.ID = e.ID
+		c.apply(env)
+	}
+}
+
+func (c *Consumer) apply(env domain.MessageEnvelope) {
+	if err := c.applyUC.Execute(env); err != nil {
+		// Puedes Nack() si quieres reintentar; en gochannel no hay persistencia.
+		c.log.Error(err.Error())
+	}
+}
@@ -9,6 +9,19 @@ import (
 	"github.com/ThreeDotsLabs/watermill/message"
 )
 
+// metaBatch marca los mensajes que llevan un lote de envelopes.
+const metaBatch = "lunar_batch"
+
+// batchEntry es un envelope dentro de un lote. El ID, que en un mensaje
+// suelto viaja como UUID, aquí va junto a él.
+type batchEntry struct {
+	ID string `json:"id"`
+	domain.MessageEnvelope
+}
+
+// Producer publica cada envelope en un mensaje. gochannel entrega cada
+// mensaje desde su propia goroutine, así que entre mensajes distintos no hay
+// orden; lo que tiene que aplicarse en orden va en PublishBatch.
 type Producer struct {
 	pub message.Publisher
 }
@@ -22,6 +35,29 @@ func (p *Producer) Publish(topic string, env domain.MessageEnvelope) error {
 	if err != nil {
 		return err
 	}
+	// El ID del envelope no va en el JSON; viaja como UUID del mensaje.
+	return p.pub.Publish(topic, message.NewMessage(idOrNew(env.ID), payload))
+}
+
+// PublishBatch mete todos los envelopes en un mensaje, que el consumer
+// deshace aplicándolos en orden.
+func (p *Producer) PublishBatch(topic string, envs []domain.MessageEnvelope) error {
+	entries := make([]batchEntry, len(envs))
+	for i, env := range envs {
+		entries[i] = batchEntry{ID: idOrNew(env.ID), MessageEnvelope: env}
+	}
+	payload, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
 	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set(metaBatch, "true")
 	return p.pub.Publish(topic, msg)
 }
+
+func idOrNew(id string) string {
+	if id == "" {
+		return watermill.NewUUID()
+	}
+	return id
+}
@@ -0,0 +1,276 @@
+package pubsub_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"lunar/src/application"
+	"lunar/src/domain"
+	"lunar/src/infrastructure/persistence"
+	"lunar/src/infrastructure/pubsub"
+	"lunar/src/infrastructure/receipt"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"go.uber.org/zap"
+)
+
+const topic = "test.messages"
+
+func TestReceipts_FollowMessagesAcrossWatermill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receipts := receipt.NewTracker(16)
+	store := persistence.NewMemoryStore(persistence.WithResultListener(receipts.Record))
+	channel := newChannel()
+	defer channel.Close()
+
+	consumer := pubsub.NewConsumer(channel, zap.NewNop(), application.NewApplyMessageUC(store, receipts), topic)
+	if err := consumer.Subscribe(ctx); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	enqueue := application.NewEnqueueMessageUC(pubsub.NewProducer(channel), receipts, topic)
+
+	launch := makeEnv("c1", 1, domain.TypeLaunched, `{"type":"Falcon-9","launchSpeed":500,"mission":"ARTEMIS"}`)
+	tests := []struct {
+		env        domain.MessageEnvelope
+		wantStatus domain.ReceiptStatus
+		wantReason bool
+	}{
+		{launch, domain.ReceiptApplied, false},
+		{launch, domain.ReceiptDuplicate, false},
+		{makeEnv("c1", 2, domain.TypeExploded, `{}`), domain.ReceiptApplied, false},
+		{makeEnv("c1", 3, domain.TypeSpeedIncreased, `{"by":100}`), domain.ReceiptRejected, true},
+		// Un payload que no valida se rechaza en el consumer, antes del store.
+		{makeEnv("c2", 1, domain.TypeSpeedIncreased, `{"by":0}`), domain.ReceiptRejected, true},
+	}
+	for _, tc := range tests {
+		rc, err := enqueue.Execute(tc.env)
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		got := waitProcessed(t, receipts, rc.ID)
+		if got.Status != tc.wantStatus || (got.Reason != "") != tc.wantReason {
+			t.Errorf("%s #%d; got=%+v want=%s", tc.env.Metadata.Channel, tc.env.Metadata.MessageNum, got, tc.wantStatus)
+		}
+	}
+}
+
+func TestEnqueueSync_ReturnsTheRocketThisMessageProduced(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receipts := receipt.NewTracker(16)
+	store := persistence.NewMemoryStore(persistence.WithResultListener(receipts.Record))
+	channel := newChannel()
+	defer channel.Close()
+
+	consumer := pubsub.NewConsumer(channel, zap.NewNop(), application.NewApplyMessageUC(store, receipts), topic)
+	if err := consumer.Subscribe(ctx); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	enqueue := application.NewEnqueueMessageUC(pubsub.NewProducer(channel), receipts, topic)
+	enqueueSync := application.NewEnqueueMessageSyncUC(enqueue, receipts, receipts)
+
+	wait, stop := context.WithTimeout(ctx, 2*time.Second)
+	defer stop()
+	launch := makeEnv("c1", 1, domain.TypeLaunched, `{"type":"Falcon-9","launchSpeed":500,"mission":"ARTEMIS"}`)
+	for _, want := range []domain.Outcome{domain.OutcomeApplied, domain.OutcomeDuplicate} {
+		rc, res, err := enqueueSync.Execute(wait, launch)
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		if res.Outcome != want || res.Rocket.Speed != 500 || res.Rocket.LastMsgNum != 1 {
+			t.Errorf("result mismatch; got=%+v want=%s", res, want)
+		}
+		if got, _ := receipts.Get(rc.ID); got.Status != domain.ReceiptStatus(want) {
+			t.Errorf("receipt mismatch; got=%+v", got)
+		}
+	}
+}
+
+// blockingPublisher no vuelve de Publish hasta que se cierra release.
+type blockingPublisher struct {
+	message.Publisher
+	release chan struct{}
+}
+
+func (p blockingPublisher) Publish(topic string, msgs ...*message.Message) error {
+	<-p.release
+	return p.Publisher.Publish(topic, msgs...)
+}
+
+func TestEnqueueSync_TimeoutCoversABlockedPublish(t *testing.T) {
+	receipts := receipt.NewTracker(16)
+	channel := newChannel()
+	defer channel.Close()
+
+	pub := blockingPublisher{Publisher: channel, release: make(chan struct{})}
+	defer close(pub.release)
+	enqueue := application.NewEnqueueMessageUC(pubsub.NewProducer(pub), receipts, topic)
+	enqueueSync := application.NewEnqueueMessageSyncUC(enqueue, receipts, receipts)
+
+	wait, stop := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer stop()
+	start := time.Now()
+	rc, res, err := enqueueSync.Execute(wait, makeEnv("c1", 1, domain.TypeSpeedIncreased, `{"by":100}`))
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sync enqueue outlived its timeout; took %s", elapsed)
+	}
+	if res.Outcome != "" || rc.ID == "" || rc.Status != domain.ReceiptQueued || rc.MessageNumber != 1 {
+		t.Errorf("expected a queued receipt; got rc=%+v res=%+v", rc, res)
+	}
+}
+
+// stuckApply no vuelve hasta que se cierra release, como un consumer atascado.
+type stuckApply struct{ release chan struct{} }
+
+func (a stuckApply) Execute(domain.MessageEnvelope) error {
+	<-a.release
+	return nil
+}
+
+func TestEnqueue_DoesNotWaitForTheConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receipts := receipt.NewTracker(16)
+	channel := newChannel()
+	defer channel.Close()
+
+	apply := stuckApply{release: make(chan struct{})}
+	defer close(apply.release)
+	if err := pubsub.NewConsumer(channel, zap.NewNop(), apply, topic).Subscribe(ctx); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	enqueue := application.NewEnqueueMessageUC(pubsub.NewProducer(channel), receipts, topic)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := enqueue.Execute(makeEnv("c1", 1, domain.TypeSpeedIncreased, `{"by":100}`))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("enqueue waited for the consumer")
+	}
+}
+
+// failingPublisher cuenta las publicaciones y falla a partir de la número
+// failAt.
+type failingPublisher struct {
+	message.Publisher
+	calls, failAt int
+}
+
+func (p *failingPublisher) Publish(topic string, msgs ...*message.Message) error {
+	p.calls++
+	if p.failAt > 0 && p.calls >= p.failAt {
+		return errors.New("broker down")
+	}
+	return p.Publisher.Publish(topic, msgs...)
+}
+
+func TestEnqueueBatch_AppliesInOrderAndTracksEach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receipts := receipt.NewTracker(16)
+	store := persistence.NewMemoryStore(persistence.WithResultListener(receipts.Record))
+	channel := newChannel()
+	defer channel.Close()
+
+	consumer := pubsub.NewConsumer(channel, zap.NewNop(), application.NewApplyMessageUC(store, receipts), topic)
+	if err := consumer.Subscribe(ctx); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	pub := &failingPublisher{Publisher: channel}
+	enqueue := application.NewEnqueueMessageBatchUC(pubsub.NewProducer(pub), receipts, topic)
+
+	launch := makeEnv("c1", 1, domain.TypeLaunched, `{"type":"Falcon-9","launchSpeed":500,"mission":"ARTEMIS"}`)
+	rcs, err := enqueue.Execute([]domain.MessageEnvelope{
+		launch,
+		makeEnv("c1", 2, domain.TypeSpeedIncreased, `{"by":100}`),
+		launch,
+	})
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	for i, want := range []domain.ReceiptStatus{domain.ReceiptApplied, domain.ReceiptApplied, domain.ReceiptDuplicate} {
+		if got := waitProcessed(t, receipts, rcs[i].ID); got.Status != want || got.MessageNumber != rcs[i].MessageNumber {
+			t.Errorf("item %d; got=%+v want=%s", i, got, want)
+		}
+	}
+	if r, _, _ := store.Get("c1"); r.Speed != 600 {
+		t.Errorf("batch not applied in order; got speed=%d", r.Speed)
+	}
+	if pub.calls != 1 {
+		t.Errorf("batch not published in one go; calls=%d", pub.calls)
+	}
+}
+
+func TestEnqueueBatch_PublishFailureRejectsAll(t *testing.T) {
+	receipts := receipt.NewTracker(16)
+	channel := newChannel()
+	defer channel.Close()
+
+	pub := &failingPublisher{Publisher: channel, failAt: 1}
+	enqueue := application.NewEnqueueMessageBatchUC(pubsub.NewProducer(pub), receipts, topic)
+
+	envs := []domain.MessageEnvelope{
+		makeEnv("c1", 1, domain.TypeSpeedIncreased, `{"by":100}`),
+		makeEnv("c1", 2, domain.TypeSpeedIncreased, `{"by":100}`),
+	}
+	envs[0].ID, envs[1].ID = "rc-1", "rc-2"
+	if _, err := enqueue.Execute(envs); err == nil {
+		t.Fatalf("expected the publish error")
+	}
+	if pub.calls != 1 {
+		t.Errorf("batch not published in one go; calls=%d", pub.calls)
+	}
+	for _, id := range []string{"rc-1", "rc-2"} {
+		if got, _ := receipts.Get(id); got.Status != domain.ReceiptRejected || got.Reason != "broker down" {
+			t.Errorf("receipt %s not rejected; got=%+v", id, got)
+		}
+	}
+}
+
+// newChannel configura gochannel como main.
+func newChannel() *gochannel.GoChannel {
+	return gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+}
+
+func waitProcessed(t *testing.T, receipts *receipt.Tracker, id string) domain.Receipt {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if rc, ok := receipts.Get(id); ok && rc.Status != domain.ReceiptQueued {
+			return rc
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("receipt %s still queued", id)
+	return domain.Receipt{}
+}
+
+func makeEnv(channel string, num int, kind, payload string) domain.MessageEnvelope {
+	env := domain.MessageEnvelope{}
+	env.Metadata.Channel = channel
+	env.Metadata.MessageNum = num
+	env.Metadata.MessageTime = "2022-02-02T19:39:05Z"
+	env.Metadata.MessageType = kind
+	env.Message = json.RawMessage(payload)
+	return env
+}
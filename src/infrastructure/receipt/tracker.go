@@ -0,0 +1,122 @@
+// Package receipt guarda en memoria los recibos de los mensajes aceptados
+// por POST /messages y los cierra con lo que avisan los stores
+// (WithResultListener).
+package receipt
+
+import (
+	"sync"
+	"time"
+
+	"lunar/src/domain"
+	"lunar/src/domain/port"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+const DefaultCapacity = 100_000
+
+// Tracker recuerda los últimos capacity recibos; al llegar al límite olvida
+// el más antiguo, esté cerrado o no.
+type Tracker struct {
+	mu       sync.Mutex
+	receipts map[string]domain.Receipt
+	waiters  map[string]chan domain.MessageResult
+	order    []string
+	next     int
+	now      func() time.Time
+}
+
+var (
+	_ port.ReceiptWriter = (*Tracker)(nil)
+	_ port.ReceiptReader = (*Tracker)(nil)
+	_ port.ReceiptWaiter = (*Tracker)(nil)
+)
+
+func NewTracker(capacity int) *Tracker {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Tracker{
+		receipts: make(map[string]domain.Receipt),
+		waiters:  make(map[string]chan domain.MessageResult),
+		order:    make([]string, capacity),
+		now:      time.Now,
+	}
+}
+
+func (t *Tracker) Queue(env domain.MessageEnvelope) domain.Receipt {
+	id := env.ID
+	if id == "" {
+		id = watermill.NewUUID()
+	}
+	rc := domain.Receipt{
+		ID:            id,
+		Status:        domain.ReceiptQueued,
+		Channel:       env.Metadata.Channel,
+		MessageNumber: env.Metadata.MessageNum,
+		AcceptedAt:    t.now(),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if old := t.order[t.next]; old != "" {
+		delete(t.receipts, old)
+	}
+	t.order[t.next] = rc.ID
+	t.next = (t.next + 1) % len(t.order)
+	t.receipts[rc.ID] = rc
+	return rc
+}
+
+// Resolve cierra el recibo sólo la primera vez: si el store ya dijo qué hizo
+// con el envelope, un error posterior del mismo Apply no lo cambia.
+func (t *Tracker) Resolve(id string, res domain.MessageResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rc, ok := t.receipts[id]
+	if ok && rc.Status != domain.ReceiptQueued {
+		return
+	}
+	if ok {
+		at := t.now()
+		rc.Status = domain.ReceiptStatus(res.Outcome)
+		rc.Reason = res.Reason
+		rc.ProcessedAt = &at
+		t.receipts[id] = rc
+	}
+	// El cohete sólo se guarda para quien lo espera, no en el recibo.
+	if ch, waiting := t.waiters[id]; waiting {
+		ch <- res
+		delete(t.waiters, id)
+	}
+}
+
+func (t *Tracker) Expect() (string, <-chan domain.MessageResult, func()) {
+	id := watermill.NewUUID()
+	// Con hueco para uno, Resolve nunca se bloquea.
+	ch := make(chan domain.MessageResult, 1)
+
+	t.mu.Lock()
+	t.waiters[id] = ch
+	t.mu.Unlock()
+	return id, ch, func() {
+		t.mu.Lock()
+		delete(t.waiters, id)
+		t.mu.Unlock()
+	}
+}
+
+// Record es el listener que se pasa a los stores. Los envelopes sin ID no
+// llegaron por POST /messages y no tienen recibo.
+func (t *Tracker) Record(env domain.MessageEnvelope, res domain.MessageResult) {
+	if env.ID != "" {
+		t.Resolve(env.ID, res)
+	}
+}
+
+func (t *Tracker) Get(id string) (domain.Receipt, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rc, ok := t.receipts[id]
+	return rc, ok
+}
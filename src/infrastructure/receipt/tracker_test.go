@@ -0,0 +1,121 @@
+package receipt_test
+
+import (
+	"testing"
+
+	"lunar/src/domain"
+	"lunar/src/infrastructure/receipt"
+)
+
+func TestTracker_QueueThenResolve(t *testing.T) {
+	tr := receipt.NewTracker(8)
+	rc := tr.Queue(envelope("c1", 3))
+
+	if rc.ID == "" || rc.Status != domain.ReceiptQueued || rc.Channel != "c1" || rc.MessageNumber != 3 {
+		t.Fatalf("queued receipt mismatch; got=%+v", rc)
+	}
+	if got, ok := tr.Get(rc.ID); !ok || got.Status != domain.ReceiptQueued || got.ProcessedAt != nil {
+		t.Fatalf("receipt not queued; got=%+v ok=%v", got, ok)
+	}
+
+	tr.Resolve(rc.ID, domain.MessageResult{Outcome: domain.OutcomeRejected, Reason: "exploded"})
+	got, _ := tr.Get(rc.ID)
+	if got.Status != domain.ReceiptRejected || got.Reason != "exploded" || got.ProcessedAt == nil {
+		t.Errorf("receipt not resolved; got=%+v", got)
+	}
+}
+
+func TestTracker_FirstResolutionWins(t *testing.T) {
+	tr := receipt.NewTracker(8)
+	rc := tr.Queue(envelope("c1", 1))
+
+	tr.Resolve(rc.ID, domain.MessageResult{Outcome: domain.OutcomeApplied})
+	tr.Resolve(rc.ID, domain.MessageResult{Outcome: domain.OutcomeRejected, Reason: "late error"})
+
+	if got, _ := tr.Get(rc.ID); got.Status != domain.ReceiptApplied || got.Reason != "" {
+		t.Errorf("second resolution overwrote the first; got=%+v", got)
+	}
+}
+
+func TestTracker_RecordIgnoresEnvelopesWithoutReceipt(t *testing.T) {
+	tr := receipt.NewTracker(8)
+	rc := tr.Queue(envelope("c1", 1))
+
+	tr.Record(envelope("c1", 1), domain.MessageResult{Outcome: domain.OutcomeDuplicate})
+	if got, _ := tr.Get(rc.ID); got.Status != domain.ReceiptQueued {
+		t.Fatalf("envelope without id resolved a receipt; got=%+v", got)
+	}
+
+	env := envelope("c1", 1)
+	env.ID = rc.ID
+	tr.Record(env, domain.MessageResult{Outcome: domain.OutcomeStale})
+	if got, _ := tr.Get(rc.ID); got.Status != domain.ReceiptStale {
+		t.Errorf("record did not resolve by id; got=%+v", got)
+	}
+}
+
+func TestTracker_ForgetsOldestBeyondCapacity(t *testing.T) {
+	tr := receipt.NewTracker(2)
+	first := tr.Queue(envelope("c1", 1))
+	second := tr.Queue(envelope("c1", 2))
+	third := tr.Queue(envelope("c1", 3))
+
+	if _, ok := tr.Get(first.ID); ok {
+		t.Errorf("oldest receipt should have been forgotten")
+	}
+	for _, rc := range []domain.Receipt{second, third} {
+		if _, ok := tr.Get(rc.ID); !ok {
+			t.Errorf("receipt %d forgotten too early", rc.MessageNumber)
+		}
+	}
+	// Cerrar uno olvidado no lo resucita.
+	tr.Resolve(first.ID, domain.MessageResult{Outcome: domain.OutcomeApplied})
+	if _, ok := tr.Get(first.ID); ok {
+		t.Errorf("resolving a forgotten receipt brought it back")
+	}
+}
+
+func TestTracker_ExpectDeliversResultForReservedID(t *testing.T) {
+	tr := receipt.NewTracker(8)
+	id, result, stop := tr.Expect()
+	defer stop()
+
+	env := envelope("c1", 1)
+	env.ID = id
+	if rc := tr.Queue(env); rc.ID != id {
+		t.Fatalf("queue ignored the reserved id; got=%s want=%s", rc.ID, id)
+	}
+	tr.Record(env, domain.MessageResult{Outcome: domain.OutcomeApplied, Rocket: domain.Rocket{Channel: "c1", LastMsgNum: 1}})
+
+	select {
+	case res := <-result:
+		if res.Outcome != domain.OutcomeApplied || res.Rocket.LastMsgNum != 1 {
+			t.Errorf("result mismatch; got=%+v", res)
+		}
+	default:
+		t.Fatalf("result not delivered")
+	}
+	if got, _ := tr.Get(id); got.Status != domain.ReceiptApplied {
+		t.Errorf("receipt not resolved; got=%+v", got)
+	}
+}
+
+func TestTracker_StoppedWaiterIsNotDelivered(t *testing.T) {
+	tr := receipt.NewTracker(8)
+	id, result, stop := tr.Expect()
+	stop()
+
+	tr.Resolve(id, domain.MessageResult{Outcome: domain.OutcomeApplied})
+	select {
+	case res := <-result:
+		t.Errorf("stopped waiter got a result; got=%+v", res)
+	default:
+	}
+}
+
+func envelope(channel string, num int) domain.MessageEnvelope {
+	var env domain.MessageEnvelope
+	env.Metadata.Channel = channel
+	env.Metadata.MessageNum = num
+	return env
+}